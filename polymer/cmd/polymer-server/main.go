@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/app"
+	"github.com/ayushh-vermaa/polymer/internal/config"
+	"github.com/ayushh-vermaa/polymer/internal/server"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt,
+		syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := config.Load(os.Args[0], os.Args[1:])
+	if err != nil {
+		log.Fatalf("Error loading configuration: %s", err)
+	}
+	log.Printf("Loaded configuration: %s", cfg)
+
+	repo, err := app.OpenRepository(ctx, &cfg.Store)
+	if err != nil {
+		log.Fatalf("Error opening %s store: %s", cfg.Store.Backend, err)
+	}
+
+	service, err := app.NewService(repo, cfg)
+	if err != nil {
+		log.Fatalf("Error configuring service: %s", err)
+	}
+
+	httpServer := &http.Server{
+		Addr:              cfg.Server.Address,
+		Handler:           server.New(service),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("Listening on %s", cfg.Server.Address)
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		log.Fatalf("Error serving HTTP: %s", err)
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(),
+		cfg.Server.ShutdownTimeout.Duration)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil &&
+		!errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Error shutting down: %s", err)
+	}
+	service.Cards.Wait()
+	if err := repo.Close(shutdownCtx); err != nil {
+		log.Fatalf("Error closing %s store: %s", cfg.Store.Backend, err)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/app"
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// catalogCard is a card of the Rewards API catalog.
+type catalogCard struct {
+	CardIssuer string `json:"cardIssuer"`
+	CardKey    string `json:"cardKey"`
+	CardName   string `json:"cardName"`
+}
+
+// fetchCatalog fetches the card list from the Rewards API with client,
+// flattened to one entry per card.
+func fetchCatalog(ctx context.Context,
+	client *rewards.Client) ([]catalogCard, error) {
+
+	cardList, err := client.FetchCardList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch card list: %w", err)
+	}
+
+	var catalog []catalogCard
+	for _, cardIssuer := range *cardList {
+		for _, card := range cardIssuer.Card {
+			catalog = append(catalog, catalogCard{
+				CardIssuer: cardIssuer.CardIssuer,
+				CardKey:    card.CardKey,
+				CardName:   card.CardName,
+			})
+		}
+	}
+	return catalog, nil
+}
+
+// cardsList lists the cards of the Rewards API catalog.
+func cardsList(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("cards list", "")
+	if err := out.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	client, err := app.NewRewardsClient(&env.cfg.Rewards)
+	if err != nil {
+		return err
+	}
+	catalog, err := fetchCatalog(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	return out.write(env.stdout, catalog, func(w io.Writer) {
+		row(w, "KEY", "NAME", "ISSUER")
+		for _, card := range catalog {
+			row(w, card.CardKey, card.CardName, card.CardIssuer)
+		}
+	})
+}
+
+// cardsShow shows the details of the cards with the given keys, fetching and
+// storing those not stored yet. With -as-of it shows the terms the cards had
+// at that date instead, from their revision history.
+func cardsShow(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("cards show", "<card key>...")
+	asOf := fs.String("as-of", "",
+		"show the terms the cards had at this date")
+	if err := out.parse(fs, args, 1, -1); err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	var cards []*rewards.CardDetail
+	if *asOf != "" {
+		at, err := rewards.ParseDate(*asOf, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid -as-of: %w", err)
+		}
+		for _, cardKey := range fs.Args() {
+			card, err := shop.CardAsOf(ctx, repo.Cards, cardKey, at)
+			if err != nil {
+				return err
+			}
+			cards = append(cards, card)
+		}
+	} else {
+		service, err := env.service(ctx)
+		if err != nil {
+			return err
+		}
+		cards, err = service.Cards.Get(ctx, fs.Args())
+		if err != nil {
+			return err
+		}
+	}
+
+	return out.write(env.stdout, cards, func(w io.Writer) {
+		for i, card := range cards {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			writeCard(w, card)
+		}
+	})
+}
+
+// cardRevision is a revision of a card with the changes from the previous
+// one.
+type cardRevision struct {
+	Revision   int               `json:"revision"`
+	ObservedAt time.Time         `json:"observedAt"`
+	Changes    *rewards.CardDiff `json:"changes"`
+}
+
+// cardsHistory shows every recorded revision of a card and what changed in
+// each.
+func cardsHistory(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("cards history", "<card key>")
+	if err := out.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	stored, err := repo.Cards.ListCardRevisions(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if len(stored) == 0 {
+		return fmt.Errorf("%w: no revisions of card %s", store.ErrNotFound,
+			fs.Arg(0))
+	}
+
+	revisions := make([]cardRevision, len(stored))
+	var previous *rewards.CardDetail
+	for i, revision := range stored {
+		revisions[i] = cardRevision{
+			Revision:   revision.Revision,
+			ObservedAt: revision.ObservedAt,
+			Changes:    rewards.DiffCardDetail(previous, &revision.CardDetail),
+		}
+		previous = &revision.CardDetail
+	}
+
+	return out.write(env.stdout, revisions, func(w io.Writer) {
+		row(w, "REVISION", "OBSERVED", "CHANGES")
+		for i, revision := range revisions {
+			changes := []string{"first observed"}
+			if i > 0 {
+				changes = describeCardDiff(revision.Changes)
+			}
+			for j, change := range changes {
+				if j == 0 {
+					row(w, revision.Revision,
+						revision.ObservedAt.Local().Format(time.DateTime),
+						change)
+				} else {
+					row(w, "", "", change)
+				}
+			}
+		}
+	})
+}
+
+// describeCardDiff returns a line describing each change in diff.
+func describeCardDiff(diff *rewards.CardDiff) []string {
+	var lines []string
+	for _, change := range diff.Fields {
+		lines = append(lines, fmt.Sprintf("%s: %v -> %v", change.Field,
+			change.Old, change.New))
+	}
+	for _, bonus := range diff.AddedSpendBonuses {
+		lines = append(lines, fmt.Sprintf("bonus added: %gx %s",
+			bonus.EarnMultiplier, bonus.SpendBonusCategoryName))
+	}
+	for _, bonus := range diff.RemovedSpendBonuses {
+		lines = append(lines, fmt.Sprintf("bonus removed: %gx %s",
+			bonus.EarnMultiplier, bonus.SpendBonusCategoryName))
+	}
+	for _, bonus := range diff.ChangedSpendBonuses {
+		for _, change := range bonus.Changes {
+			lines = append(lines, fmt.Sprintf("bonus %s: %s: %v -> %v",
+				bonus.SpendBonusCategoryName, change.Field, change.Old,
+				change.New))
+		}
+	}
+	for _, benefit := range diff.AddedBenefits {
+		lines = append(lines, "benefit added: "+benefit.BenefitTitle)
+	}
+	for _, benefit := range diff.RemovedBenefits {
+		lines = append(lines, "benefit removed: "+benefit.BenefitTitle)
+	}
+	for _, spend := range diff.AddedAnnualSpend {
+		lines = append(lines, "annual spend bonus added: "+
+			spend.AnnualSpendDesc)
+	}
+	for _, spend := range diff.RemovedAnnualSpend {
+		lines = append(lines, "annual spend bonus removed: "+
+			spend.AnnualSpendDesc)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "no changes")
+	}
+	return lines
+}
+
+// writeCard writes the main details of card as a two column table.
+func writeCard(w io.Writer, card *rewards.CardDetail) {
+	row(w, "Key", card.CardKey)
+	row(w, "Name", card.CardName)
+	row(w, "Issuer", card.CardIssuer)
+	row(w, "Network", card.CardNetwork)
+	row(w, "Annual fee", card.AnnualFee)
+	row(w, "Foreign transaction fee", fmt.Sprintf("%.2f%%",
+		card.FxFeeRate()*100))
+	row(w, "Base earn", fmt.Sprintf("%gx %s", card.BaseSpendAmount,
+		card.BaseSpendEarnType))
+	for _, bonus := range card.SpendBonusCategory {
+		row(w, "Bonus", fmt.Sprintf("%gx %s", bonus.EarnMultiplier,
+			bonus.SpendBonusCategoryName))
+	}
+	if card.IsSignupBonus == 1 {
+		row(w, "Sign-up bonus", fmt.Sprintf("%s after $%.2f in %g %s(s)",
+			card.SignupBonusAmount, card.SignupBonusSpend,
+			card.SignupBonusLength, card.SignupBonusLengthPeriod))
+	}
+}
+
+// cardsSync syncs the stored cards with the Rewards API catalog. It fails
+// if any card failed to sync or the sync was interrupted, after writing the
+// report.
+func cardsSync(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("cards sync", "")
+	concurrency := fs.Int("concurrency", shop.DefaultSyncConcurrency,
+		"card details fetched at once")
+	if err := out.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	client, err := app.NewRewardsClient(&env.cfg.Rewards)
+	if err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	report, err := shop.SyncCatalog(ctx, repo.Cards, shop.SyncOptions{
+		Client:      client,
+		Concurrency: *concurrency,
+	})
+	if report == nil {
+		return err
+	}
+	syncErr := err
+
+	if out.format == formatJSON {
+		err = out.write(env.stdout, report, nil)
+	} else {
+		err = report.WriteReport(env.stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if syncErr != nil {
+		return fmt.Errorf("sync stopped: %w", syncErr)
+	}
+	if report.Failed() {
+		return fmt.Errorf("%d of %d cards failed to sync",
+			len(report.Errors), report.Listed)
+	}
+	return nil
+}
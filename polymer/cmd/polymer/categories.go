@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+)
+
+// categoriesList lists the spend category taxonomy, indenting each category
+// below its parent.
+func categoriesList(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("categories list", "")
+	if err := out.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	taxonomy := rewards.DefaultTaxonomy()
+	categories := taxonomy.Categories()
+	return out.write(env.stdout, categories, func(w io.Writer) {
+		row(w, "ID", "CATEGORY", "EXCLUDES")
+		for _, category := range categories {
+			indent := strings.Repeat("  ", len(taxonomy.Path(category.ID))-1)
+			var excludes []string
+			for _, id := range category.Excludes {
+				if excluded, ok := taxonomy.Category(id); ok {
+					excludes = append(excludes, excluded.Name)
+				}
+			}
+			row(w, category.ID, indent+category.Name,
+				strings.Join(excludes, ", "))
+		}
+	})
+}
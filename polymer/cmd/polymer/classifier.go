@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+)
+
+// classifierResult is what classifier train reports.
+type classifierResult struct {
+	Model      string  `json:"model"`
+	Examples   int     `json:"examples"`
+	Categories int     `json:"categories"`
+	Tested     int     `json:"tested"`
+	Accuracy   float64 `json:"accuracy"`
+}
+
+// classifierTrain trains the merchant category classifier from the
+// categorized stored domains and the merchants of the stored transactions
+// categorized by rules, domains or MCCs, and saves it to the
+// configured model file, or the one given with -out. With -test, that
+// fraction of the merchants, picked the same way on every run, is first held
+// out to measure its accuracy on.
+func classifierTrain(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("classifier train", "")
+	model := fs.String("out", env.cfg.Classifier.ModelPath,
+		"model file to write")
+	test := fs.Float64("test", 0.2,
+		"fraction of merchants held out to measure accuracy, 0 for none")
+	if err := out.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *model == "" {
+		return errors.New("classifier train: -out is required when no " +
+			"model file is configured")
+	}
+	if *test < 0 || *test >= 1 {
+		return errors.New("classifier train: -test must be in [0, 1)")
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	examples, err := shop.ClassifierExamples(ctx, repo)
+	if err != nil {
+		return err
+	}
+	result := &classifierResult{Model: *model, Examples: len(examples)}
+	if held := int(*test * float64(len(examples))); held > 0 {
+		shuffled := append([]shop.ClassifierExample(nil), examples...)
+		random := rand.New(rand.NewSource(1))
+		random.Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		trial, err := shop.TrainClassifier(shuffled[held:])
+		if err != nil {
+			return fmt.Errorf("classifier train: %w", err)
+		}
+		result.Tested = held
+		result.Accuracy = trial.Accuracy(shuffled[:held])
+	}
+
+	classifier, err := shop.TrainClassifier(examples)
+	if err != nil {
+		return fmt.Errorf("classifier train: %w", err)
+	}
+	if err := classifier.Save(*model); err != nil {
+		return fmt.Errorf("failed to save classifier: %w", err)
+	}
+	result.Categories = len(classifier.Classes)
+	return out.write(env.stdout, result, func(w io.Writer) {
+		row(w, "Model", result.Model)
+		row(w, "Merchants", result.Examples)
+		row(w, "Categories", result.Categories)
+		if result.Tested > 0 {
+			row(w, "Held out", result.Tested)
+			row(w, "Accuracy", fmt.Sprintf("%.1f%%", result.Accuracy*100))
+		}
+	})
+}
+
+// classifierPredict shows the categories the trained classifier predicts for
+// a merchant, most likely first.
+func classifierPredict(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("classifier predict", "")
+	domain := fs.String("domain", "", "merchant domain, e.g. amazon.com")
+	descriptor := fs.String("descriptor", "",
+		"merchant statement descriptor")
+	top := fs.Int("top", 3, "number of categories to show")
+	if err := out.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *domain == "" && *descriptor == "" {
+		return errors.New("classifier predict: -domain or -descriptor is " +
+			"required")
+	}
+	model := env.cfg.Classifier.ModelPath
+	if model == "" {
+		return errors.New("classifier predict: no model file is configured")
+	}
+	classifier, err := shop.LoadClassifier(model)
+	if err != nil {
+		return err
+	}
+
+	predictions := classifier.Predict(*domain, *descriptor)
+	if len(predictions) > *top {
+		predictions = predictions[:max(*top, 0)]
+	}
+	if predictions == nil {
+		predictions = []shop.Prediction{}
+	}
+	return out.write(env.stdout, predictions, func(w io.Writer) {
+		row(w, "CATEGORY ID", "CATEGORY", "CONFIDENCE")
+		for _, prediction := range predictions {
+			row(w, prediction.CategoryID, prediction.CategoryName,
+				fmt.Sprintf("%.2f", prediction.Confidence))
+		}
+	})
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// domainsAdd stores a merchant domain with its spend category, under its
+// normalized name.
+func domainsAdd(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("domains add", "<domain>")
+	categoryID := fs.Int("category-id", store.UncategorizedID,
+		"spend bonus category ID")
+	categoryName := fs.String("category-name", "", "spend bonus category name")
+	mcc := fs.String("mcc", "", "merchant category code (ISO 18245)")
+	if err := out.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	baseDomain, err := shop.NormalizeDomainRecord(fs.Arg(0), categoryID,
+		*categoryName, *mcc)
+	if err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	domain, err := repo.Domains.InsertDomain(ctx, baseDomain)
+	if err != nil {
+		return err
+	}
+	return writeDomain(env.stdout, out, domain)
+}
+
+// domainsUpdate changes the name, spend category or MCC of a stored merchant
+// domain. Only the flags given change the domain.
+func domainsUpdate(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("domains update", "<domain>")
+	rename := fs.String("rename", "", "new domain name")
+	categoryID := fs.Int("category-id", store.UncategorizedID,
+		fmt.Sprintf("spend bonus category ID, %d for none",
+			store.UncategorizedID))
+	categoryName := fs.String("category-name", "", "spend bonus category name")
+	mcc := fs.String("mcc", "",
+		"merchant category code (ISO 18245), empty for none")
+	if err := out.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	name, err := shop.NormalizeDomain(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	domain, err := repo.Domains.GetDomainByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if set["rename"] {
+		name = *rename
+	}
+	if !set["mcc"] {
+		*mcc = domain.MCC
+	}
+	baseDomain, err := shop.NormalizeDomainRecord(name, categoryID,
+		*categoryName, *mcc)
+	if err != nil {
+		return err
+	}
+	if !set["category-id"] && !set["category-name"] {
+		baseDomain.CategoryID = domain.CategoryID
+		baseDomain.CategoryName = domain.CategoryName
+	}
+	domain.BaseDomain = baseDomain
+
+	if err := repo.Domains.UpdateDomain(ctx, domain); err != nil {
+		return err
+	}
+	return writeDomain(env.stdout, out, domain)
+}
+
+// domainsDelete deletes the stored merchant domains with the given names.
+func domainsDelete(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("domains delete", "<domain>...")
+	if err := out.parse(fs, args, 1, -1); err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, arg := range fs.Args() {
+		name, err := shop.NormalizeDomain(arg)
+		if err != nil {
+			return err
+		}
+		if err := repo.Domains.DeleteDomain(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// domainsList lists the stored merchant domains by name, a page at a time,
+// optionally only those of the categories given with -category.
+func domainsList(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("domains list", "")
+	filter := domainFilterFlags(fs)
+	fs.IntVar(&filter.Limit, "limit", 50, "most domains to list, 0 for all")
+	fs.IntVar(&filter.Offset, "offset", 0, "domains to skip")
+	if err := out.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return errors.New("domains list: -limit and -offset must not be " +
+			"negative")
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	domains, err := repo.Domains.ListDomains(ctx, filter)
+	if err != nil {
+		return err
+	}
+	return writeDomains(env.stdout, out, domains)
+}
+
+// domainsImport stores the merchant domains of a CSV or JSON file, in place
+// of the stored domains of the same names. Nothing is stored unless every
+// domain in the file is valid.
+func domainsImport(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("domains import", "<file>")
+	format := fs.String("type", "",
+		"file format: csv or json, by default from the file extension")
+	if err := out.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	if *format == "" {
+		*format = shop.DomainFormat(fs.Arg(0))
+	}
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	baseDomains, err := shop.ParseDomains(file, *format)
+	if err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	domains, err := repo.Domains.UpsertDomains(ctx, baseDomains)
+	if err != nil {
+		return err
+	}
+	result := struct {
+		Imported int `json:"imported"`
+	}{len(domains)}
+	return out.write(env.stdout, result, func(w io.Writer) {
+		row(w, "Imported", result.Imported)
+	})
+}
+
+// domainsExport writes the stored merchant domains, optionally only those of
+// the categories given with -category, as CSV or JSON to stdout or the file
+// given with -out, in the form domains import reads.
+func domainsExport(ctx context.Context, env *env, args []string) error {
+	fs := flag.NewFlagSet("domains export", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: polymer domains export [flags]")
+		fs.PrintDefaults()
+	}
+	filter := domainFilterFlags(fs)
+	outName := fs.String("out", "", "file to write instead of stdout")
+	format := fs.String("type", "",
+		"file format: csv or json, by default from the -out extension or csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errors.New("domains export: wrong number of arguments")
+	}
+	if *format == "" {
+		*format = shop.DomainFormat(*outName)
+	}
+	if *format == "" {
+		*format = shop.DomainFormatCSV
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	domains, err := repo.Domains.ListDomains(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if *outName == "" {
+		return shop.WriteDomains(env.stdout, *format, domains)
+	}
+	file, err := os.Create(*outName)
+	if err != nil {
+		return err
+	}
+	if err := shop.WriteDomains(file, *format, domains); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// domainFilterFlags defines the -category flag selecting domains on fs, and
+// returns the filter it sets. A category selects the domains of the
+// categories below it too.
+func domainFilterFlags(fs *flag.FlagSet) *store.DomainFilter {
+	filter := &store.DomainFilter{}
+	fs.Func("category", "only domains of this category name or ID, "+
+		"none for uncategorized; repeat for several", func(value string) error {
+		if strings.EqualFold(value, "none") {
+			filter.CategoryIDs = append(filter.CategoryIDs,
+				store.UncategorizedID)
+			return nil
+		}
+		categoryID, err := parseCategory(value)
+		if err != nil {
+			return err
+		}
+		taxonomy := rewards.DefaultTaxonomy()
+		for _, category := range taxonomy.Categories() {
+			if slices.Contains(taxonomy.Path(category.ID), categoryID) {
+				filter.CategoryIDs = append(filter.CategoryIDs, category.ID)
+			}
+		}
+		return nil
+	})
+	return filter
+}
+
+// domainsLookup shows the stored merchant domain a host name or URL belongs
+// to, following aliases and parent domains.
+func domainsLookup(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("domains lookup", "<domain>")
+	if err := out.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	domain, err := shop.LookupDomain(ctx, repo.Domains, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return writeDomain(env.stdout, out, domain)
+}
+
+// domainsAlias maps a domain name to a stored merchant domain, so that it and
+// its subdomains are looked up as that merchant.
+func domainsAlias(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("domains alias", "<alias> <domain>")
+	if err := out.parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+	alias, err := shop.NormalizeDomain(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	name, err := shop.NormalizeDomain(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.Domains.GetDomainByName(ctx, name); err != nil {
+		return err
+	}
+	domainAlias, err := repo.Domains.SetDomainAlias(ctx, alias, name)
+	if err != nil {
+		return err
+	}
+	return writeDomainAliases(env.stdout, out,
+		[]*store.DomainAlias{domainAlias})
+}
+
+// domainsAliases lists the stored domain aliases.
+func domainsAliases(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("domains aliases", "")
+	if err := out.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	aliases, err := repo.Domains.ListDomainAliases(ctx)
+	if err != nil {
+		return err
+	}
+	return writeDomainAliases(env.stdout, out, aliases)
+}
+
+// domainView is how a stored domain is written as JSON.
+type domainView struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	CategoryID   int    `json:"categoryID"`
+	CategoryName string `json:"categoryName"`
+	MCC          string `json:"mcc,omitempty"`
+}
+
+func newDomainView(domain *store.Domain) domainView {
+	return domainView{domain.ID.Hex(), domain.Name, domain.CategoryID,
+		domain.CategoryName, domain.MCC}
+}
+
+func writeDomain(w io.Writer, out *output, domain *store.Domain) error {
+	view := newDomainView(domain)
+	return out.write(w, view, func(w io.Writer) {
+		row(w, "NAME", "CATEGORY ID", "CATEGORY", "MCC")
+		row(w, view.Name, view.CategoryID, view.CategoryName, view.MCC)
+	})
+}
+
+func writeDomains(w io.Writer, out *output, domains []*store.Domain) error {
+	views := make([]domainView, len(domains))
+	for i, domain := range domains {
+		views[i] = newDomainView(domain)
+	}
+	return out.write(w, views, func(w io.Writer) {
+		row(w, "NAME", "CATEGORY ID", "CATEGORY", "MCC")
+		for _, view := range views {
+			row(w, view.Name, view.CategoryID, view.CategoryName, view.MCC)
+		}
+	})
+}
+
+func writeDomainAliases(w io.Writer, out *output,
+	aliases []*store.DomainAlias) error {
+
+	if aliases == nil {
+		aliases = []*store.DomainAlias{}
+	}
+	return out.write(w, aliases, func(w io.Writer) {
+		row(w, "ALIAS", "DOMAIN")
+		for _, domainAlias := range aliases {
+			row(w, domainAlias.Alias, domainAlias.Domain)
+		}
+	})
+}
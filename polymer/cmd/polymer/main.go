@@ -0,0 +1,221 @@
+// Command polymer manages the card catalog, merchant domains, categorization
+// rules and wallets, recommends the best card in a wallet for a purchase and
+// imports card statements to compare the cards used with the best ones.
+//
+// Usage:
+//
+//	polymer [config flags] <command> <subcommand> [flags] [arguments]
+//
+// Flags of a subcommand must precede its arguments. Run polymer help for the
+// list of commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/ayushh-vermaa/polymer/internal/app"
+	"github.com/ayushh-vermaa/polymer/internal/config"
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// command runs a subcommand with its arguments.
+type command func(ctx context.Context, env *env, args []string) error
+
+// commands maps each command and subcommand name to its implementation.
+var commands = map[string]map[string]command{
+	"cards": {
+		"list":    cardsList,
+		"show":    cardsShow,
+		"sync":    cardsSync,
+		"history": cardsHistory,
+	},
+	"categories": {
+		"list": categoriesList,
+	},
+	"classifier": {
+		"train":   classifierTrain,
+		"predict": classifierPredict,
+	},
+	"domains": {
+		"add":     domainsAdd,
+		"update":  domainsUpdate,
+		"delete":  domainsDelete,
+		"list":    domainsList,
+		"import":  domainsImport,
+		"export":  domainsExport,
+		"lookup":  domainsLookup,
+		"alias":   domainsAlias,
+		"aliases": domainsAliases,
+	},
+	"wallet": {
+		"create":      walletCreate,
+		"show":        walletShow,
+		"add-card":    walletAddCard,
+		"remove-card": walletRemoveCard,
+		"analyze":     walletAnalyze,
+	},
+	"mcc": {
+		"list": mccList,
+		"show": mccShow,
+	},
+	"rules": {
+		"import":   rulesImport,
+		"list":     rulesList,
+		"override": rulesOverride,
+		"delete":   rulesDelete,
+		"explain":  rulesExplain,
+	},
+	"recommend": {
+		"": recommend,
+	},
+	"tx": {
+		"list":     txList,
+		"import":   txImport,
+		"profiles": txProfiles,
+	},
+}
+
+// env holds what the commands share: the configuration, the output, and the
+// repository and the service running on it, which are opened on first use.
+type env struct {
+	cfg         *config.Config
+	stdout      io.Writer
+	repo        *store.Repository
+	shopService *shop.Service
+}
+
+// repository opens the configured storage backend once and returns it.
+func (env *env) repository(ctx context.Context) (*store.Repository, error) {
+	if env.repo != nil {
+		return env.repo, nil
+	}
+	repo, err := app.OpenRepository(ctx, &env.cfg.Store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s store: %w",
+			env.cfg.Store.Backend, err)
+	}
+	env.repo = repo
+	return repo, nil
+}
+
+// service returns the service running on the repository, opening both
+// once.
+func (env *env) service(ctx context.Context) (*shop.Service, error) {
+	if env.shopService != nil {
+		return env.shopService, nil
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	service, err := app.NewService(repo, env.cfg)
+	if err != nil {
+		return nil, err
+	}
+	env.shopService = service
+	return service, nil
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "polymer: %s\n", err)
+		}
+		os.Exit(2)
+	}
+}
+
+// run parses the configuration flags in args and runs the command that
+// follows them.
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	if len(args) > 0 && args[0] == "help" {
+		usage(stdout)
+		return nil
+	}
+
+	cfg, args, err := config.LoadCommand("polymer", args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return errors.New("no command given")
+	}
+
+	subcommands, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+	name, args := args[0], args[1:]
+	cmd, ok := subcommands[""]
+	if !ok {
+		if len(args) == 0 {
+			return fmt.Errorf("%s: no subcommand given, want one of: %s",
+				name, strings.Join(subcommandNames(subcommands), ", "))
+		}
+		if cmd, ok = subcommands[args[0]]; !ok {
+			return fmt.Errorf("%s: unknown subcommand: %s", name, args[0])
+		}
+		args = args[1:]
+	}
+
+	cmdEnv := &env{cfg: cfg, stdout: stdout}
+	err = cmd(ctx, cmdEnv, args)
+	if cmdEnv.shopService != nil {
+		// Let stale cards served by the command finish refreshing.
+		cmdEnv.shopService.Cards.Wait()
+	}
+	if cmdEnv.repo != nil {
+		err = errors.Join(err, cmdEnv.repo.Close(context.WithoutCancel(ctx)))
+	}
+	return err
+}
+
+// usage writes the list of commands to w.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: polymer [config flags] <command> <subcommand> "+
+		"[flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		subcommands := subcommandNames(commands[name])
+		if len(subcommands) == 0 {
+			fmt.Fprintf(w, "  %s\n", name)
+			continue
+		}
+		fmt.Fprintf(w, "  %s %s\n", name, strings.Join(subcommands, "|"))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run polymer -h for the config flags and "+
+		"polymer <command> <subcommand> -h for the flags of a subcommand.")
+}
+
+// subcommandNames returns the sorted names of subcommands, without the
+// unnamed default.
+func subcommandNames(subcommands map[string]command) []string {
+	var names []string
+	for name := range subcommands {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// mccList lists the bundled merchant category codes.
+func mccList(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("mcc list", "")
+	if err := out.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	return writeMCCs(env.stdout, out, rewards.MCCs())
+}
+
+// mccShow shows the bundled merchant category codes given.
+func mccShow(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("mcc show", "<code>...")
+	if err := out.parse(fs, args, 1, -1); err != nil {
+		return err
+	}
+
+	var mccs []*rewards.MCC
+	for _, code := range fs.Args() {
+		mcc, ok := rewards.LookupMCC(code)
+		if !ok {
+			return fmt.Errorf("%w: unknown merchant category code: %s",
+				store.ErrNotFound, code)
+		}
+		mccs = append(mccs, mcc)
+	}
+	return writeMCCs(env.stdout, out, mccs)
+}
+
+func writeMCCs(w io.Writer, out *output, mccs []*rewards.MCC) error {
+	return out.write(w, mccs, func(w io.Writer) {
+		row(w, "MCC", "GROUP", "CATEGORY IDS", "DESCRIPTION")
+		for _, mcc := range mccs {
+			ids := make([]string, len(mcc.CategoryIDs))
+			for i, id := range mcc.CategoryIDs {
+				ids[i] = strconv.Itoa(id)
+			}
+			row(w, mcc.Code, mcc.CategoryGroup, strings.Join(ids, ","),
+				mcc.Description)
+		}
+	})
+}
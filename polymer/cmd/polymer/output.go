@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats selected by the -format flag.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// output writes a command's result in the format selected on its flag set.
+type output struct {
+	format string
+}
+
+// newFlagSet returns the flag set of a subcommand, with the -format flag
+// registered on it. The arguments named in usage are shown in its help.
+func newFlagSet(name, usage string) (*flag.FlagSet, *output) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	out := &output{}
+	fs.StringVar(&out.format, "format", formatTable,
+		"output format: table or json")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: polymer %s [flags] %s\n", name,
+			usage)
+		fs.PrintDefaults()
+	}
+	return fs, out
+}
+
+// parse parses args with fs and checks the output format and that between
+// min and max arguments remain. A negative max sets no upper limit.
+func (out *output) parse(fs *flag.FlagSet, args []string,
+	min, max int) error {
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if out.format != formatTable && out.format != formatJSON {
+		return fmt.Errorf("%s: unknown output format: %s", fs.Name(),
+			out.format)
+	}
+	if fs.NArg() < min || (max >= 0 && fs.NArg() > max) {
+		fs.Usage()
+		return fmt.Errorf("%s: wrong number of arguments", fs.Name())
+	}
+	return nil
+}
+
+// write writes value as indented JSON, or calls table with a tab separated
+// writer whose columns are aligned when it is flushed.
+func (out *output) write(w io.Writer, value any,
+	table func(w io.Writer)) error {
+
+	if out.format == formatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
+
+// row writes the tab separated cells of a table row.
+func row(w io.Writer, cells ...any) {
+	formatted := make([]string, len(cells))
+	for i, cell := range cells {
+		switch cell := cell.(type) {
+		case float64:
+			formatted[i] = fmt.Sprintf("%.2f", cell)
+		default:
+			formatted[i] = fmt.Sprint(cell)
+		}
+	}
+	fmt.Fprintln(w, strings.Join(formatted, "\t"))
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// rulesImport replaces the shared categorization rules with those of a JSON
+// file, in the order they are listed.
+func rulesImport(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("rules import", "<file>")
+	if err := out.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	baseRules, err := shop.LoadCategoryRules(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules, err := repo.CategoryRules.ReplaceCategoryRules(ctx, baseRules)
+	if err != nil {
+		return err
+	}
+	return writeRules(env.stdout, out, rules)
+}
+
+// rulesList lists the shared categorization rules, preceded by the
+// overrides of a wallet with -wallet, in the order they are tried.
+func rulesList(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("rules list", "")
+	walletID := fs.String("wallet", "", "also list the overrides of this "+
+		"wallet")
+	if err := out.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules, err := repo.CategoryRules.ListCategoryRules(ctx, *walletID)
+	if err != nil {
+		return err
+	}
+	return writeRules(env.stdout, out, rules)
+}
+
+// rulesOverride makes a wallet categorize a merchant domain and its
+// subdomains, or with -descriptor the statement descriptors containing the
+// given text, as a category, whatever else is known about the merchant.
+func rulesOverride(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("rules override", "<domain or descriptor>")
+	walletID := fs.String("wallet", "", "ID of the stored wallet")
+	category := fs.String("category", "", "category name or ID")
+	descriptor := fs.Bool("descriptor", false,
+		"match statement descriptors containing the argument")
+	if err := out.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	if *walletID == "" {
+		return errors.New("rules override: -wallet is required")
+	}
+	categoryID, err := parseCategory(*category)
+	if err != nil {
+		return fmt.Errorf("rules override: invalid -category: %w", err)
+	}
+
+	baseRule := &store.BaseCategoryRule{
+		Name:       "override " + fs.Arg(0),
+		WalletID:   *walletID,
+		Match:      store.RuleMatchSuffix,
+		Field:      store.RuleFieldDomain,
+		Pattern:    fs.Arg(0),
+		CategoryID: categoryID,
+	}
+	if *descriptor {
+		baseRule.Match = store.RuleMatchKeywords
+		baseRule.Field = store.RuleFieldDescriptor
+		baseRule.Pattern = ""
+		baseRule.Keywords = []string{fs.Arg(0)}
+	}
+	if err := shop.NormalizeCategoryRule(baseRule); err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := repo.Wallets.GetWallet(ctx, *walletID); err != nil {
+		return err
+	}
+
+	rule, err := repo.CategoryRules.InsertCategoryRule(ctx, baseRule)
+	if err != nil {
+		return err
+	}
+	return writeRules(env.stdout, out, []*store.CategoryRule{rule})
+}
+
+// rulesDelete deletes the categorization rules with the given IDs.
+func rulesDelete(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("rules delete", "<rule id>...")
+	if err := out.parse(fs, args, 1, -1); err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range fs.Args() {
+		if err := repo.CategoryRules.DeleteCategoryRule(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rulesExplain shows the category a merchant would be given and which rule
+// or stored data decided it.
+func rulesExplain(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("rules explain", "")
+	walletID := fs.String("wallet", "", "apply the overrides of this wallet")
+	domain := fs.String("domain", "", "merchant domain, e.g. amazon.com")
+	descriptor := fs.String("descriptor", "",
+		"merchant statement descriptor")
+	mcc := fs.String("mcc", "", "merchant category code")
+	if err := out.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *domain == "" && *descriptor == "" {
+		return errors.New("rules explain: -domain or -descriptor is required")
+	}
+	if *mcc != "" && !rewards.ValidMCC(*mcc) {
+		return fmt.Errorf("rules explain: invalid -mcc: %s", *mcc)
+	}
+	service, err := env.service(ctx)
+	if err != nil {
+		return err
+	}
+
+	category, err := service.Categorize(ctx, *walletID,
+		store.MerchantDetails{
+			DomainName: *domain,
+			Descriptor: *descriptor,
+			MCC:        *mcc,
+		})
+	if err != nil {
+		return err
+	}
+	return out.write(env.stdout, category, func(w io.Writer) {
+		row(w, "Category ID", category.ID)
+		row(w, "Category", category.Name)
+		if category.MCC != "" {
+			row(w, "MCC", category.MCC)
+		}
+		explanation := "no rule matched"
+		if category.Explanation != nil {
+			explanation = category.Explanation.String()
+		}
+		row(w, "Decided by", explanation)
+	})
+}
+
+// parseCategory returns the ID of the taxonomy category named or numbered by
+// value.
+func parseCategory(value string) (int, error) {
+	taxonomy := rewards.DefaultTaxonomy()
+	if id, err := strconv.Atoi(value); err == nil {
+		if _, ok := taxonomy.Category(id); ok {
+			return id, nil
+		}
+	}
+	if category, ok := taxonomy.CategoryByName(value); ok {
+		return category.ID, nil
+	}
+	return 0, fmt.Errorf("unknown category: %q", value)
+}
+
+func writeRules(w io.Writer, out *output, rules []*store.CategoryRule) error {
+	if rules == nil {
+		rules = []*store.CategoryRule{}
+	}
+	taxonomy := rewards.DefaultTaxonomy()
+	return out.write(w, rules, func(w io.Writer) {
+		row(w, "ID", "WALLET", "NAME", "MATCH", "FIELD", "PATTERN",
+			"CATEGORY")
+		for _, rule := range rules {
+			pattern := rule.Pattern
+			if rule.Match == store.RuleMatchKeywords {
+				pattern = strings.Join(rule.Keywords, ", ")
+			}
+			category := strconv.Itoa(rule.CategoryID)
+			if known, ok := taxonomy.Category(rule.CategoryID); ok {
+				category = known.Name
+			}
+			row(w, rule.ID.Hex(), rule.WalletID, rule.Name, rule.Match,
+				rule.Field, pattern, category)
+		}
+	})
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// recommend selects the best card for a purchase, from a stored wallet or
+// from the cards given with -cards, and records it with -record.
+func recommend(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("recommend", "")
+	walletID := fs.String("wallet", "", "ID of the stored wallet to use")
+	cardKeys := fs.String("cards", "",
+		"comma separated card keys to use instead of a stored wallet")
+	domain := fs.String("domain", "", "merchant domain, e.g. amazon.com")
+	descriptor := fs.String("descriptor", "",
+		"merchant statement descriptor")
+	amount := fs.Float64("amount", 0, "purchase amount in USD")
+	country := fs.String("country", "", "merchant country, if abroad")
+	currency := fs.String("currency", "", "charge currency, if not USD")
+	mcc := fs.String("mcc", "",
+		"merchant category code, instead of the domain's")
+	record := fs.Bool("record", false, "record the purchase as a transaction")
+	if err := out.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *domain == "" && *descriptor == "" {
+		return errors.New("recommend: -domain or -descriptor is required")
+	}
+	if *amount <= 0 {
+		return errors.New("recommend: -amount must be positive")
+	}
+	if *mcc != "" && !rewards.ValidMCC(*mcc) {
+		return fmt.Errorf("recommend: invalid -mcc: %s", *mcc)
+	}
+	if (*walletID == "") == (*cardKeys == "") {
+		return errors.New("recommend: exactly one of -wallet and -cards " +
+			"is required")
+	}
+	service, err := env.service(ctx)
+	if err != nil {
+		return err
+	}
+
+	var wallet *shop.BaseWallet
+	if *walletID != "" {
+		wallet, err = loadWallet(ctx, env, *walletID)
+	} else {
+		keys := strings.Split(*cardKeys, ",")
+		if _, err := service.Cards.Fill(ctx, keys); err != nil {
+			return err
+		}
+		wallet, err = service.BuildWallet(ctx, keys)
+	}
+	if err != nil {
+		if !shop.PartialWallet(wallet, err) {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "polymer: recommend: going on without "+
+			"cards: %s\n", err)
+	}
+
+	merchant := store.MerchantDetails{
+		DomainName: *domain,
+		Descriptor: *descriptor,
+		Country:    *country,
+		Currency:   *currency,
+		MCC:        *mcc,
+	}
+	var cardDetails *store.CardDetails
+	if *record {
+		cardDetails, err = service.Transact(ctx, merchant, *amount, wallet)
+	} else {
+		var transaction *store.BaseTransaction
+		transaction, err = service.Recommend(ctx, merchant, *amount,
+			time.Now(), wallet)
+		if transaction != nil {
+			cardDetails = &transaction.CardDetails
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return out.write(env.stdout, cardDetails, func(w io.Writer) {
+		reward := cardDetails.RewardDetails
+		row(w, "Card", fmt.Sprintf("%s (%s)", cardDetails.CardName,
+			cardDetails.CardKey))
+		row(w, "Earn", fmt.Sprintf("%gx %s", reward.Amount, reward.Currency))
+		row(w, "Value", fmt.Sprintf("%.2f%% (%s valuation)",
+			reward.Value*100, reward.Valuation))
+		if reward.FxFee > 0 {
+			row(w, "Foreign transaction fee", fmt.Sprintf("%.2f%%",
+				reward.FxFee*100))
+		}
+		if reward.SignupBonusValue > 0 {
+			row(w, "Sign-up bonus value", fmt.Sprintf("%.2f%%",
+				reward.SignupBonusValue*100))
+		}
+	})
+}
+
+// txList lists the stored transactions, optionally limited to a wallet,
+// cards and a period.
+func txList(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("tx list", "")
+	walletID := fs.String("wallet", "", "only transactions of this wallet")
+	cardKeys := fs.String("cards", "",
+		"only transactions with these comma separated card keys")
+	from := fs.String("from", "", "earliest date, inclusive, YYYY-MM-DD")
+	to := fs.String("to", "", "latest date, exclusive, YYYY-MM-DD")
+	if err := out.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	filter := store.TransactionFilter{WalletID: *walletID}
+	if *cardKeys != "" {
+		filter.CardKeys = strings.Split(*cardKeys, ",")
+	}
+	for _, bound := range []struct {
+		flag  string
+		value string
+		time  *time.Time
+	}{
+		{"from", *from, &filter.From},
+		{"to", *to, &filter.To},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		at, err := rewards.ParseDate(bound.value, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid -%s: %w", bound.flag, err)
+		}
+		*bound.time = at
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	transactions, err := repo.Transactions.ListTransactions(ctx, &filter)
+	if err != nil {
+		return err
+	}
+	if transactions == nil {
+		transactions = []*store.Transaction{}
+	}
+
+	return out.write(env.stdout, transactions, func(w io.Writer) {
+		row(w, "DATE", "MERCHANT", "AMOUNT", "CARD", "VALUE")
+		for _, transaction := range transactions {
+			merchant := transaction.MerchantDetails.DomainName
+			if merchant == "" {
+				merchant = transaction.MerchantDetails.Descriptor
+			}
+			row(w, transaction.TransactionAt.Local().Format(time.DateTime),
+				merchant,
+				transaction.SpendAmount, transaction.CardDetails.CardName,
+				fmt.Sprintf("%.2f%%",
+					transaction.CardDetails.RewardDetails.Value*100))
+		}
+	})
+}
+
+// txImport imports the charges of a CSV statement exported by a card issuer
+// into a stored wallet, with the column mapping profile given with -profile,
+// and reports which card the wallet would have recommended for each.
+func txImport(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("tx import", "<file>")
+	profileName := fs.String("profile", "",
+		"column mapping profile of the statement, see tx profiles")
+	walletID := fs.String("wallet", "", "ID of the stored wallet")
+	card := fs.String("card", "",
+		"card key of the charges the statement does not tell the card of")
+	cardMap := fs.String("cards", "", "comma separated value=card key "+
+		"pairs mapping the statement's card column to card keys")
+	dryRun := fs.Bool("dry-run", false,
+		"evaluate the charges without storing them")
+	if err := out.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	if *walletID == "" {
+		return errors.New("tx import: -wallet is required")
+	}
+	profiles, err := shop.LoadStatementProfiles(
+		env.cfg.Statements.ProfilesPath)
+	if err != nil {
+		return err
+	}
+	profile, ok := profiles[strings.ToLower(*profileName)]
+	if !ok {
+		return fmt.Errorf("tx import: unknown -profile: %q, want one of %s",
+			*profileName, strings.Join(profileNames(profiles), ", "))
+	}
+	options := shop.StatementOptions{Card: *card, DryRun: *dryRun}
+	if *cardMap != "" {
+		options.Cards = make(map[string]string)
+		for _, pair := range strings.Split(*cardMap, ",") {
+			value, cardKey, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("tx import: invalid -cards pair: %q", pair)
+			}
+			options.Cards[strings.TrimSpace(value)] = strings.TrimSpace(cardKey)
+		}
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	rows, err := shop.ParseStatement(file, profile)
+	if err != nil {
+		return err
+	}
+	service, err := env.service(ctx)
+	if err != nil {
+		return err
+	}
+	wallet, err := loadWallet(ctx, env, *walletID)
+	if err != nil {
+		return err
+	}
+
+	report, err := service.ImportStatement(ctx, wallet, rows, options)
+	if err != nil {
+		return err
+	}
+	if out.format == formatJSON {
+		return out.write(env.stdout, report, nil)
+	}
+	return report.WriteReport(env.stdout)
+}
+
+// txProfiles lists the column mapping profiles statements can be imported
+// with: the built-in ones and those of the configured profiles file.
+func txProfiles(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("tx profiles", "")
+	if err := out.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	profiles, err := shop.LoadStatementProfiles(
+		env.cfg.Statements.ProfilesPath)
+	if err != nil {
+		return err
+	}
+
+	names := profileNames(profiles)
+	list := make([]*shop.StatementProfile, len(names))
+	for i, name := range names {
+		list[i] = profiles[name]
+	}
+	return out.write(env.stdout, list, func(w io.Writer) {
+		row(w, "NAME", "DATE", "DESCRIPTION", "AMOUNT", "CARD")
+		for _, profile := range list {
+			amount := profile.Amount
+			if profile.Debit != "" {
+				amount = profile.Debit + " / " + profile.Credit
+			}
+			row(w, profile.Name, profile.Date, profile.Description, amount,
+				profile.Card)
+		}
+	})
+}
+
+// profileNames returns the names of profiles in order.
+func profileNames(profiles map[string]*shop.StatementProfile) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// walletCreate stores a new wallet holding the cards with the given keys.
+func walletCreate(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("wallet create", "[card key...]")
+	name := fs.String("name", "", "wallet name")
+	valuation := fs.String("valuation", shop.DefaultValuationName,
+		"point valuation: default, cash, api, table or conservative")
+	prioritize := fs.Bool("prioritize-signup-bonus", false,
+		"favor cards whose sign-up bonus is at risk of being missed")
+	if err := out.parse(fs, args, 0, -1); err != nil {
+		return err
+	}
+	if _, err := shop.NewValuation(*valuation, nil); err != nil {
+		return err
+	}
+	service, err := env.service(ctx)
+	if err != nil {
+		return err
+	}
+
+	baseWallet := store.BaseWallet{
+		Name:                  *name,
+		Cards:                 []store.WalletCard{},
+		Valuation:             *valuation,
+		PrioritizeSignupBonus: *prioritize,
+	}
+	for _, cardKey := range fs.Args() {
+		if baseWallet.CardIndex(cardKey) >= 0 {
+			return fmt.Errorf("duplicate card: %s", cardKey)
+		}
+		baseWallet.Cards = append(baseWallet.Cards,
+			store.WalletCard{CardKey: cardKey})
+	}
+	if _, err := service.Cards.Fill(ctx, baseWallet.CardKeys()); err != nil {
+		return err
+	}
+
+	wallet, err := service.Repo.Wallets.InsertWallet(ctx, &baseWallet)
+	if err != nil {
+		return err
+	}
+	return writeWallet(env.stdout, out, wallet)
+}
+
+// walletShow shows the stored wallet with the given ID.
+func walletShow(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("wallet show", "<wallet id>")
+	if err := out.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	wallet, err := repo.Wallets.GetWallet(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return writeWallet(env.stdout, out, wallet)
+}
+
+// walletAddCard adds the card with the given key to a stored wallet.
+func walletAddCard(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("wallet add-card", "<wallet id> <card key>")
+	opened := fs.String("opened", "", "date the card was opened, YYYY-MM-DD")
+	if err := out.parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+	card := store.WalletCard{CardKey: fs.Arg(1)}
+	if *opened != "" {
+		openedOn, err := rewards.ParseDate(*opened, time.UTC)
+		if err != nil {
+			return fmt.Errorf("invalid -opened: %w", err)
+		}
+		card.OpenedOn = openedOn
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	service, err := env.service(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := service.Cards.Fill(ctx, []string{card.CardKey}); err != nil {
+		return err
+	}
+
+	wallet, err := repo.Wallets.AddWalletCard(ctx, fs.Arg(0), card)
+	if err != nil {
+		return err
+	}
+	return writeWallet(env.stdout, out, wallet)
+}
+
+// walletRemoveCard removes the card with the given key from a stored
+// wallet.
+func walletRemoveCard(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("wallet remove-card", "<wallet id> <card key>")
+	if err := out.parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+	repo, err := env.repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	wallet, err := repo.Wallets.RemoveWalletCard(ctx, fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	return writeWallet(env.stdout, out, wallet)
+}
+
+// walletAnalyze reports what each card in a stored wallet earned over the
+// past year against its annual fee.
+func walletAnalyze(ctx context.Context, env *env, args []string) error {
+	fs, out := newFlagSet("wallet analyze", "<wallet id>")
+	if err := out.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	wallet, err := loadWallet(ctx, env, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	analysis, err := wallet.Analyze(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if out.format == formatJSON {
+		return out.write(env.stdout, analysis, nil)
+	}
+	return analysis.WriteReport(env.stdout)
+}
+
+// loadWallet loads the stored wallet with the given ID with its cards.
+func loadWallet(ctx context.Context, env *env,
+	id string) (*shop.BaseWallet, error) {
+
+	service, err := env.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := service.Repo.Wallets.GetWallet(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return service.LoadWallet(ctx, stored)
+}
+
+func writeWallet(w io.Writer, out *output, wallet *store.Wallet) error {
+	return out.write(w, wallet, func(w io.Writer) {
+		row(w, "ID", wallet.ID.Hex())
+		row(w, "Name", wallet.Name)
+		row(w, "Valuation", wallet.Valuation)
+		row(w, "Prioritize sign-up bonus", wallet.PrioritizeSignupBonus)
+		for _, card := range wallet.Cards {
+			opened := ""
+			if !card.OpenedOn.IsZero() {
+				opened = "opened " + card.OpenedOn.Format(time.DateOnly)
+			}
+			row(w, "Card", card.CardKey, opened)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+// Command rewards-mock serves a fake Rewards Credit Card API for offline
+// development. Point polymer at it with -rewards-url and -rewards-key.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards/rewardstest"
+)
+
+func main() {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8090", "listen address")
+	apiKey := fs.String("key", "test", "subscription key to accept as skey")
+	fixturesDir := fs.String("fixtures", "",
+		"directory of fixture JSON files, empty for the built-in ones")
+	latency := fs.Duration("latency", 0, "delay before every response")
+	errorRate := fs.Float64("error-rate", 0,
+		"share of requests failing with 500")
+	rateLimitRate := fs.Float64("rate-limit-rate", 0,
+		"share of requests failing with 429")
+	retryAfter := fs.Duration("retry-after", time.Second,
+		"Retry-After sent with 429")
+	fs.Parse(os.Args[1:])
+
+	fixtures := rewardstest.DefaultFixtures()
+	if *fixturesDir != "" {
+		var err error
+		fixtures, err = rewardstest.LoadFixtures(os.DirFS(*fixturesDir), ".")
+		if err != nil {
+			log.Fatalf("Error loading fixtures: %s", err)
+		}
+	}
+
+	mock := rewardstest.NewServer(fixtures, *apiKey)
+	mock.SetFaults(rewardstest.Faults{
+		Latency:       *latency,
+		ErrorRate:     *errorRate,
+		RateLimitRate: *rateLimitRate,
+		RetryAfter:    *retryAfter,
+	})
+
+	log.Printf("Serving %d cards on http://%s", len(fixtures.CardDetails),
+		*addr)
+	if err := http.ListenAndServe(*addr, mock); err != nil {
+		log.Fatalf("Error serving HTTP: %s", err)
+	}
+}
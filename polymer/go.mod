@@ -2,7 +2,13 @@ module github.com/ayushh-vermaa/polymer
 
 go 1.23.1
 
-require github.com/shopspring/decimal v1.4.0
+require (
+	github.com/mattn/go-sqlite3 v1.14.22
+	github.com/shopspring/decimal v1.4.0
+	go.mongodb.org/mongo-driver v1.16.1
+	golang.org/x/net v0.21.0
+	golang.org/x/sync v0.7.0
+)
 
 require (
 	github.com/golang/snappy v0.0.4 // indirect
@@ -12,8 +18,6 @@ require (
 	github.com/xdg-go/scram v1.1.2 // indirect
 	github.com/xdg-go/stringprep v1.0.4 // indirect
 	github.com/youmark/pkcs8 v0.0.0-20181117223130-1be2e3e5546d // indirect
-	go.mongodb.org/mongo-driver v1.16.1 // indirect
 	golang.org/x/crypto v0.22.0 // indirect
-	golang.org/x/sync v0.7.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
-)
\ No newline at end of file
+)
@@ -0,0 +1,118 @@
+// Package app wires the configuration into the services shared by the
+// polymer commands.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/ayushh-vermaa/polymer/internal/config"
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// NewRewardsClient returns a Rewards API client with the configured
+// settings. It fails if no API key is configured, which only the commands
+// reaching the API need.
+func NewRewardsClient(cfg *config.RewardsConfig) (*rewards.Client, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.New(
+			"rewards.apiKey is required to reach the Rewards API")
+	}
+	return rewards.NewClient(rewardsConfig(cfg)), nil
+}
+
+// rewardsConfig converts the Rewards API section of the configuration.
+func rewardsConfig(cfg *config.RewardsConfig) rewards.Config {
+	return rewards.Config{
+		APIUrl:         cfg.BaseURL,
+		APIKey:         cfg.APIKey.Reveal(),
+		RequestTimeout: cfg.Timeout.Duration,
+		MaxRetries:     cfg.MaxRetries,
+		RateLimit:      cfg.RateLimit,
+		RateBurst:      cfg.RateBurst,
+	}
+}
+
+// NewService returns the service the wallet operations run on over repo,
+// with a card cache refreshing cards as old as the configured max age, and
+// the configured classifier. Without an API key, the card cache only serves
+// the cards already stored.
+func NewService(repo *store.Repository,
+	cfg *config.Config) (*shop.Service, error) {
+
+	classifier, err := LoadClassifier(&cfg.Classifier)
+	if err != nil {
+		return nil, err
+	}
+	var client *rewards.Client
+	if cfg.Rewards.APIKey != "" {
+		client = rewards.NewClient(rewardsConfig(&cfg.Rewards))
+	}
+	cards := shop.NewCardCache(repo.Cards, client,
+		cfg.Store.CardMaxAge.Duration)
+	service := shop.NewService(repo, cards)
+	service.Classifier = classifier
+	service.MinConfidence = cfg.Classifier.MinConfidence
+	return service, nil
+}
+
+// LoadClassifier loads the trained merchant category classifier that
+// categorization falls back to. It returns nil, disabling the fallback, if
+// no model file is configured or it does not exist yet because no
+// classifier was trained.
+func LoadClassifier(cfg *config.ClassifierConfig) (*shop.Classifier, error) {
+	if cfg.ModelPath == "" {
+		return nil, nil
+	}
+	classifier, err := shop.LoadClassifier(cfg.ModelPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load classifier %s: %w",
+			cfg.ModelPath, err)
+	}
+	return classifier, nil
+}
+
+// OpenRepository connects to the storage backend selected in cfg.
+func OpenRepository(ctx context.Context,
+	cfg *config.StoreConfig) (*store.Repository, error) {
+
+	switch cfg.Backend {
+	case config.BackendMongo:
+		client, err := store.ConnectMongoDB(ctx, cfg.MongoURI.Reveal(),
+			cfg.Timeout.Duration)
+		if err != nil {
+			return nil, err
+		}
+		options := store.MongoOptions{
+			DatabaseName:           cfg.DatabaseName,
+			CardCollection:         cfg.Collections.Card,
+			CardRevisionCollection: cfg.Collections.CardRevision,
+			DomainCollection:       cfg.Collections.Domain,
+			DomainAliasCollection:  cfg.Collections.DomainAlias,
+			CategoryRuleCollection: cfg.Collections.CategoryRule,
+			TransactionCollection:  cfg.Collections.Transaction,
+			WalletCollection:       cfg.Collections.Wallet,
+			Timeout:                cfg.Timeout.Duration,
+		}
+		if err := store.CreateMongoIndexes(ctx, client, options); err != nil {
+			return nil, err
+		}
+		return store.NewMongoRepository(client, options), nil
+	case config.BackendSQLite:
+		db, err := store.ConnectSQLite(ctx, cfg.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		return store.NewSQLiteRepository(db), nil
+	case config.BackendMemory:
+		return store.NewMemoryRepository(), nil
+	default:
+		return nil, fmt.Errorf("unknown store backend: %s", cfg.Backend)
+	}
+}
@@ -0,0 +1,498 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// Backends supported by StoreConfig.Backend.
+const (
+	BackendMongo  = "mongo"
+	BackendSQLite = "sqlite"
+	BackendMemory = "memory"
+)
+
+// Config is the complete runtime configuration of polymer.
+type Config struct {
+	Store      StoreConfig      `json:"store"`
+	Rewards    RewardsConfig    `json:"rewards"`
+	Server     ServerConfig     `json:"server"`
+	Classifier ClassifierConfig `json:"classifier"`
+	Statements StatementsConfig `json:"statements"`
+}
+
+// StoreConfig selects and configures the storage backend.
+type StoreConfig struct {
+	Backend      string      `json:"backend"`      // mongo, sqlite or memory
+	MongoURI     Secret      `json:"mongoURI"`     // MongoDB connection string
+	DatabaseName string      `json:"databaseName"` // MongoDB database name
+	SQLitePath   string      `json:"sqlitePath"`   // SQLite database file
+	Timeout      Duration    `json:"timeout"`      // Timeout of a single store request
+	CardMaxAge   Duration    `json:"cardMaxAge"`   // Age at which a stored card is refreshed, 0 for never
+	Collections  Collections `json:"collections"`
+}
+
+// Collections names the MongoDB collections used by the store.
+type Collections struct {
+	Card         string `json:"card"`
+	CardRevision string `json:"cardRevision"`
+	Domain       string `json:"domain"`
+	DomainAlias  string `json:"domainAlias"`
+	CategoryRule string `json:"categoryRule"`
+	Transaction  string `json:"transaction"`
+	Wallet       string `json:"wallet"`
+}
+
+// RewardsConfig configures access to the Rewards Credit Card API.
+type RewardsConfig struct {
+	BaseURL string   `json:"baseURL"` // API base URL, without trailing slash
+	APIKey  Secret   `json:"apiKey"`  // Subscription key sent as skey
+	Timeout Duration `json:"timeout"` // Timeout of a single API request
+
+	MaxRetries int     `json:"maxRetries"` // Retries of a failed request
+	RateLimit  float64 `json:"rateLimit"`  // Requests per second, 0 for no limit
+	RateBurst  int     `json:"rateBurst"`  // Requests allowed at once
+}
+
+// ServerConfig configures the HTTP API server.
+type ServerConfig struct {
+	Address         string   `json:"address"`         // Listen address, host:port
+	ShutdownTimeout Duration `json:"shutdownTimeout"` // Grace period for open requests
+}
+
+// ClassifierConfig configures the merchant category classifier.
+type ClassifierConfig struct {
+	ModelPath     string  `json:"modelPath"`     // Trained model file, empty for none
+	MinConfidence float64 `json:"minConfidence"` // Confidence a prediction needs to be used
+}
+
+// StatementsConfig configures the import of card statements.
+type StatementsConfig struct {
+	ProfilesPath string `json:"profilesPath"` // Column mapping profiles file, empty for the built-in ones only
+}
+
+// Default returns the configuration used when nothing overrides a setting.
+// It carries no credentials, so a usable configuration always needs at least
+// the Rewards API key (and a MongoDB URI for the mongo backend).
+func Default() *Config {
+	return &Config{
+		Store: StoreConfig{
+			Backend:      BackendMongo,
+			DatabaseName: "polymer",
+			SQLitePath:   "polymer.db",
+			Timeout:      Duration{5 * time.Second},
+			CardMaxAge:   Duration{24 * time.Hour},
+			Collections: Collections{
+				Card:         store.CardCollection,
+				CardRevision: store.CardRevisionCollection,
+				Domain:       store.DomainCollection,
+				DomainAlias:  store.DomainAliasCollection,
+				CategoryRule: store.CategoryRuleCollection,
+				Transaction:  store.TransactionCollection,
+				Wallet:       store.WalletCollection,
+			},
+		},
+		Rewards: RewardsConfig{
+			BaseURL:    rewards.DefaultAPIUrl,
+			Timeout:    Duration{10 * time.Second},
+			MaxRetries: 3,
+			RateLimit:  5,
+			RateBurst:  5,
+		},
+		Server: ServerConfig{
+			Address:         "localhost:8080",
+			ShutdownTimeout: Duration{10 * time.Second},
+		},
+		Classifier: ClassifierConfig{
+			MinConfidence: shop.DefaultMinConfidence,
+		},
+	}
+}
+
+// Load builds the configuration from, in increasing order of precedence, the
+// defaults, a JSON config file, POLYMER_* environment variables and the
+// command line flags in args. The config file is named by the -config flag or
+// the POLYMER_CONFIG environment variable. The result is validated before it
+// is returned. Arguments other than flags are an error.
+func Load(name string, args []string) (*Config, error) {
+	cfg, rest, err := LoadCommand(name, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s",
+			strings.Join(rest, " "))
+	}
+	return cfg, nil
+}
+
+// LoadCommand is like Load, but stops parsing flags at the first argument
+// that is not a flag and returns it and the arguments following it, such as
+// a subcommand and its own flags.
+func LoadCommand(name string, args []string) (*Config, []string, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv("POLYMER_CONFIG"),
+		"path of a JSON config file")
+	overrides := registerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(os.LookupEnv); err != nil {
+		return nil, nil, err
+	}
+
+	// Only flags given explicitly override the file and environment.
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		if apply, ok := overrides[f.Name]; ok && flagErr == nil {
+			if err := apply(cfg, f.Value.String()); err != nil {
+				flagErr = fmt.Errorf("invalid -%s: %w", f.Name, err)
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+// loadFile merges the JSON config file at path into cfg. Unknown keys are an
+// error, so that a misspelled setting is not silently ignored.
+func (cfg *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// setting applies a single string value to a field of the configuration.
+type setting func(cfg *Config, value string) error
+
+// settings maps each setting to its environment variable and flag names.
+var settings = []struct {
+	env   string
+	flag  string
+	usage string
+	apply setting
+}{
+	{"POLYMER_STORE_BACKEND", "store",
+		"storage backend: mongo, sqlite or memory",
+		func(cfg *Config, v string) error {
+			cfg.Store.Backend = v
+			return nil
+		}},
+	{"POLYMER_MONGO_URI", "mongo-uri", "MongoDB connection string",
+		func(cfg *Config, v string) error {
+			cfg.Store.MongoURI = Secret(v)
+			return nil
+		}},
+	{"POLYMER_DATABASE_NAME", "database", "MongoDB database name",
+		func(cfg *Config, v string) error {
+			cfg.Store.DatabaseName = v
+			return nil
+		}},
+	{"POLYMER_SQLITE_PATH", "sqlite-path", "SQLite database file",
+		func(cfg *Config, v string) error {
+			cfg.Store.SQLitePath = v
+			return nil
+		}},
+	{"POLYMER_STORE_TIMEOUT", "store-timeout",
+		"timeout of a single store request",
+		func(cfg *Config, v string) error {
+			return cfg.Store.Timeout.Set(v)
+		}},
+	{"POLYMER_CARD_MAX_AGE", "card-max-age",
+		"age at which a stored card is refreshed from the API, 0 for never",
+		func(cfg *Config, v string) error {
+			return cfg.Store.CardMaxAge.Set(v)
+		}},
+	{"POLYMER_CARD_COLLECTION", "card-collection", "MongoDB card collection",
+		func(cfg *Config, v string) error {
+			cfg.Store.Collections.Card = v
+			return nil
+		}},
+	{"POLYMER_CARD_REVISION_COLLECTION", "card-revision-collection",
+		"MongoDB card revision collection",
+		func(cfg *Config, v string) error {
+			cfg.Store.Collections.CardRevision = v
+			return nil
+		}},
+	{"POLYMER_DOMAIN_COLLECTION", "domain-collection",
+		"MongoDB domain collection",
+		func(cfg *Config, v string) error {
+			cfg.Store.Collections.Domain = v
+			return nil
+		}},
+	{"POLYMER_DOMAIN_ALIAS_COLLECTION", "domain-alias-collection",
+		"MongoDB domain alias collection",
+		func(cfg *Config, v string) error {
+			cfg.Store.Collections.DomainAlias = v
+			return nil
+		}},
+	{"POLYMER_CATEGORY_RULE_COLLECTION", "category-rule-collection",
+		"MongoDB category rule collection",
+		func(cfg *Config, v string) error {
+			cfg.Store.Collections.CategoryRule = v
+			return nil
+		}},
+	{"POLYMER_TRANSACTION_COLLECTION", "transaction-collection",
+		"MongoDB transaction collection",
+		func(cfg *Config, v string) error {
+			cfg.Store.Collections.Transaction = v
+			return nil
+		}},
+	{"POLYMER_WALLET_COLLECTION", "wallet-collection",
+		"MongoDB wallet collection",
+		func(cfg *Config, v string) error {
+			cfg.Store.Collections.Wallet = v
+			return nil
+		}},
+	{"POLYMER_REWARDS_API_URL", "rewards-url", "Rewards API base URL",
+		func(cfg *Config, v string) error {
+			cfg.Rewards.BaseURL = v
+			return nil
+		}},
+	{"POLYMER_REWARDS_API_KEY", "rewards-key",
+		"Rewards API subscription key",
+		func(cfg *Config, v string) error {
+			cfg.Rewards.APIKey = Secret(v)
+			return nil
+		}},
+	{"POLYMER_REWARDS_TIMEOUT", "rewards-timeout",
+		"timeout of a single Rewards API request",
+		func(cfg *Config, v string) error {
+			return cfg.Rewards.Timeout.Set(v)
+		}},
+	{"POLYMER_REWARDS_MAX_RETRIES", "rewards-max-retries",
+		"retries of a failed Rewards API request",
+		func(cfg *Config, v string) (err error) {
+			cfg.Rewards.MaxRetries, err = strconv.Atoi(v)
+			return err
+		}},
+	{"POLYMER_REWARDS_RATE_LIMIT", "rewards-rate-limit",
+		"Rewards API requests per second, 0 for no limit",
+		func(cfg *Config, v string) (err error) {
+			cfg.Rewards.RateLimit, err = strconv.ParseFloat(v, 64)
+			return err
+		}},
+	{"POLYMER_REWARDS_RATE_BURST", "rewards-rate-burst",
+		"Rewards API requests allowed at once",
+		func(cfg *Config, v string) (err error) {
+			cfg.Rewards.RateBurst, err = strconv.Atoi(v)
+			return err
+		}},
+	{"POLYMER_SERVER_ADDRESS", "addr", "HTTP API server listen address",
+		func(cfg *Config, v string) error {
+			cfg.Server.Address = v
+			return nil
+		}},
+	{"POLYMER_SHUTDOWN_TIMEOUT", "shutdown-timeout",
+		"grace period for open requests when the server stops",
+		func(cfg *Config, v string) error {
+			return cfg.Server.ShutdownTimeout.Set(v)
+		}},
+	{"POLYMER_CLASSIFIER_MODEL", "classifier-model",
+		"trained merchant category classifier model file",
+		func(cfg *Config, v string) error {
+			cfg.Classifier.ModelPath = v
+			return nil
+		}},
+	{"POLYMER_STATEMENT_PROFILES", "statement-profiles",
+		"statement column mapping profiles file, added to the built-in ones",
+		func(cfg *Config, v string) error {
+			cfg.Statements.ProfilesPath = v
+			return nil
+		}},
+	{"POLYMER_CLASSIFIER_MIN_CONFIDENCE", "classifier-min-confidence",
+		"confidence a classifier prediction needs to be used, 0 to 1",
+		func(cfg *Config, v string) (err error) {
+			cfg.Classifier.MinConfidence, err = strconv.ParseFloat(v, 64)
+			return err
+		}},
+}
+
+// loadEnv applies every setting whose environment variable is set.
+func (cfg *Config) loadEnv(lookup func(string) (string, bool)) error {
+	for _, s := range settings {
+		value, ok := lookup(s.env)
+		if !ok {
+			continue
+		}
+		if err := s.apply(cfg, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", s.env, err)
+		}
+	}
+	return nil
+}
+
+// registerFlags defines a string flag for every setting on fs and returns the
+// setters keyed by flag name.
+func registerFlags(fs *flag.FlagSet) map[string]setting {
+	overrides := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		fs.String(s.flag, "", fmt.Sprintf("%s (env %s)", s.usage, s.env))
+		overrides[s.flag] = s.apply
+	}
+	return overrides
+}
+
+// Validate reports every problem with the configuration in a single error.
+func (cfg *Config) Validate() error {
+	var errs []error
+
+	storage := cfg.Store
+	switch storage.Backend {
+	case BackendMongo:
+		if storage.MongoURI == "" {
+			errs = append(errs, errors.New(
+				"store.mongoURI is required for the mongo backend"))
+		}
+		if storage.DatabaseName == "" {
+			errs = append(errs, errors.New(
+				"store.databaseName is required for the mongo backend"))
+		}
+		errs = append(errs, storage.Collections.validate()...)
+	case BackendSQLite:
+		if storage.SQLitePath == "" {
+			errs = append(errs, errors.New(
+				"store.sqlitePath is required for the sqlite backend"))
+		}
+	case BackendMemory:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"store.backend %q must be one of %s, %s or %s",
+			storage.Backend, BackendMongo, BackendSQLite, BackendMemory))
+	}
+	if storage.Timeout.Duration <= 0 {
+		errs = append(errs, errors.New("store.timeout must be positive"))
+	}
+	if storage.CardMaxAge.Duration < 0 {
+		errs = append(errs, errors.New("store.cardMaxAge must not be negative"))
+	}
+
+	api := cfg.Rewards
+	if u, err := url.Parse(api.BaseURL); err != nil ||
+		(u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf(
+			"rewards.baseURL %q must be an absolute http(s) URL",
+			api.BaseURL))
+	}
+	if api.Timeout.Duration <= 0 {
+		errs = append(errs, errors.New("rewards.timeout must be positive"))
+	}
+	if api.MaxRetries < 0 {
+		errs = append(errs, errors.New(
+			"rewards.maxRetries must not be negative"))
+	}
+	if api.RateLimit < 0 {
+		errs = append(errs, errors.New(
+			"rewards.rateLimit must not be negative"))
+	}
+	if api.RateLimit > 0 && api.RateBurst < 1 {
+		errs = append(errs, errors.New(
+			"rewards.rateBurst must be positive with a rate limit"))
+	}
+
+	server := cfg.Server
+	if server.Address == "" {
+		errs = append(errs, errors.New("server.address is required"))
+	}
+	if server.ShutdownTimeout.Duration <= 0 {
+		errs = append(errs, errors.New(
+			"server.shutdownTimeout must be positive"))
+	}
+
+	classifier := cfg.Classifier
+	if classifier.MinConfidence < 0 || classifier.MinConfidence > 1 {
+		errs = append(errs, errors.New(
+			"classifier.minConfidence must be between 0 and 1"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate checks that every collection is named and that no two share a
+// name.
+func (c Collections) validate() []error {
+	var errs []error
+	seen := make(map[string]string)
+	for _, collection := range []struct{ field, name string }{
+		{"card", c.Card},
+		{"cardRevision", c.CardRevision},
+		{"domain", c.Domain},
+		{"domainAlias", c.DomainAlias},
+		{"categoryRule", c.CategoryRule},
+		{"transaction", c.Transaction},
+		{"wallet", c.Wallet},
+	} {
+		if collection.name == "" {
+			errs = append(errs, fmt.Errorf(
+				"store.collections.%s is required", collection.field))
+			continue
+		}
+		if other, ok := seen[collection.name]; ok {
+			errs = append(errs, fmt.Errorf(
+				"store.collections.%s and store.collections.%s are both %q",
+				other, collection.field, collection.name))
+		}
+		seen[collection.name] = collection.field
+	}
+	return errs
+}
+
+// String returns the configuration as JSON with every secret redacted, so it
+// is safe to log. The MongoDB URI keeps everything but its password.
+func (cfg *Config) String() string {
+	view := struct {
+		Store struct {
+			StoreConfig
+			MongoURI string `json:"mongoURI"`
+		} `json:"store"`
+		Rewards    RewardsConfig    `json:"rewards"`
+		Server     ServerConfig     `json:"server"`
+		Classifier ClassifierConfig `json:"classifier"`
+		Statements StatementsConfig `json:"statements"`
+	}{Rewards: cfg.Rewards, Server: cfg.Server, Classifier: cfg.Classifier,
+		Statements: cfg.Statements}
+	view.Store.StoreConfig = cfg.Store
+	view.Store.MongoURI = RedactURI(cfg.Store.MongoURI.Reveal())
+
+	data, err := json.Marshal(view)
+	if err != nil {
+		return fmt.Sprintf("<invalid config: %v>", err)
+	}
+	return string(data)
+}
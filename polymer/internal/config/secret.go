@@ -0,0 +1,69 @@
+package config
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// Secret is a string setting, such as a password or API key, that must never
+// appear in logs. It formats and marshals as a fixed placeholder; use Reveal
+// to get the actual value.
+type Secret string
+
+// Reveal returns the secret value.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// String implements fmt.Stringer with the value redacted.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// GoString implements fmt.GoStringer with the value redacted.
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+// MarshalJSON implements json.Marshaler with the value redacted.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It is needed because
+// MarshalJSON would otherwise make Secret round trip as the placeholder.
+func (s *Secret) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*s = Secret(value)
+	return nil
+}
+
+// RedactURI returns uri with any password replaced by a placeholder, keeping
+// the rest readable for logs. A URI that cannot be parsed is redacted whole.
+func RedactURI(uri string) string {
+	if uri == "" {
+		return ""
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return redacted
+	}
+	if _, ok := u.User.Password(); !ok {
+		return u.String()
+	}
+	// Swap the escaped password in the formatted URI rather than using
+	// url.UserPassword, which would escape the placeholder.
+	userinfo := u.User.String()
+	username, _, _ := strings.Cut(userinfo, ":")
+	return strings.Replace(u.String(), userinfo+"@",
+		username+":"+redacted+"@", 1)
+}
@@ -1,8 +1,13 @@
 package rewards
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/shopspring/decimal"
 )
@@ -81,22 +86,37 @@ type AnnualSpend struct {
 }
 
 // FetchCardDetail fetches detail from the API in format of CardDetail for the
-// specified cardKey string and returns with any error.
-func FetchCardDetail(cardKey string) (*CardDetail, error) {
+// specified cardKey string with the default client and returns with any
+// error.
+func FetchCardDetail(ctx context.Context, cardKey string) (*CardDetail,
+	error) {
+
+	return defaultClient.FetchCardDetail(ctx, cardKey)
+}
+
+// FetchCardDetail fetches detail from the API in format of CardDetail for the
+// specified cardKey string and returns with any error. An unknown cardKey
+// gives ErrCardNotFound.
+func (client *Client) FetchCardDetail(ctx context.Context,
+	cardKey string) (*CardDetail, error) {
+
 	params := []string{cardKey}
-	resp, err := FetchEndpoint("card_detail", params)
-	if err != nil {
+	resp, err := client.FetchEndpoint(ctx, "card_detail", params)
+	if errors.Is(err, ErrNotFound) {
+		return &CardDetail{}, fmt.Errorf("%w: %w", ErrCardNotFound, err)
+	} else if err != nil {
 		return &CardDetail{}, err
 	}
 	defer resp.Body.Close()
 
 	var cardDetails []CardDetail
 	if err := json.NewDecoder(resp.Body).Decode(&cardDetails); err != nil {
-		return &CardDetail{}, err
+		return &CardDetail{}, fmt.Errorf("%w: %w", ErrDecode, err)
 	}
 
 	if len(cardDetails) == 0 {
-		err := fmt.Errorf("no details found for cardKey: %s", cardKey)
+		err := fmt.Errorf("%w: no details found for cardKey: %s",
+			ErrCardNotFound, cardKey)
 		return &CardDetail{}, err
 	}
 
@@ -104,9 +124,46 @@ func FetchCardDetail(cardKey string) (*CardDetail, error) {
 }
 
 // IsApplicable determines if the bonus applies based on the given merchant
-// category.
+// category: the bonus is for that category or, in the default taxonomy, for
+// one above it that does not exclude it. A Dining bonus thus applies to Fast
+// Food merchants and a Travel bonus to Airfare.
 func (bonus *SpendBonusCategory) IsApplicable(categoryID int) bool {
-	return bonus.SpendBonusCategoryID == categoryID
+	return DefaultTaxonomy().Applies(bonus, categoryID)
+}
+
+// signupAmountPattern matches the first number in a sign-up bonus amount,
+// allowing thousands separators and decimals (e.g. "60,000" or "$200.00").
+var signupAmountPattern = regexp.MustCompile(`\d[\d,]*(\.\d+)?`)
+
+// ParseSignupBonusAmount extracts the number of points, miles or dollars from
+// the free-form SignupBonusAmount, and reports whether it is a cash amount:
+// either written in dollars or for a cash sign-up bonus item.
+func (card *CardDetail) ParseSignupBonusAmount() (float64, bool, error) {
+	match := signupAmountPattern.FindString(card.SignupBonusAmount)
+	if match == "" {
+		return 0, false, fmt.Errorf("%w: no amount in %q",
+			ErrInvalidSignupBonus, card.SignupBonusAmount)
+	}
+
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(match, ",", ""), 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("%w %q: %w", ErrInvalidSignupBonus,
+			card.SignupBonusAmount, err)
+	}
+
+	isCash := strings.Contains(card.SignupBonusAmount, "$") ||
+		strings.Contains(strings.ToLower(card.SignUpBonusItem), "cash")
+	return amount, isCash, nil
+}
+
+// FxFeeRate returns the foreign transaction fee the card charges per dollar
+// spent, or zero if it charges none.
+func (card *CardDetail) FxFeeRate() float64 {
+	if card.IsFxFee != 1 {
+		return 0
+	}
+	percent, _ := decimal.NewFromString("100")
+	return decimal.NewFromFloat(card.FxFee).Div(percent).InexactFloat64()
 }
 
 // RewardValue gets the value of a reward for a card in dollars per dollar after
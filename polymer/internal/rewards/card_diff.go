@@ -0,0 +1,195 @@
+package rewards
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ChangedFields returns the JSON names of the fields of CardDetail whose
+// values differ between before and after, in declaration order. Empty and
+// nil lists are equal.
+func ChangedFields(before, after *CardDetail) []string {
+	var changed []string
+	beforeValue := reflect.ValueOf(before).Elem()
+	afterValue := reflect.ValueOf(after).Elem()
+	fields := beforeValue.Type()
+	for i := 0; i < fields.NumField(); i++ {
+		if !equalFields(beforeValue.Field(i), afterValue.Field(i)) {
+			changed = append(changed, jsonName(fields.Field(i)))
+		}
+	}
+	return changed
+}
+
+// equalFields reports whether two values of a CardDetail field are equal.
+func equalFields(a, b reflect.Value) bool {
+	if a.Kind() == reflect.Slice && a.Len() == 0 && b.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// jsonName returns the name of field in JSON.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// FieldChange is a change to a single field, named as in JSON.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// SpendBonusChange lists the changes to a spend bonus category present in
+// both versions of a card.
+type SpendBonusChange struct {
+	SpendBonusCategoryID   int           `json:"spendBonusCategoryID"`
+	SpendBonusCategoryName string        `json:"spendBonusCategoryName"`
+	Changes                []FieldChange `json:"changes"`
+}
+
+// CardDiff is the field-level difference between two versions of a
+// CardDetail. Spend bonus categories are matched by SpendBonusCategoryID, in
+// order of occurrence when a card lists an ID more than once; benefits and
+// annual spend bonuses are matched by value.
+type CardDiff struct {
+	Fields              []FieldChange        `json:"fields,omitempty"`
+	AddedSpendBonuses   []SpendBonusCategory `json:"addedSpendBonuses,omitempty"`
+	RemovedSpendBonuses []SpendBonusCategory `json:"removedSpendBonuses,omitempty"`
+	ChangedSpendBonuses []SpendBonusChange   `json:"changedSpendBonuses,omitempty"`
+	AddedBenefits       []Benefit            `json:"addedBenefits,omitempty"`
+	RemovedBenefits     []Benefit            `json:"removedBenefits,omitempty"`
+	AddedAnnualSpend    []AnnualSpend        `json:"addedAnnualSpend,omitempty"`
+	RemovedAnnualSpend  []AnnualSpend        `json:"removedAnnualSpend,omitempty"`
+}
+
+// IsEmpty reports whether the diff has no changes.
+func (diff *CardDiff) IsEmpty() bool {
+	return len(diff.Fields) == 0 && len(diff.AddedSpendBonuses) == 0 &&
+		len(diff.RemovedSpendBonuses) == 0 &&
+		len(diff.ChangedSpendBonuses) == 0 && len(diff.AddedBenefits) == 0 &&
+		len(diff.RemovedBenefits) == 0 && len(diff.AddedAnnualSpend) == 0 &&
+		len(diff.RemovedAnnualSpend) == 0
+}
+
+// DiffCardDetail returns the changes from before to after. A nil before card
+// is treated as empty, so every set field of after is reported.
+func DiffCardDetail(before, after *CardDetail) *CardDiff {
+	if before == nil {
+		before = &CardDetail{}
+	}
+
+	diff := &CardDiff{
+		Fields: fieldChanges(reflect.ValueOf(before).Elem(),
+			reflect.ValueOf(after).Elem()),
+	}
+	diffSpendBonuses(diff, before.SpendBonusCategory, after.SpendBonusCategory)
+	diff.RemovedBenefits, diff.AddedBenefits = diffLists(before.Benefit,
+		after.Benefit)
+	diff.RemovedAnnualSpend, diff.AddedAnnualSpend = diffLists(
+		before.AnnualSpend, after.AnnualSpend)
+	return diff
+}
+
+// fieldChanges returns the changes to the scalar fields of two values of the
+// same struct type. List fields are skipped.
+func fieldChanges(before, after reflect.Value) []FieldChange {
+	var changes []FieldChange
+	fields := before.Type()
+	for i := 0; i < fields.NumField(); i++ {
+		if before.Field(i).Kind() == reflect.Slice ||
+			equalFields(before.Field(i), after.Field(i)) {
+			continue
+		}
+		changes = append(changes, FieldChange{
+			Field: jsonName(fields.Field(i)),
+			Old:   before.Field(i).Interface(),
+			New:   after.Field(i).Interface(),
+		})
+	}
+	return changes
+}
+
+// diffSpendBonuses records the spend bonus categories added, removed and
+// changed between before and after in diff.
+func diffSpendBonuses(diff *CardDiff, before, after []SpendBonusCategory) {
+	// The nth occurrence of an ID in before matches its nth occurrence in
+	// after.
+	type occurrence struct{ id, n int }
+	occurrences := func(bonuses []SpendBonusCategory) []occurrence {
+		seen := make(map[int]int)
+		keys := make([]occurrence, len(bonuses))
+		for i, bonus := range bonuses {
+			id := bonus.SpendBonusCategoryID
+			keys[i] = occurrence{id, seen[id]}
+			seen[id]++
+		}
+		return keys
+	}
+
+	newIndex := make(map[occurrence]int)
+	for i, key := range occurrences(after) {
+		newIndex[key] = i
+	}
+
+	matched := make([]bool, len(after))
+	for i, key := range occurrences(before) {
+		j, ok := newIndex[key]
+		if !ok {
+			diff.RemovedSpendBonuses = append(diff.RemovedSpendBonuses,
+				before[i])
+			continue
+		}
+		matched[j] = true
+		changes := fieldChanges(reflect.ValueOf(before[i]),
+			reflect.ValueOf(after[j]))
+		if len(changes) > 0 {
+			diff.ChangedSpendBonuses = append(diff.ChangedSpendBonuses,
+				SpendBonusChange{
+					SpendBonusCategoryID:   after[j].SpendBonusCategoryID,
+					SpendBonusCategoryName: after[j].SpendBonusCategoryName,
+					Changes:                changes,
+				})
+		}
+	}
+
+	for j, bonus := range after {
+		if !matched[j] {
+			diff.AddedSpendBonuses = append(diff.AddedSpendBonuses, bonus)
+		}
+	}
+}
+
+// diffLists returns the entries of before missing from after and the entries
+// of after missing from before, counting repeated entries.
+func diffLists[T comparable](before, after []T) (removed, added []T) {
+	counts := make(map[T]int)
+	for _, entry := range after {
+		counts[entry]++
+	}
+	for _, entry := range before {
+		if counts[entry] > 0 {
+			counts[entry]--
+			continue
+		}
+		removed = append(removed, entry)
+	}
+
+	counts = make(map[T]int)
+	for _, entry := range before {
+		counts[entry]++
+	}
+	for _, entry := range after {
+		if counts[entry] > 0 {
+			counts[entry]--
+			continue
+		}
+		added = append(added, entry)
+	}
+	return removed, added
+}
@@ -0,0 +1,98 @@
+package rewards_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+)
+
+// gold returns a card to derive changed versions from.
+func gold() *rewards.CardDetail {
+	return &rewards.CardDetail{
+		CardKey:   "amex-gold",
+		CardName:  "Gold",
+		AnnualFee: 250,
+		SpendBonusCategory: []rewards.SpendBonusCategory{
+			{SpendBonusCategoryID: 2, SpendBonusCategoryName: "Dining",
+				EarnMultiplier: 4},
+			{SpendBonusCategoryID: 9, SpendBonusCategoryName: "Airfare",
+				EarnMultiplier: 3},
+		},
+		Benefit: []rewards.Benefit{{BenefitTitle: "Dining credit"}},
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(card *rewards.CardDetail)
+		want   []string
+	}{
+		{"unchanged", func(card *rewards.CardDetail) {}, nil},
+		{"empty and nil lists", func(card *rewards.CardDetail) {
+			card.AnnualSpend = []rewards.AnnualSpend{}
+		}, nil},
+		{"fields in declaration order", func(card *rewards.CardDetail) {
+			card.AnnualFee = 325
+			card.CardName = "Gold Card"
+		}, []string{"cardName", "annualFee"}},
+		{"list", func(card *rewards.CardDetail) {
+			card.SpendBonusCategory[0].EarnMultiplier = 3
+		}, []string{"spendBonusCategory"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			after := gold()
+			test.change(after)
+			got := rewards.ChangedFields(gold(), after)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("ChangedFields() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDiffCardDetail(t *testing.T) {
+	after := gold()
+	after.AnnualFee = 325
+	after.SpendBonusCategory[0].EarnMultiplier = 3
+	after.SpendBonusCategory = append(after.SpendBonusCategory[:1],
+		rewards.SpendBonusCategory{SpendBonusCategoryID: 5,
+			SpendBonusCategoryName: "Supermarkets", EarnMultiplier: 4})
+	after.Benefit = []rewards.Benefit{{BenefitTitle: "Uber Cash"}}
+
+	diff := rewards.DiffCardDetail(gold(), after)
+	if len(diff.Fields) != 1 || diff.Fields[0].Field != "annualFee" ||
+		diff.Fields[0].Old != 250.0 || diff.Fields[0].New != 325.0 {
+		t.Errorf("Fields = %+v, want annualFee from 250 to 325", diff.Fields)
+	}
+	if len(diff.ChangedSpendBonuses) != 1 ||
+		diff.ChangedSpendBonuses[0].SpendBonusCategoryID != 2 ||
+		diff.ChangedSpendBonuses[0].Changes[0].Field != "earnMultiplier" {
+		t.Errorf("ChangedSpendBonuses = %+v, want the dining multiplier",
+			diff.ChangedSpendBonuses)
+	}
+	if len(diff.AddedSpendBonuses) != 1 ||
+		diff.AddedSpendBonuses[0].SpendBonusCategoryID != 5 {
+		t.Errorf("AddedSpendBonuses = %+v, want supermarkets",
+			diff.AddedSpendBonuses)
+	}
+	if len(diff.RemovedSpendBonuses) != 1 ||
+		diff.RemovedSpendBonuses[0].SpendBonusCategoryID != 9 {
+		t.Errorf("RemovedSpendBonuses = %+v, want airfare",
+			diff.RemovedSpendBonuses)
+	}
+	if len(diff.AddedBenefits) != 1 || len(diff.RemovedBenefits) != 1 {
+		t.Errorf("benefits added %+v and removed %+v, want one each",
+			diff.AddedBenefits, diff.RemovedBenefits)
+	}
+
+	if diff := rewards.DiffCardDetail(gold(), gold()); !diff.IsEmpty() {
+		t.Errorf("DiffCardDetail() of equal cards = %+v, want empty", diff)
+	}
+	diff = rewards.DiffCardDetail(nil, gold())
+	if len(diff.AddedSpendBonuses) != 2 || len(diff.Fields) != 3 {
+		t.Errorf("DiffCardDetail(nil) = %+v, want every set field", diff)
+	}
+}
@@ -1,7 +1,9 @@
 package rewards
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 )
 
 // CardListResponse represents API response to fetchCardList.
@@ -13,11 +15,19 @@ type CardListResponse []struct {
 	} `json:"card"`
 }
 
+// FetchCardList fetches list of cards from the API in format of
+// CardListResponse with the default client and returns with any error.
+func FetchCardList(ctx context.Context) (*CardListResponse, error) {
+	return defaultClient.FetchCardList(ctx)
+}
+
 // FetchCardList fetches list of cards from the API in format of
 // CardListResponse and returns with any error.
-func FetchCardList() (*CardListResponse, error) {
+func (client *Client) FetchCardList(ctx context.Context) (*CardListResponse,
+	error) {
+
 	var params []string
-	resp, err := FetchEndpoint("card_list", params)
+	resp, err := client.FetchEndpoint(ctx, "card_list", params)
 	if err != nil {
 		return nil, err
 	}
@@ -25,7 +35,7 @@ func FetchCardList() (*CardListResponse, error) {
 
 	var cardList CardListResponse
 	if err := json.NewDecoder(resp.Body).Decode(&cardList); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrDecode, err)
 	}
 
 	return &cardList, nil
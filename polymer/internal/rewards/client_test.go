@@ -0,0 +1,245 @@
+package rewards_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/internal/rewards/rewardstest"
+)
+
+const testAPIKey = "test-key"
+
+// newTestClient returns a client of a mock API serving fixtures, retrying
+// up to maxRetries times with short backoffs, and the mock.
+func newTestClient(t *testing.T, fixtures *rewardstest.Fixtures,
+	maxRetries int) (*rewards.Client, *rewardstest.Server) {
+
+	t.Helper()
+	mock := rewardstest.NewServer(fixtures, testAPIKey)
+	ts := httptest.NewServer(mock)
+	t.Cleanup(ts.Close)
+
+	client := rewards.NewClient(mock.Config(ts.URL))
+	client.Retry = rewards.RetryPolicy{
+		MaxRetries: maxRetries,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   10 * time.Millisecond,
+	}
+	return client, mock
+}
+
+func TestFetchCardList(t *testing.T) {
+	client, _ := newTestClient(t, rewardstest.DefaultFixtures(), 0)
+
+	cardList, err := client.FetchCardList(context.Background())
+	if err != nil {
+		t.Fatalf("FetchCardList() error = %v", err)
+	}
+	found := false
+	for _, issuer := range *cardList {
+		for _, card := range issuer.Card {
+			found = found || card.CardKey == "chase-sapphirepreferred"
+		}
+	}
+	if !found {
+		t.Errorf("FetchCardList() = %+v, want chase-sapphirepreferred",
+			*cardList)
+	}
+}
+
+func TestFetchCardDetail(t *testing.T) {
+	client, _ := newTestClient(t, rewardstest.DefaultFixtures(), 0)
+
+	card, err := client.FetchCardDetail(context.Background(), "amex-gold")
+	if err != nil {
+		t.Fatalf("FetchCardDetail() error = %v", err)
+	}
+	if card.CardKey != "amex-gold" || card.CardName == "" {
+		t.Errorf("FetchCardDetail() = %q %q, want amex-gold with a name",
+			card.CardKey, card.CardName)
+	}
+}
+
+func TestFetchRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		failures   []int
+		wantErr    error
+		requests   int
+	}{
+		{"no failure", 2, nil, nil, 1},
+		{"server error", 2, []int{500}, nil, 2},
+		{"rate limited", 2, []int{429, 503}, nil, 3},
+		{"retries exhausted", 1, []int{500, 502}, rewards.ErrUnexpectedStatus,
+			2},
+		{"not retried", 2, []int{401}, rewards.ErrUnauthorized, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, mock := newTestClient(t, rewardstest.DefaultFixtures(),
+				test.maxRetries)
+			mock.FailNext(test.failures...)
+
+			_, err := client.FetchCardDetail(context.Background(),
+				"citi-doublecash")
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("FetchCardDetail() error = %v, want %v", err,
+					test.wantErr)
+			}
+			if got := mock.Requests(); got != test.requests {
+				t.Errorf("requests = %d, want %d", got, test.requests)
+			}
+		})
+	}
+}
+
+func TestFetchHonorsRetryAfter(t *testing.T) {
+	client, mock := newTestClient(t, rewardstest.DefaultFixtures(), 1)
+	mock.SetFaults(rewardstest.Faults{RetryAfter: time.Second})
+	mock.FailNext(http.StatusTooManyRequests)
+
+	start := time.Now()
+	if _, err := client.FetchCardList(context.Background()); err != nil {
+		t.Fatalf("FetchCardList() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want at least the 1s of Retry-After",
+			elapsed)
+	}
+	if got := mock.Requests(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+func TestFetchRetryAfterPastDeadline(t *testing.T) {
+	client, mock := newTestClient(t, rewardstest.DefaultFixtures(), 1)
+	mock.SetFaults(rewardstest.Faults{RetryAfter: time.Minute})
+	mock.FailNext(http.StatusTooManyRequests)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := client.FetchCardList(ctx)
+
+	var statusErr *rewards.StatusError
+	if !errors.As(err, &statusErr) || statusErr.RetryAfter != time.Minute {
+		t.Fatalf("FetchCardList() error = %v, want a 429 asking for 1m", err)
+	}
+	if got := mock.Requests(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cardKey string
+		status  int
+		wantErr []error
+	}{
+		{"unauthorized", "amex-gold", 401,
+			[]error{rewards.ErrUnauthorized, rewards.ErrUnexpectedStatus}},
+		{"forbidden", "amex-gold", 403, []error{rewards.ErrUnauthorized}},
+		{"rate limited", "amex-gold", 429, []error{rewards.ErrQuotaExceeded}},
+		{"not found", "amex-gold", 404,
+			[]error{rewards.ErrCardNotFound, rewards.ErrNotFound}},
+		{"unknown card", "no-such-card", 0,
+			[]error{rewards.ErrCardNotFound}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, mock := newTestClient(t, rewardstest.DefaultFixtures(),
+				0)
+			mock.FailNext(test.status)
+
+			_, err := client.FetchCardDetail(context.Background(),
+				test.cardKey)
+			for _, want := range test.wantErr {
+				if !errors.Is(err, want) {
+					t.Errorf("FetchCardDetail() error = %v, want %v", err,
+						want)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchWrongKey(t *testing.T) {
+	client, _ := newTestClient(t, rewardstest.DefaultFixtures(), 0)
+	client.APIKey = "wrong-key"
+
+	_, err := client.FetchCardList(context.Background())
+	if !errors.Is(err, rewards.ErrUnauthorized) {
+		t.Errorf("FetchCardList() error = %v, want %v", err,
+			rewards.ErrUnauthorized)
+	}
+}
+
+func TestFetchQuotaForbidden(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"statusCode": 403, "message": "Out of call `+
+				`volume quota. Quota will be replenished in 10:00:00."}`,
+				http.StatusForbidden)
+		}))
+	defer ts.Close()
+	client := rewards.NewClient(rewards.Config{APIUrl: ts.URL,
+		APIKey: testAPIKey})
+
+	_, err := client.FetchCardList(context.Background())
+	if !errors.Is(err, rewards.ErrQuotaExceeded) {
+		t.Errorf("FetchCardList() error = %v, want %v", err,
+			rewards.ErrQuotaExceeded)
+	}
+	if errors.Is(err, rewards.ErrUnauthorized) {
+		t.Errorf("FetchCardList() error = %v, want no %v", err,
+			rewards.ErrUnauthorized)
+	}
+}
+
+func TestFetchDecodeErrors(t *testing.T) {
+	fixtures := &rewardstest.Fixtures{
+		CardList: []byte(`{"cardIssuer": `),
+		CardDetails: map[string][]byte{
+			"bad-card": []byte(`not json`),
+		},
+	}
+	client, _ := newTestClient(t, fixtures, 0)
+
+	if _, err := client.FetchCardList(
+		context.Background()); !errors.Is(err, rewards.ErrDecode) {
+		t.Errorf("FetchCardList() error = %v, want %v", err,
+			rewards.ErrDecode)
+	}
+	_, err := client.FetchCardDetail(context.Background(), "bad-card")
+	if !errors.Is(err, rewards.ErrDecode) {
+		t.Errorf("FetchCardDetail() error = %v, want %v", err,
+			rewards.ErrDecode)
+	}
+}
+
+func TestFetchRedactsKey(t *testing.T) {
+	const secret = "very-secret-key"
+	ts := httptest.NewServer(http.NotFoundHandler())
+	ts.Close()
+	client := rewards.NewClient(rewards.Config{APIUrl: ts.URL,
+		APIKey: secret})
+
+	_, err := client.FetchCardDetail(context.Background(), "amex-gold")
+	if !errors.Is(err, rewards.ErrRequestFailed) {
+		t.Fatalf("FetchCardDetail() error = %v, want %v", err,
+			rewards.ErrRequestFailed)
+	}
+	if strings.Contains(err.Error(), secret) {
+		t.Errorf("FetchCardDetail() error = %q, leaks the API key", err)
+	}
+	if !strings.Contains(err.Error(), "skey=[REDACTED]") {
+		t.Errorf("FetchCardDetail() error = %q, want the key redacted", err)
+	}
+}
@@ -1,53 +1,192 @@
 package rewards
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
 const (
-	APIUrl         = "https://rewardscc-api.azure-api.net/v1"
-	APIKey         = "a33f53b560b747e9a29bb7b6be234a45"
-	RequestTimeout = 10 * time.Second
+	DefaultAPIUrl         = "https://rewardscc-api.azure-api.net/v1"
+	DefaultRequestTimeout = 10 * time.Second
+	DefaultMaxRetries     = 3
+	DefaultRateLimit      = 5 // Requests per second
+	DefaultRateBurst      = 5
 )
 
+// Config holds the settings used to reach the Rewards Credit Card API.
+type Config struct {
+	APIUrl         string        // Base URL, without trailing slash
+	APIKey         string        // Subscription key sent as skey
+	RequestTimeout time.Duration // Timeout of a single request
+	MaxRetries     int           // Retries of a failed request, 0 for none
+	RateLimit      float64       // Requests per second, 0 for no limit
+	RateBurst      int           // Requests allowed at once
+}
+
+// DefaultConfig returns the Config used until Configure is called. It has no
+// API key.
+func DefaultConfig() Config {
+	return Config{
+		APIUrl:         DefaultAPIUrl,
+		RequestTimeout: DefaultRequestTimeout,
+		MaxRetries:     DefaultMaxRetries,
+		RateLimit:      DefaultRateLimit,
+		RateBurst:      DefaultRateBurst,
+	}
+}
+
 var Endpoints = map[string]string{
 	"card_list":   "creditcard-cardlist",
 	"card_detail": "creditcard-detail-bycard",
 }
 
+// maxErrorBody limits how much of an error response body is kept in a
+// StatusError.
+const maxErrorBody = 512
+
+// Client makes requests to the Rewards Credit Card API. Failed requests are
+// retried as set by Retry, and every attempt first waits for the Limiter, if
+// any, so the client stays within the API plan's quota. A Client is safe for
+// concurrent use; its fields must not change once it is in use.
+type Client struct {
+	BaseURL    string       // Base URL, without trailing slash
+	APIKey     string       // Subscription key sent as skey
+	HTTPClient *http.Client // Client making the requests
+	Retry      RetryPolicy
+	Limiter    *RateLimiter // Limits the request rate, if set
+}
+
+// NewClient returns a Client with the given settings.
+func NewClient(cfg Config) *Client {
+	client := &Client{
+		BaseURL:    cfg.APIUrl,
+		APIKey:     cfg.APIKey,
+		HTTPClient: &http.Client{Timeout: cfg.RequestTimeout},
+		Retry:      DefaultRetryPolicy(),
+	}
+	client.Retry.MaxRetries = cfg.MaxRetries
+	if cfg.RateLimit > 0 {
+		client.Limiter = NewRateLimiter(cfg.RateLimit, cfg.RateBurst)
+	}
+	return client
+}
+
+// defaultClient is used by the package level functions, and shared by them so
+// connections are reused and the rate limit applies to every request.
+var defaultClient = NewClient(DefaultConfig())
+
+// Configure sets the Config used by every subsequent API request made with
+// the package level functions. It is meant to be called once at startup,
+// before any request is made.
+func Configure(cfg Config) {
+	defaultClient = NewClient(cfg)
+}
+
+// DefaultClient returns the Client used by the package level functions.
+func DefaultClient() *Client {
+	return defaultClient
+}
+
+// FetchEndpoint makes an authenticated GET request to the specified endpoint
+// with the default client.
+func FetchEndpoint(ctx context.Context, endpointName string,
+	params []string) (*http.Response, error) {
+
+	return defaultClient.FetchEndpoint(ctx, endpointName, params)
+}
+
 // FetchEndpoint makes an authenticated GET request to the specified endpoint.
-func FetchEndpoint(endpointName string, params []string) (*http.Response,
-	error) {
+// Requests failing with 429 Too Many Requests, a 5xx status or a network
+// error are retried with backoff, honoring any Retry-After header. Each
+// attempt is abandoned when ctx is done or the request timeout elapses,
+// whichever comes first. A status other than 200 OK is returned as a
+// *StatusError, with the response closed.
+func (client *Client) FetchEndpoint(ctx context.Context, endpointName string,
+	params []string) (*http.Response, error) {
 
 	endpoint, exists := Endpoints[endpointName]
 	if !exists {
-		return nil, fmt.Errorf("endpoint '%s' does not exist", endpointName)
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownEndpoint, endpointName)
 	}
 
-	url := fmt.Sprintf("%s/%s", APIUrl, endpoint)
+	endpointURL := fmt.Sprintf("%s/%s", client.BaseURL, endpoint)
 	for _, param := range params {
-		url += "/" + param
+		endpointURL += "/" + url.PathEscape(param)
+	}
+	endpointURL += "?skey=" + url.QueryEscape(client.APIKey)
+
+	for attempt := 0; ; attempt++ {
+		if client.Limiter != nil {
+			if err := client.Limiter.Wait(ctx); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrRequestFailed, err)
+			}
+		}
+
+		resp, err := client.get(ctx, endpointURL)
+		if err == nil {
+			return resp, nil
+		}
+
+		delay, retry := client.Retry.delay(ctx, attempt, err)
+		if !retry {
+			return nil, err
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrRequestFailed, err)
+		}
 	}
-	url += "?skey=" + APIKey
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// get makes a single GET request to endpointURL.
+func (client *Client) get(ctx context.Context,
+	endpointURL string) (*http.Response, error) {
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpointURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, fmt.Errorf("failed to create HTTP request: %w",
+			client.redactKey(err))
 	}
 	req.Header.Add("Accept", "application/json")
 
-	client := &http.Client{
-		Timeout: RequestTimeout,
+	httpClient := client.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
 	}
-
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
-	} else if resp.StatusCode != http.StatusOK {
-		return resp, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("%w: %w", ErrRequestFailed,
+			client.redactKey(err))
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	// Drain the rest so the connection can be reused.
+	io.Copy(io.Discard, resp.Body)
+
+	return nil, &StatusError{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(body)),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"),
+			time.Now()),
 	}
+}
 
-	return resp, nil
+// redactKey removes the API key from the URL carried by a *url.Error, so
+// request errors can be logged safely.
+func (client *Client) redactKey(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && client.APIKey != "" {
+		urlErr.URL = strings.ReplaceAll(urlErr.URL,
+			"skey="+url.QueryEscape(client.APIKey), "skey=[REDACTED]")
+	}
+	return err
 }
@@ -0,0 +1,110 @@
+package rewards
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dateTimeLayouts and dateLayouts list the formats seen in Rewards API
+// responses, tried in order. Values in dateLayouts denote a whole day.
+var dateTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006/01/02",
+	"01/02/2006",
+	"1/2/2006",
+	"01-02-2006",
+	"1-2-2006",
+	"01/02/06",
+	"1/2/06",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"2 Jan 2006",
+	"2 January 2006",
+	"20060102",
+}
+
+// ParseDate parses a date as returned by the Rewards API, accepting any of
+// dateTimeLayouts and dateLayouts. Dates without a zone are read in loc. An
+// empty value, or the all-zero placeholder some records carry, parses as the
+// zero time.
+func ParseDate(value string, loc *time.Location) (time.Time, error) {
+	date, _, err := parseDate(value, loc)
+	return date, err
+}
+
+// parseDate is ParseDate, also reporting whether value only named a day.
+func parseDate(value string, loc *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.HasPrefix(value, "0000-00-00") {
+		return time.Time{}, false, nil
+	}
+
+	for _, layout := range dateTimeLayouts {
+		if date, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return date, false, nil
+		}
+	}
+	for _, layout := range dateLayouts {
+		if date, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return date, true, nil
+		}
+	}
+
+	return time.Time{}, false, fmt.Errorf("unrecognized date format: %q",
+		value)
+}
+
+// IsInDateLimit reports whether a purchase made at falls within the bonus's
+// date limit. Bonuses without a date limit always do. Both limit dates are
+// inclusive and an empty one leaves that side of the window open. A bonus
+// whose limit dates cannot be parsed is treated as outside its window, since
+// there is no telling whether it is active.
+func (bonus *SpendBonusCategory) IsInDateLimit(at time.Time) bool {
+	if bonus.IsDateLimit != 1 {
+		return true
+	}
+
+	begin, end, err := bonus.DateLimit(at.Location())
+	if err != nil {
+		return false
+	}
+
+	if !begin.IsZero() && at.Before(begin) {
+		return false
+	}
+	if !end.IsZero() && !at.Before(end) {
+		return false
+	}
+	return true
+}
+
+// DateLimit returns the bounds [begin, end) of the bonus's date limit, with
+// dates read in loc. A bound is zero when its limit date is empty. An end date
+// without a time of day covers that whole day.
+func (bonus *SpendBonusCategory) DateLimit(loc *time.Location) (time.Time,
+	time.Time, error) {
+
+	begin, err := ParseDate(bonus.LimitBeginDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid begin date: %w",
+			err)
+	}
+
+	end, wholeDay, err := parseDate(bonus.LimitEndDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date: %w",
+			err)
+	}
+	if wholeDay {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	return begin, end, nil
+}
@@ -0,0 +1,106 @@
+package rewards_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+)
+
+func TestParseDate(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	day := time.Date(2026, time.March, 5, 0, 0, 0, 0, newYork)
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", time.Time{}, false},
+		{"  ", time.Time{}, false},
+		{"0000-00-00", time.Time{}, false},
+		{"0000-00-00 00:00:00", time.Time{}, false},
+		{"2026-03-05", day, false},
+		{" 2026-03-05 ", day, false},
+		{"2026/03/05", day, false},
+		{"03/05/2026", day, false},
+		{"3/5/2026", day, false},
+		{"03-05-2026", day, false},
+		{"3/5/26", day, false},
+		{"Mar 5, 2026", day, false},
+		{"March 5, 2026", day, false},
+		{"5 Mar 2026", day, false},
+		{"20260305", day, false},
+		{"2026-03-05T09:30:00", day.Add(9*time.Hour + 30*time.Minute),
+			false},
+		{"2026-03-05 09:30:00", day.Add(9*time.Hour + 30*time.Minute),
+			false},
+		{"2026-03-05T09:30:00Z",
+			time.Date(2026, time.March, 5, 9, 30, 0, 0, time.UTC), false},
+		{"05.03.2026", time.Time{}, true},
+		{"soon", time.Time{}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := rewards.ParseDate(test.value, newYork)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseDate(%q) error = %v, want error %v",
+					test.value, err, test.wantErr)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("ParseDate(%q) = %v, want %v", test.value, got,
+					test.want)
+			}
+		})
+	}
+}
+
+func TestIsInDateLimit(t *testing.T) {
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	limited := func(begin, end string) *rewards.SpendBonusCategory {
+		return &rewards.SpendBonusCategory{IsDateLimit: 1,
+			LimitBeginDate: begin, LimitEndDate: end}
+	}
+
+	tests := []struct {
+		name  string
+		bonus *rewards.SpendBonusCategory
+		at    time.Time
+		want  bool
+	}{
+		{"no date limit", &rewards.SpendBonusCategory{
+			LimitBeginDate: "2026-04-01", LimitEndDate: "2026-06-30"},
+			at(time.January, 1, 0), true},
+		{"before the window", limited("2026-04-01", "2026-06-30"),
+			at(time.March, 31, 23), false},
+		{"on the first day", limited("2026-04-01", "2026-06-30"),
+			at(time.April, 1, 0), true},
+		{"during the last day", limited("2026-04-01", "2026-06-30"),
+			at(time.June, 30, 23), true},
+		{"after the last day", limited("2026-04-01", "2026-06-30"),
+			at(time.July, 1, 0), false},
+		{"end with a time of day", limited("2026-04-01",
+			"2026-06-30 12:00:00"), at(time.June, 30, 13), false},
+		{"open begin", limited("", "2026-06-30"), at(time.January, 1, 0),
+			true},
+		{"open end", limited("2026-04-01", ""), at(time.December, 31, 0),
+			true},
+		{"placeholder dates", limited("0000-00-00", "0000-00-00"),
+			at(time.March, 1, 0), true},
+		{"unreadable date", limited("2026-04-01", "end of June"),
+			at(time.May, 1, 0), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.bonus.IsInDateLimit(test.at); got != test.want {
+				t.Errorf("IsInDateLimit(%v) = %v, want %v", test.at, got,
+					test.want)
+			}
+		})
+	}
+}
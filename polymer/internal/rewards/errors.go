@@ -0,0 +1,98 @@
+package rewards
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnknownEndpoint is returned for an endpoint name missing from
+	// Endpoints.
+	ErrUnknownEndpoint = errors.New("unknown endpoint")
+
+	// ErrRequestFailed is returned when the API could not be reached.
+	ErrRequestFailed = errors.New("HTTP request failed")
+
+	// ErrUnexpectedStatus is returned when the API answers with a status
+	// other than 200 OK.
+	ErrUnexpectedStatus = errors.New("unexpected status code")
+
+	// ErrNotFound is returned when the API answers 404 Not Found.
+	ErrNotFound = errors.New("not found")
+
+	// ErrUnauthorized is returned when the API rejects the subscription key.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrQuotaExceeded is returned when the API plan's rate limit or call
+	// volume quota is exceeded.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+
+	// ErrDecode is returned when the API response body cannot be decoded.
+	ErrDecode = errors.New("failed to decode response")
+
+	// ErrCardNotFound is returned when the API has no detail for a card key.
+	ErrCardNotFound = errors.New("card not found")
+
+	// ErrInvalidSignupBonus is returned when no amount can be read from a
+	// card's sign-up bonus.
+	ErrInvalidSignupBonus = errors.New("invalid sign-up bonus amount")
+)
+
+// StatusError is returned when the API answers with a status other than
+// 200 OK. It matches ErrUnexpectedStatus and, depending on the status, one of
+// ErrNotFound, ErrUnauthorized or ErrQuotaExceeded.
+type StatusError struct {
+	StatusCode int
+	Message    string        // Start of the response body
+	RetryAfter time.Duration // Delay asked for by Retry-After, if any
+}
+
+func (err *StatusError) Error() string {
+	msg := fmt.Sprintf("%s: %d %s", ErrUnexpectedStatus, err.StatusCode,
+		http.StatusText(err.StatusCode))
+	if err.Message != "" {
+		msg += ": " + err.Message
+	}
+	return msg
+}
+
+// Unwrap returns ErrUnexpectedStatus and the error matching the status, if
+// any.
+func (err *StatusError) Unwrap() []error {
+	errs := []error{ErrUnexpectedStatus}
+	if kind := err.kind(); kind != nil {
+		errs = append(errs, kind)
+	}
+	return errs
+}
+
+// kind returns the sentinel error matching the status, or nil.
+func (err *StatusError) kind() error {
+	switch err.StatusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusTooManyRequests:
+		return ErrQuotaExceeded
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		// The API gateway answers 403 both for a bad key and, mentioning
+		// the quota, once the plan's call volume is used up.
+		if strings.Contains(strings.ToLower(err.Message), "quota") {
+			return ErrQuotaExceeded
+		}
+		return ErrUnauthorized
+	default:
+		return nil
+	}
+}
+
+// Temporary reports whether the request may succeed if retried: on
+// 429 Too Many Requests and on server errors.
+func (err *StatusError) Temporary() bool {
+	return err.StatusCode == http.StatusTooManyRequests ||
+		err.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,80 @@
+package rewards
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting how often requests are made. The
+// bucket holds up to burst tokens and refills at rate tokens per second;
+// every request takes one token, waiting for it if the bucket is empty.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64   // Tokens added per second
+	burst  float64   // Bucket capacity
+	tokens float64   // Tokens available at last, negative when reserved
+	last   time.Time // When tokens was last updated
+}
+
+// NewRateLimiter returns a RateLimiter allowing rate requests per second on
+// average and burst requests at once. It starts full. A rate of zero or less
+// leaves requests unlimited, as a zero RateLimit does in Config.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait takes a token, waiting until one is available or ctx is done.
+func (limiter *RateLimiter) Wait(ctx context.Context) error {
+	delay := limiter.reserve(time.Now())
+	if delay <= 0 {
+		return nil
+	}
+	if err := sleep(ctx, delay); err != nil {
+		limiter.cancel()
+		return err
+	}
+	return nil
+}
+
+// reserve takes a token at now, possibly leaving the bucket in debt, and
+// returns how long to wait until the token is actually available.
+func (limiter *RateLimiter) reserve(now time.Time) time.Duration {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	if !(limiter.rate > 0) {
+		return 0
+	}
+	if elapsed := now.Sub(limiter.last); elapsed > 0 {
+		limiter.tokens += elapsed.Seconds() * limiter.rate
+		if limiter.tokens > limiter.burst {
+			limiter.tokens = limiter.burst
+		}
+		limiter.last = now
+	}
+
+	limiter.tokens--
+	if limiter.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-limiter.tokens / limiter.rate * float64(time.Second))
+}
+
+// cancel returns a token reserved by a Wait that gave up.
+func (limiter *RateLimiter) cancel() {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	limiter.tokens++
+	if limiter.tokens > limiter.burst {
+		limiter.tokens = limiter.burst
+	}
+}
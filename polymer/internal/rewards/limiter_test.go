@@ -0,0 +1,69 @@
+package rewards_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+)
+
+func TestRateLimiter(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     float64
+		burst    int
+		requests int
+		wantErr  error // Of the last request, given 50ms
+	}{
+		{"within the burst", 1, 3, 3, nil},
+		{"past the burst", 1, 3, 4, context.DeadlineExceeded},
+		{"refilled in time", 100, 1, 2, nil},
+		{"no burst", 1, 0, 2, context.DeadlineExceeded},
+		{"zero rate unlimited", 0, 1, 100, nil},
+		{"negative rate unlimited", -1, 1, 100, nil},
+		{"NaN rate unlimited", math.NaN(), 1, 100, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limiter := rewards.NewRateLimiter(test.rate, test.burst)
+			ctx, cancel := context.WithTimeout(context.Background(),
+				50*time.Millisecond)
+			defer cancel()
+
+			var err error
+			for range test.requests {
+				if err = limiter.Wait(ctx); err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("Wait() error = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRateLimiterReturnsCancelledToken(t *testing.T) {
+	limiter := rewards.NewRateLimiter(20, 1)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	// A request giving up returns its token, so the next one only waits
+	// for the token the first request took.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait() error = %v, want %v", err, context.Canceled)
+	}
+	start := time.Now()
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 90*time.Millisecond {
+		t.Errorf("Wait() took %v, want about 50ms", elapsed)
+	}
+}
@@ -0,0 +1,165 @@
+package rewards
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// mccTable is the bundled ISO 18245 merchant category code table. Each row
+// gives a code, its description, the spend category group the code belongs to
+// and the IDs of the most specific categories of the default taxonomy that
+// cover it, separated by semicolons.
+//
+//go:embed mcc.csv
+var mccTable []byte
+
+// MCC is an ISO 18245 merchant category code and what it maps to in the
+// Rewards API's spend bonus categories.
+type MCC struct {
+	Code        string `json:"code"` // Four digits, e.g. 5812
+	Description string `json:"description"`
+
+	// Spend bonus category group covering the code, e.g. Dining
+	CategoryGroup string `json:"categoryGroup"`
+	// IDs of the most specific categories of the default taxonomy covering
+	// the code; may be empty when no category is known to cover it
+	CategoryIDs []int `json:"categoryIDs"`
+}
+
+// CategoryID returns the first category covering the code, or -1 if none is
+// known.
+func (mcc *MCC) CategoryID() int {
+	if len(mcc.CategoryIDs) == 0 {
+		return -1
+	}
+	return mcc.CategoryIDs[0]
+}
+
+// mccs parses the bundled table once, indexing it by code. The table ships
+// with the code, so failing to parse it or to find its categories in the
+// default taxonomy is a programming error.
+var mccs = sync.OnceValue(func() map[string]*MCC {
+	table, err := parseMCCTable(mccTable)
+	if err != nil {
+		panic(err)
+	}
+	for _, mcc := range table {
+		for _, id := range mcc.CategoryIDs {
+			if _, ok := DefaultTaxonomy().Category(id); !ok {
+				panic(fmt.Sprintf("MCC %s has unknown category %d",
+					mcc.Code, id))
+			}
+		}
+	}
+	return table
+})
+
+// parseMCCTable parses a CSV table of merchant category codes, as described
+// on mccTable, with a header row.
+func parseMCCTable(data []byte) (map[string]*MCC, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid MCC table: %w", err)
+	}
+
+	table := make(map[string]*MCC, len(records))
+	for i, record := range records {
+		if i == 0 {
+			continue
+		}
+		if len(record) != 4 {
+			return nil, fmt.Errorf("invalid MCC table: line %d has %d "+
+				"fields, want 4", i+1, len(record))
+		}
+		mcc := MCC{
+			Code:          record[0],
+			Description:   record[1],
+			CategoryGroup: record[2],
+		}
+		if !ValidMCC(mcc.Code) {
+			return nil, fmt.Errorf("invalid MCC table: line %d: invalid "+
+				"code %q", i+1, mcc.Code)
+		}
+		for _, field := range strings.Split(record[3], ";") {
+			if field == "" {
+				continue
+			}
+			id, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid MCC table: line %d: "+
+					"invalid category ID %q", i+1, field)
+			}
+			mcc.CategoryIDs = append(mcc.CategoryIDs, id)
+		}
+		table[mcc.Code] = &mcc
+	}
+	return table, nil
+}
+
+// ValidMCC reports whether code has the form of a merchant category code:
+// exactly four digits.
+func ValidMCC(code string) bool {
+	if len(code) != 4 {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// LookupMCC returns the bundled entry for the merchant category code, and
+// whether there is one.
+func LookupMCC(code string) (*MCC, bool) {
+	mcc, ok := mccs()[strings.TrimSpace(code)]
+	if !ok {
+		return nil, false
+	}
+	found := *mcc
+	return &found, true
+}
+
+// MCCs returns every bundled merchant category code, ordered by code.
+func MCCs() []*MCC {
+	table := mccs()
+	list := make([]*MCC, 0, len(table))
+	for _, mcc := range table {
+		found := *mcc
+		list = append(list, &found)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return list
+}
+
+// IsApplicableMCC determines if the bonus applies to merchants with the given
+// merchant category code, being applicable to one of the categories covering
+// it.
+func (bonus *SpendBonusCategory) IsApplicableMCC(code string) bool {
+	mcc, ok := LookupMCC(code)
+	if !ok {
+		return false
+	}
+	for _, id := range mcc.CategoryIDs {
+		if bonus.IsApplicable(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// AppliesTo determines if the bonus applies to a merchant in the given
+// category or, if mcc is not empty, with that merchant category code.
+func (bonus *SpendBonusCategory) AppliesTo(categoryID int, mcc string) bool {
+	return bonus.IsApplicable(categoryID) ||
+		(mcc != "" && bonus.IsApplicableMCC(mcc))
+}
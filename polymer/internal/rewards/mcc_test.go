@@ -0,0 +1,96 @@
+package rewards_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+)
+
+func TestLookupMCC(t *testing.T) {
+	tests := []struct {
+		code      string
+		wantOK    bool
+		wantGroup string
+		wantIDs   []int
+	}{
+		{"5812", true, "Dining", []int{1001}},
+		{" 5411 ", true, "Grocery", []int{5}},
+		{"3000", true, "Travel", []int{9}},
+		{"8011", true, "Health", nil},
+		{"0000", false, "", nil},
+		{"581", false, "", nil},
+		{"", false, "", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			mcc, ok := rewards.LookupMCC(test.code)
+			if ok != test.wantOK {
+				t.Fatalf("LookupMCC(%q) ok = %v, want %v", test.code, ok,
+					test.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if mcc.CategoryGroup != test.wantGroup ||
+				!slices.Equal(mcc.CategoryIDs, test.wantIDs) {
+				t.Errorf("LookupMCC(%q) = %s %v, want %s %v", test.code,
+					mcc.CategoryGroup, mcc.CategoryIDs, test.wantGroup,
+					test.wantIDs)
+			}
+		})
+	}
+}
+
+func TestLookupMCCReturnsCopy(t *testing.T) {
+	mcc, _ := rewards.LookupMCC("5812")
+	mcc.CategoryGroup = "Changed"
+	if again, _ := rewards.LookupMCC("5812"); again.CategoryGroup != "Dining" {
+		t.Errorf("LookupMCC() group = %s after a change to an earlier "+
+			"result, want Dining", again.CategoryGroup)
+	}
+}
+
+func TestValidMCC(t *testing.T) {
+	for code, want := range map[string]bool{
+		"5812": true, "0000": true, "581": false, "58120": false,
+		"58a2": false, "": false,
+	} {
+		if got := rewards.ValidMCC(code); got != want {
+			t.Errorf("ValidMCC(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestAppliesTo(t *testing.T) {
+	dining := &rewards.SpendBonusCategory{SpendBonusCategoryID: 2,
+		SpendBonusCategoryName: "Dining"}
+	travel := &rewards.SpendBonusCategory{SpendBonusCategoryID: 8,
+		SpendBonusCategoryName: "Travel"}
+
+	tests := []struct {
+		name       string
+		bonus      *rewards.SpendBonusCategory
+		categoryID int
+		mcc        string
+		want       bool
+	}{
+		{"category only", dining, 2, "", true},
+		{"restaurant code", dining, 0, "5812", true},
+		{"grocery code", dining, 0, "5411", false},
+		{"code below the bonus", travel, 0, "3000", true},
+		{"excluded code", travel, 0, "7012", false},
+		{"code without categories", dining, 0, "8011", false},
+		{"unknown code", dining, 0, "0000", false},
+		{"category despite the code", dining, 2, "5411", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.bonus.AppliesTo(test.categoryID, test.mcc)
+			if got != test.want {
+				t.Errorf("AppliesTo(%d, %q) = %v, want %v", test.categoryID,
+					test.mcc, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package rewards
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy sets how a Client retries failed requests. The delay before a
+// retry grows exponentially from BaseDelay up to MaxDelay, with random jitter
+// so that clients failing together do not retry together. A Retry-After
+// header takes precedence over the computed delay.
+type RetryPolicy struct {
+	MaxRetries int           // Retries after the first attempt, 0 for none
+	BaseDelay  time.Duration // Delay before the first retry
+	MaxDelay   time.Duration // Longest delay between attempts
+}
+
+// DefaultRetryPolicy returns the RetryPolicy of a Client made by NewClient.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: DefaultMaxRetries,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+// delay returns how long to wait before retrying a request whose attempt
+// number attempt, counting from 0, failed with err, and whether to retry it
+// at all. A request is not retried when the wait would outlast ctx.
+func (policy RetryPolicy) delay(ctx context.Context, attempt int,
+	err error) (time.Duration, bool) {
+
+	if attempt >= policy.MaxRetries || ctx.Err() != nil {
+		return 0, false
+	}
+
+	var delay time.Duration
+	var statusErr *StatusError
+	switch {
+	case errors.As(err, &statusErr):
+		if !statusErr.Temporary() {
+			return 0, false
+		}
+		delay = statusErr.RetryAfter
+	case errors.Is(err, ErrRequestFailed):
+	default:
+		return 0, false
+	}
+
+	if delay <= 0 {
+		delay = policy.backoff(attempt)
+	}
+	if deadline, ok := ctx.Deadline(); ok &&
+		time.Now().Add(delay).After(deadline) {
+		return 0, false
+	}
+	return delay, true
+}
+
+// backoff returns the jittered delay before retry number attempt + 1: a
+// random duration between half and all of BaseDelay doubled attempt times,
+// capped at MaxDelay.
+func (policy RetryPolicy) backoff(attempt int) time.Duration {
+	delay := policy.MaxDelay
+	if attempt < 32 {
+		if d := policy.BaseDelay << attempt; d > 0 && d < delay {
+			delay = d
+		}
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// parseRetryAfter parses a Retry-After header value, given either in seconds
+// or as an HTTP date, into the delay it asks for from now. It returns 0 when
+// the value is missing or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// sleep waits for delay, or until ctx is done.
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
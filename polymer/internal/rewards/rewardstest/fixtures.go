@@ -0,0 +1,75 @@
+package rewardstest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+)
+
+//go:embed fixtures
+var defaultFixtures embed.FS
+
+// Fixtures holds the response bodies served by a Server: the card list and
+// the card detail of each card, by card key. They are served as is, so they
+// keep the exact form of real API responses.
+type Fixtures struct {
+	CardList    []byte
+	CardDetails map[string][]byte
+}
+
+// DefaultFixtures returns the fixtures shipped with the package, a handful
+// of cards of the main issuers.
+func DefaultFixtures() *Fixtures {
+	fixtures, err := LoadFixtures(defaultFixtures, "fixtures")
+	if err != nil {
+		panic(fmt.Sprintf("invalid default fixtures: %s", err))
+	}
+	return fixtures
+}
+
+// LoadFixtures reads fixtures from the directory dir of fsys, which holds
+// the card list as card_list.json and the detail of each card as
+// card_detail/<card key>.json. Every file must decode as the response it
+// stands for.
+func LoadFixtures(fsys fs.FS, dir string) (*Fixtures, error) {
+	cardList, err := fs.ReadFile(fsys, path.Join(dir, "card_list.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read card list: %w", err)
+	}
+	var response rewards.CardListResponse
+	if err := json.Unmarshal(cardList, &response); err != nil {
+		return nil, fmt.Errorf("invalid card list: %w", err)
+	}
+
+	detailDir := path.Join(dir, "card_detail")
+	entries, err := fs.ReadDir(fsys, detailDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read card details: %w", err)
+	}
+
+	fixtures := &Fixtures{
+		CardList:    cardList,
+		CardDetails: make(map[string][]byte, len(entries)),
+	}
+	for _, entry := range entries {
+		cardKey, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		detail, err := fs.ReadFile(fsys, path.Join(detailDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read card detail: %w", err)
+		}
+		if err := json.Unmarshal(detail, &[]rewards.CardDetail{}); err != nil {
+			return nil, fmt.Errorf("invalid card detail %s: %w", cardKey, err)
+		}
+		fixtures.CardDetails[cardKey] = detail
+	}
+
+	return fixtures, nil
+}
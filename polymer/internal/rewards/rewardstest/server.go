@@ -0,0 +1,173 @@
+// Package rewardstest provides a fake Rewards Credit Card API for tests and
+// offline development. A Server serves the card list and card details from
+// fixtures, checks the subscription key like the real API gateway does, and
+// can inject latency, server errors and rate limiting.
+//
+// Use it with net/http/httptest:
+//
+//	mock := rewardstest.NewServer(rewardstest.DefaultFixtures(), "key")
+//	ts := httptest.NewServer(mock)
+//	defer ts.Close()
+//	client := rewards.NewClient(mock.Config(ts.URL))
+package rewardstest
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+)
+
+// Faults sets the failures a Server injects into its responses.
+type Faults struct {
+	Latency       time.Duration // Delay before every response
+	ErrorRate     float64       // Share of requests failing with 500
+	RateLimitRate float64       // Share of requests failing with 429
+	RetryAfter    time.Duration // Retry-After sent with 429, if set
+}
+
+// Server is a fake Rewards Credit Card API. It is an http.Handler and is
+// safe for concurrent use.
+type Server struct {
+	fixtures *Fixtures
+	apiKey   string
+
+	mu       sync.Mutex
+	faults   Faults
+	failNext []int
+	requests int
+}
+
+// NewServer returns a Server serving fixtures to requests made with apiKey.
+func NewServer(fixtures *Fixtures, apiKey string) *Server {
+	return &Server{fixtures: fixtures, apiKey: apiKey}
+}
+
+// Config returns the settings of a client of the Server listening at
+// baseURL. The client does not retry nor limit its rate.
+func (server *Server) Config(baseURL string) rewards.Config {
+	return rewards.Config{
+		APIUrl:         strings.TrimSuffix(baseURL, "/"),
+		APIKey:         server.apiKey,
+		RequestTimeout: rewards.DefaultRequestTimeout,
+	}
+}
+
+// SetFaults sets the failures injected into subsequent responses.
+func (server *Server) SetFaults(faults Faults) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.faults = faults
+}
+
+// FailNext makes the next requests fail with the given statuses, in order,
+// before any other fault is considered. A status of 0 lets its request
+// through.
+func (server *Server) FailNext(statuses ...int) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.failNext = append(server.failNext, statuses...)
+}
+
+// Requests returns the number of requests served so far.
+func (server *Server) Requests() int {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.requests
+}
+
+// ServeHTTP implements http.Handler.
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status, faults := server.nextFault()
+	if faults.Latency > 0 {
+		select {
+		case <-time.After(faults.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	switch {
+	case status == http.StatusTooManyRequests:
+		if faults.RetryAfter > 0 {
+			seconds := int((faults.RetryAfter + time.Second - 1) / time.Second)
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		}
+		writeError(w, status, "Rate limit is exceeded.")
+		return
+	case status != 0:
+		writeError(w, status, http.StatusText(status))
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if r.URL.Query().Get("skey") != server.apiKey {
+		writeError(w, http.StatusUnauthorized, "Access denied due to "+
+			"invalid subscription key. Make sure to provide a valid key "+
+			"for an active subscription.")
+		return
+	}
+
+	endpoint, param, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"),
+		"/")
+	switch {
+	case endpoint == rewards.Endpoints["card_list"] && param == "":
+		writeBody(w, server.fixtures.CardList)
+	case endpoint == rewards.Endpoints["card_detail"] && param != "":
+		// Like the real API, an unknown card has an empty detail list.
+		detail, ok := server.fixtures.CardDetails[param]
+		if !ok {
+			detail = []byte("[]")
+		}
+		writeBody(w, detail)
+	default:
+		writeError(w, http.StatusNotFound, "Resource not found")
+	}
+}
+
+// nextFault counts a request and returns the status it must fail with, or
+// 0, and the faults in effect.
+func (server *Server) nextFault() (int, Faults) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.requests++
+	if len(server.failNext) > 0 {
+		status := server.failNext[0]
+		server.failNext = server.failNext[1:]
+		return status, server.faults
+	}
+
+	roll := rand.Float64()
+	switch {
+	case roll < server.faults.RateLimitRate:
+		return http.StatusTooManyRequests, server.faults
+	case roll < server.faults.RateLimitRate+server.faults.ErrorRate:
+		return http.StatusInternalServerError, server.faults
+	default:
+		return 0, server.faults
+	}
+}
+
+func writeBody(w http.ResponseWriter, body []byte) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(body)
+}
+
+// writeError writes an error in the form used by the API gateway.
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		StatusCode int    `json:"statusCode"`
+		Message    string `json:"message"`
+	}{status, message})
+}
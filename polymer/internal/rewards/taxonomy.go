@@ -0,0 +1,240 @@
+package rewards
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// categoryTree is the bundled spend category taxonomy, as a JSON tree of
+// taxonomyNode. Categories the Rewards API has IDs for keep them; the others
+// get IDs from 1000 up, which the API does not use.
+//
+//go:embed categories.json
+var categoryTree []byte
+
+// Category is a spend category in a Taxonomy.
+type Category struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Group  string `json:"group"`            // Name of the root category above it
+	Parent int    `json:"parent,omitempty"` // 0 for a root category
+
+	// Descendants a bonus in this category does not cover, with their own
+	// descendants
+	Excludes []int `json:"excludes,omitempty"`
+}
+
+// Taxonomy is a hierarchy of spend categories. A bonus in a category covers
+// merchants in it and in every category below it, except for those it
+// excludes. Roots are category groups, e.g. Dining or Travel.
+type Taxonomy struct {
+	categories []*Category // Depth first, parents before children
+	byID       map[int]*Category
+	byName     map[string]*Category // By lower case name
+}
+
+// taxonomyNode is a category of a taxonomy in its JSON form.
+type taxonomyNode struct {
+	ID       int            `json:"id"`
+	Name     string         `json:"name"`
+	Excludes []int          `json:"excludes"`
+	Children []taxonomyNode `json:"children"`
+}
+
+// ParseTaxonomy parses a taxonomy from a JSON array of root categories, each
+// with an id, a name, the IDs of the descendants it excludes and its child
+// categories, e.g.
+//
+//	[{"id": 8, "name": "Travel", "excludes": [1028], "children": [
+//		{"id": 9, "name": "Airfare"}, {"id": 1028, "name": "Timeshares"}]}]
+//
+// IDs must be positive and IDs and names unique.
+func ParseTaxonomy(data []byte) (*Taxonomy, error) {
+	var roots []taxonomyNode
+	if err := json.Unmarshal(data, &roots); err != nil {
+		return nil, fmt.Errorf("invalid taxonomy: %w", err)
+	}
+
+	taxonomy := &Taxonomy{
+		byID:   make(map[int]*Category),
+		byName: make(map[string]*Category),
+	}
+	for _, root := range roots {
+		if err := taxonomy.add(&root, root.Name, 0); err != nil {
+			return nil, fmt.Errorf("invalid taxonomy: %w", err)
+		}
+	}
+
+	for _, category := range taxonomy.byID {
+		for _, id := range category.Excludes {
+			if !taxonomy.isBelow(id, category.ID) {
+				return nil, fmt.Errorf("invalid taxonomy: %s excludes %d, "+
+					"which is not below it", category.Name, id)
+			}
+		}
+	}
+	return taxonomy, nil
+}
+
+// add adds node and its children to the taxonomy.
+func (taxonomy *Taxonomy) add(node *taxonomyNode, group string,
+	parent int) error {
+
+	name := strings.TrimSpace(node.Name)
+	switch {
+	case node.ID <= 0:
+		return fmt.Errorf("category %q has no positive id", name)
+	case name == "":
+		return fmt.Errorf("category %d has no name", node.ID)
+	case taxonomy.byID[node.ID] != nil:
+		return fmt.Errorf("duplicate category id %d", node.ID)
+	case taxonomy.byName[strings.ToLower(name)] != nil:
+		return fmt.Errorf("duplicate category name %q", name)
+	}
+
+	category := &Category{
+		ID:       node.ID,
+		Name:     name,
+		Group:    group,
+		Parent:   parent,
+		Excludes: node.Excludes,
+	}
+	taxonomy.categories = append(taxonomy.categories, category)
+	taxonomy.byID[category.ID] = category
+	taxonomy.byName[strings.ToLower(name)] = category
+
+	for i := range node.Children {
+		if err := taxonomy.add(&node.Children[i], group,
+			category.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultTaxonomy parses the bundled taxonomy once. The taxonomy ships with
+// the code, so failing to parse it is a programming error.
+var defaultTaxonomy = sync.OnceValue(func() *Taxonomy {
+	taxonomy, err := ParseTaxonomy(categoryTree)
+	if err != nil {
+		panic(err)
+	}
+	return taxonomy
+})
+
+// DefaultTaxonomy returns the bundled spend category taxonomy.
+func DefaultTaxonomy() *Taxonomy {
+	return defaultTaxonomy()
+}
+
+// Categories returns every category depth first, each followed by the
+// categories below it.
+func (taxonomy *Taxonomy) Categories() []*Category {
+	return append([]*Category(nil), taxonomy.categories...)
+}
+
+// Category returns the category with the given ID, and whether there is one.
+func (taxonomy *Taxonomy) Category(id int) (*Category, bool) {
+	category, ok := taxonomy.byID[id]
+	return category, ok
+}
+
+// CategoryByName returns the category with the given name, ignoring case,
+// and whether there is one.
+func (taxonomy *Taxonomy) CategoryByName(name string) (*Category, bool) {
+	category, ok := taxonomy.byName[strings.ToLower(strings.TrimSpace(name))]
+	return category, ok
+}
+
+// Path returns the IDs of the category with the given ID and of the
+// categories above it, nearest first, or nil for an unknown category.
+func (taxonomy *Taxonomy) Path(id int) []int {
+	var path []int
+	for category, ok := taxonomy.byID[id]; ok; category, ok =
+		taxonomy.byID[category.Parent] {
+		path = append(path, category.ID)
+	}
+	return path
+}
+
+// isBelow reports whether the category with the given ID is strictly below
+// the category with ID ancestor.
+func (taxonomy *Taxonomy) isBelow(id, ancestor int) bool {
+	path := taxonomy.Path(id)
+	for _, above := range path[min(1, len(path)):] {
+		if above == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// Covers reports whether a bonus in the category with ID bonusID applies to
+// merchants in the category with ID categoryID: the categories are the same,
+// or the merchant's is below the bonus's and not excluded by it.
+func (taxonomy *Taxonomy) Covers(bonusID, categoryID int) bool {
+	if bonusID == categoryID {
+		return true
+	}
+	bonus, ok := taxonomy.byID[bonusID]
+	if !ok {
+		return false
+	}
+
+	for _, id := range taxonomy.Path(categoryID) {
+		if id == bonusID {
+			return true
+		}
+		for _, excluded := range bonus.Excludes {
+			if id == excluded {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// BonusCategory returns the category of the taxonomy that bonus is for:
+// the one with its SpendBonusCategoryID or else the more specific of those
+// named by its subcategory group (where "All Travel" names the Travel group)
+// and its name. It reports whether one was found. A bonus of an unknown ID
+// matching neither is uncategorized rather than taken for the root of its
+// group, which would cover every merchant of the group.
+func (taxonomy *Taxonomy) BonusCategory(
+	bonus *SpendBonusCategory) (*Category, bool) {
+
+	if category, ok := taxonomy.byID[bonus.SpendBonusCategoryID]; ok {
+		return category, true
+	}
+
+	subgroup := strings.TrimSpace(bonus.SpendBonusSubcategoryGroup)
+	subgroup = strings.TrimPrefix(subgroup, "All ")
+
+	var found *Category
+	depth := 0
+	for _, name := range []string{subgroup, bonus.SpendBonusCategoryName} {
+		category, ok := taxonomy.CategoryByName(name)
+		if !ok {
+			continue
+		}
+		if d := len(taxonomy.Path(category.ID)); d > depth {
+			found, depth = category, d
+		}
+	}
+	return found, found != nil
+}
+
+// Applies reports whether bonus applies to merchants in the category with ID
+// categoryID, walking the taxonomy from the bonus's category down.
+func (taxonomy *Taxonomy) Applies(bonus *SpendBonusCategory,
+	categoryID int) bool {
+
+	if bonus.SpendBonusCategoryID == categoryID {
+		return true
+	}
+	category, ok := taxonomy.BonusCategory(bonus)
+	return ok && taxonomy.Covers(category.ID, categoryID)
+}
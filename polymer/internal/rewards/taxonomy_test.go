@@ -0,0 +1,131 @@
+package rewards_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+)
+
+func TestParseTaxonomy(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"valid", `[{"id": 8, "name": "Travel", "excludes": [1028],
+			"children": [{"id": 9, "name": "Airfare"},
+				{"id": 1028, "name": "Timeshares"}]}]`, ""},
+		{"not JSON", `{`, "invalid taxonomy"},
+		{"no id", `[{"name": "Travel"}]`, "no positive id"},
+		{"no name", `[{"id": 8, "name": " "}]`, "has no name"},
+		{"duplicate id", `[{"id": 8, "name": "Travel"},
+			{"id": 8, "name": "Dining"}]`, "duplicate category id 8"},
+		{"duplicate name", `[{"id": 8, "name": "Travel"},
+			{"id": 9, "name": "travel"}]`, "duplicate category name"},
+		{"exclusion not below", `[{"id": 8, "name": "Travel",
+			"excludes": [2]}, {"id": 2, "name": "Dining"}]`,
+			"not below it"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := rewards.ParseTaxonomy([]byte(test.data))
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("ParseTaxonomy() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("ParseTaxonomy() error = %v, want %q", err,
+					test.wantErr)
+			}
+		})
+	}
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		name       string
+		bonusID    int
+		categoryID int
+		want       bool
+	}{
+		{"same category", 2, 2, true},
+		{"child", 2, 1001, true},
+		{"grandchild", 8, 1031, true},
+		{"parent", 1001, 2, false},
+		{"sibling", 9, 1021, false},
+		{"other group", 2, 5, false},
+		{"excluded", 8, 1028, false},
+		{"excluded group gas", 1040, 1043, false},
+		{"below the excluded", 5, 1011, true},
+		{"unknown bonus", 9999, 1001, false},
+		{"unknown category", 2, 9999, false},
+		{"same unknown category", 9999, 9999, true},
+	}
+	taxonomy := rewards.DefaultTaxonomy()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := taxonomy.Covers(test.bonusID, test.categoryID)
+			if got != test.want {
+				t.Errorf("Covers(%d, %d) = %v, want %v", test.bonusID,
+					test.categoryID, got, test.want)
+			}
+		})
+	}
+}
+
+func TestBonusCategory(t *testing.T) {
+	tests := []struct {
+		name   string
+		bonus  rewards.SpendBonusCategory
+		wantID int // 0 for uncategorized
+	}{
+		{"known ID", rewards.SpendBonusCategory{SpendBonusCategoryID: 9,
+			SpendBonusCategoryName: "Hotels"}, 9},
+		{"by name", rewards.SpendBonusCategory{SpendBonusCategoryID: 77,
+			SpendBonusCategoryName: "hotels"}, 1021},
+		{"all of a group", rewards.SpendBonusCategory{
+			SpendBonusCategoryID:       77,
+			SpendBonusCategoryName:     "Travel purchases",
+			SpendBonusSubcategoryGroup: "All Travel"}, 8},
+		{"more specific name", rewards.SpendBonusCategory{
+			SpendBonusCategoryID:       77,
+			SpendBonusCategoryName:     "Rideshare and Taxis",
+			SpendBonusSubcategoryGroup: "Travel"}, 1031},
+		{"unknown in a known group", rewards.SpendBonusCategory{
+			SpendBonusCategoryID:    77,
+			SpendBonusCategoryName:  "Space tourism",
+			SpendBonusCategoryGroup: "Travel"}, 0},
+	}
+	taxonomy := rewards.DefaultTaxonomy()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			category, ok := taxonomy.BonusCategory(&test.bonus)
+			gotID := 0
+			if ok {
+				gotID = category.ID
+			}
+			if gotID != test.wantID {
+				t.Errorf("BonusCategory() = %d, want %d", gotID,
+					test.wantID)
+			}
+		})
+	}
+}
+
+func TestAppliesUnknownCategory(t *testing.T) {
+	// A bonus of an unknown category only applies to its own ID, never to
+	// the rest of its group.
+	bonus := &rewards.SpendBonusCategory{SpendBonusCategoryID: 77,
+		SpendBonusCategoryName:  "Space tourism",
+		SpendBonusCategoryGroup: "Travel"}
+	taxonomy := rewards.DefaultTaxonomy()
+	for categoryID, want := range map[int]bool{77: true, 8: false, 9: false,
+		1021: false} {
+		if got := taxonomy.Applies(bonus, categoryID); got != want {
+			t.Errorf("Applies(%d) = %v, want %v", categoryID, got, want)
+		}
+	}
+}
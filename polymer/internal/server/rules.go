@@ -0,0 +1,139 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// handleListRules lists the category rules a wallet's purchases are
+// categorized by: its overrides, then the shared rules.
+func (server *Server) handleListRules(w http.ResponseWriter,
+	r *http.Request) {
+
+	wallet, err := server.repo.Wallets.GetWallet(r.Context(),
+		r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	rules, err := server.repo.CategoryRules.ListCategoryRules(r.Context(),
+		wallet.ID.Hex())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if rules == nil {
+		rules = []*store.CategoryRule{}
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+// handleAddRule stores a category rule overriding how the wallet's
+// purchases are categorized, tried after the wallet's other overrides.
+func (server *Server) handleAddRule(w http.ResponseWriter,
+	r *http.Request) {
+
+	var baseRule store.BaseCategoryRule
+	if err := decodeJSON(w, r, &baseRule); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := shop.NormalizeCategoryRule(&baseRule); err != nil {
+		writeError(w, fmt.Errorf("%w: %w", errBadRequest, err))
+		return
+	}
+
+	wallet, err := server.repo.Wallets.GetWallet(r.Context(),
+		r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	baseRule.WalletID = wallet.ID.Hex()
+
+	rule, err := server.repo.CategoryRules.InsertCategoryRule(r.Context(),
+		&baseRule)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+// handleDeleteRule deletes one of the wallet's category rule overrides.
+func (server *Server) handleDeleteRule(w http.ResponseWriter,
+	r *http.Request) {
+
+	walletID := r.PathValue("id")
+	ruleID := r.PathValue("ruleID")
+	rules, err := server.repo.CategoryRules.ListCategoryRules(r.Context(),
+		walletID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	found := false
+	for _, rule := range rules {
+		if rule.ID.Hex() == ruleID && rule.WalletID == walletID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		writeError(w, fmt.Errorf("%w: wallet has no category rule: %s",
+			store.ErrNotFound, ruleID))
+		return
+	}
+
+	err = server.repo.CategoryRules.DeleteCategoryRule(r.Context(), ruleID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCategorize returns the category the wallet's purchases from the
+// merchant described by the query parameters domain, descriptor and mcc are
+// given, with the explanation of how it was decided.
+func (server *Server) handleCategorize(w http.ResponseWriter,
+	r *http.Request) {
+
+	query := r.URL.Query()
+	merchant := store.MerchantDetails{
+		DomainName: strings.TrimSpace(query.Get("domain")),
+		Descriptor: strings.TrimSpace(query.Get("descriptor")),
+		MCC:        query.Get("mcc"),
+	}
+	if merchant.DomainName == "" && merchant.Descriptor == "" {
+		writeError(w, fmt.Errorf("%w: domain or descriptor is required",
+			errBadRequest))
+		return
+	}
+	if merchant.MCC != "" && !rewards.ValidMCC(merchant.MCC) {
+		writeError(w, fmt.Errorf("%w: invalid mcc: %s", errBadRequest,
+			merchant.MCC))
+		return
+	}
+
+	wallet, err := server.repo.Wallets.GetWallet(r.Context(),
+		r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	category, err := server.service.Categorize(r.Context(), wallet.ID.Hex(),
+		merchant)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, category)
+}
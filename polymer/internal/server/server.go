@@ -0,0 +1,131 @@
+// Package server implements the polymer HTTP API, which manages stored
+// wallets and recommends the best card in a wallet for a purchase.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// maxBodyBytes limits the size of request bodies.
+const maxBodyBytes = 1 << 20
+
+// errBadRequest is wrapped by errors caused by an invalid request.
+var errBadRequest = errors.New("bad request")
+
+// Server serves the polymer HTTP API from a repository.
+type Server struct {
+	service *shop.Service
+	repo    *store.Repository // The service's repository
+	mux     *http.ServeMux
+}
+
+// New returns a Server running on the given service.
+func New(service *shop.Service) *Server {
+	server := &Server{
+		service: service,
+		repo:    service.Repo,
+		mux:     http.NewServeMux(),
+	}
+	server.routes()
+	return server
+}
+
+func (server *Server) routes() {
+	server.mux.HandleFunc("GET /healthz", server.handleHealth)
+	server.mux.HandleFunc("POST /wallets", server.handleCreateWallet)
+	server.mux.HandleFunc("GET /wallets/{id}", server.handleGetWallet)
+	server.mux.HandleFunc("POST /wallets/{id}/cards", server.handleAddCard)
+	server.mux.HandleFunc("DELETE /wallets/{id}/cards/{cardKey}",
+		server.handleRemoveCard)
+	server.mux.HandleFunc("GET /wallets/{id}/recommendation",
+		server.handleRecommend)
+	server.mux.HandleFunc("POST /wallets/{id}/transactions",
+		server.handleTransact)
+	server.mux.HandleFunc("GET /wallets/{id}/transactions",
+		server.handleListTransactions)
+	server.mux.HandleFunc("GET /wallets/{id}/rules", server.handleListRules)
+	server.mux.HandleFunc("POST /wallets/{id}/rules", server.handleAddRule)
+	server.mux.HandleFunc("DELETE /wallets/{id}/rules/{ruleID}",
+		server.handleDeleteRule)
+	server.mux.HandleFunc("GET /wallets/{id}/category",
+		server.handleCategorize)
+}
+
+// ServeHTTP implements http.Handler.
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mux.ServeHTTP(w, r)
+}
+
+func (server *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// decodeJSON decodes the JSON request body into value, rejecting unknown
+// fields and trailing data.
+func decodeJSON(w http.ResponseWriter, r *http.Request, value any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		return fmt.Errorf("%w: invalid request body: %w", errBadRequest, err)
+	}
+	if decoder.More() {
+		return fmt.Errorf("%w: invalid request body: trailing data",
+			errBadRequest)
+	}
+	return nil
+}
+
+// writeJSON writes value as the JSON response body with the given status.
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Failed to write response: %s", err)
+	}
+}
+
+// writeError writes err as a JSON error response, with the status it maps
+// to. Internal errors are logged and not disclosed to the client.
+func writeError(w http.ResponseWriter, err error) {
+	status := errorStatus(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Printf("Internal server error: %s", err)
+		message = http.StatusText(status)
+	}
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// errorStatus maps err to an HTTP status code.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, errBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, store.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, store.ErrDuplicate):
+		return http.StatusConflict
+	case errors.Is(err, rewards.ErrCardNotFound),
+		errors.Is(err, shop.ErrNoBeneficialCard):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, rewards.ErrQuotaExceeded):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, rewards.ErrRequestFailed),
+		errors.Is(err, rewards.ErrUnexpectedStatus),
+		errors.Is(err, rewards.ErrDecode):
+		return http.StatusBadGateway
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,316 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// purchaseRequest describes a purchase to recommend a card for or record.
+type purchaseRequest struct {
+	Domain     string    `json:"domain"`
+	Descriptor string    `json:"descriptor,omitempty"` // Statement descriptor
+	Amount     float64   `json:"amount"`
+	Country    string    `json:"country,omitempty"`
+	Currency   string    `json:"currency,omitempty"`
+	MCC        string    `json:"mcc,omitempty"` // Overrides the domain's MCC
+	At         time.Time `json:"at"`            // Defaults to now
+}
+
+// handleCreateWallet stores a new wallet. Cards not stored yet are fetched
+// from the Rewards API.
+func (server *Server) handleCreateWallet(w http.ResponseWriter,
+	r *http.Request) {
+
+	var baseWallet store.BaseWallet
+	if err := decodeJSON(w, r, &baseWallet); err != nil {
+		writeError(w, err)
+		return
+	}
+	if baseWallet.Cards == nil {
+		baseWallet.Cards = []store.WalletCard{}
+	}
+	if err := server.validateWallet(r.Context(), &baseWallet); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	wallet, err := server.repo.Wallets.InsertWallet(r.Context(), &baseWallet)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, wallet)
+}
+
+func (server *Server) handleGetWallet(w http.ResponseWriter,
+	r *http.Request) {
+
+	wallet, err := server.repo.Wallets.GetWallet(r.Context(),
+		r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, wallet)
+}
+
+// handleAddCard adds a card to a wallet, fetching and storing it if it is
+// not stored yet. A card the wallet already holds is a conflict. The wallet
+// is looked up first, so that no card is fetched for a missing wallet.
+func (server *Server) handleAddCard(w http.ResponseWriter, r *http.Request) {
+	var card store.WalletCard
+	if err := decodeJSON(w, r, &card); err != nil {
+		writeError(w, err)
+		return
+	}
+	if card.CardKey == "" {
+		writeError(w, fmt.Errorf("%w: cardKey is required", errBadRequest))
+		return
+	}
+
+	stored, err := server.repo.Wallets.GetWallet(r.Context(),
+		r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if stored.CardIndex(card.CardKey) >= 0 {
+		writeError(w, fmt.Errorf("%w: wallet already holds card: %s",
+			store.ErrDuplicate, card.CardKey))
+		return
+	}
+	_, err = server.service.Cards.Fill(r.Context(), []string{card.CardKey})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	wallet, err := server.repo.Wallets.AddWalletCard(r.Context(),
+		r.PathValue("id"), card)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, wallet)
+}
+
+func (server *Server) handleRemoveCard(w http.ResponseWriter,
+	r *http.Request) {
+
+	wallet, err := server.repo.Wallets.RemoveWalletCard(r.Context(),
+		r.PathValue("id"), r.PathValue("cardKey"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, wallet)
+}
+
+// handleRecommend recommends the best card in a wallet for the purchase
+// described by the query parameters domain, descriptor, amount, country,
+// currency, mcc and at, without recording it.
+func (server *Server) handleRecommend(w http.ResponseWriter,
+	r *http.Request) {
+
+	query := r.URL.Query()
+	purchase := purchaseRequest{
+		Domain:     query.Get("domain"),
+		Descriptor: query.Get("descriptor"),
+		Country:    query.Get("country"),
+		Currency:   query.Get("currency"),
+		MCC:        query.Get("mcc"),
+	}
+	if value := query.Get("amount"); value != "" {
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			writeError(w, fmt.Errorf("%w: invalid amount: %s", errBadRequest,
+				value))
+			return
+		}
+		purchase.Amount = amount
+	}
+	if value := query.Get("at"); value != "" {
+		at, err := parseTime(value)
+		if err != nil {
+			writeError(w, fmt.Errorf("%w: invalid at: %w", errBadRequest, err))
+			return
+		}
+		purchase.At = at
+	}
+
+	transaction, err := server.recommend(r.Context(), r.PathValue("id"),
+		&purchase)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, transaction)
+}
+
+// handleTransact records a purchase with the best card in a wallet.
+func (server *Server) handleTransact(w http.ResponseWriter,
+	r *http.Request) {
+
+	var purchase purchaseRequest
+	if err := decodeJSON(w, r, &purchase); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	transaction, err := server.recommend(r.Context(), r.PathValue("id"),
+		&purchase)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	stored, err := server.repo.Transactions.InsertTransaction(r.Context(),
+		transaction)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, stored)
+}
+
+// handleListTransactions lists the transactions recorded against a wallet,
+// optionally limited to the period given by the query parameters from
+// (inclusive) and to (exclusive).
+func (server *Server) handleListTransactions(w http.ResponseWriter,
+	r *http.Request) {
+
+	wallet, err := server.repo.Wallets.GetWallet(r.Context(),
+		r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	filter := store.TransactionFilter{WalletID: wallet.ID.Hex()}
+	query := r.URL.Query()
+	for _, bound := range []struct {
+		name string
+		time *time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	} {
+		value := query.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		if *bound.time, err = parseTime(value); err != nil {
+			writeError(w, fmt.Errorf("%w: invalid %s: %w", errBadRequest,
+				bound.name, err))
+			return
+		}
+	}
+
+	transactions, err := server.repo.Transactions.ListTransactions(
+		r.Context(), &filter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if transactions == nil {
+		transactions = []*store.Transaction{}
+	}
+	writeJSON(w, http.StatusOK, transactions)
+}
+
+// recommend loads the wallet with the given ID and selects its best card
+// for purchase.
+func (server *Server) recommend(ctx context.Context, id string,
+	purchase *purchaseRequest) (*store.BaseTransaction, error) {
+
+	purchase.Domain = strings.TrimSpace(purchase.Domain)
+	purchase.Descriptor = strings.TrimSpace(purchase.Descriptor)
+	if purchase.Domain == "" && purchase.Descriptor == "" {
+		return nil, fmt.Errorf("%w: domain or descriptor is required",
+			errBadRequest)
+	}
+	if math.IsNaN(purchase.Amount) || math.IsInf(purchase.Amount, 0) {
+		return nil, fmt.Errorf("%w: amount must be a finite number",
+			errBadRequest)
+	}
+	if purchase.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", errBadRequest)
+	}
+	if purchase.MCC != "" && !rewards.ValidMCC(purchase.MCC) {
+		return nil, fmt.Errorf("%w: invalid mcc: %s", errBadRequest,
+			purchase.MCC)
+	}
+	if purchase.At.IsZero() {
+		purchase.At = time.Now()
+	}
+
+	stored, err := server.repo.Wallets.GetWallet(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(stored.Cards) == 0 {
+		return nil, fmt.Errorf("%w: wallet has no cards", errBadRequest)
+	}
+
+	wallet, err := server.service.LoadWallet(ctx, stored)
+	if err != nil {
+		if !shop.PartialWallet(wallet, err) {
+			return nil, err
+		}
+		log.Printf("Recommending for wallet %s without cards: %s", id, err)
+	}
+
+	merchant := store.MerchantDetails{
+		DomainName: purchase.Domain,
+		Descriptor: purchase.Descriptor,
+		Country:    purchase.Country,
+		Currency:   purchase.Currency,
+		MCC:        purchase.MCC,
+	}
+	return server.service.Recommend(ctx, merchant, purchase.Amount,
+		purchase.At, wallet)
+}
+
+// validateWallet checks the wallet's valuation and cards, fetching and
+// storing any card not stored yet.
+func (server *Server) validateWallet(ctx context.Context,
+	wallet *store.BaseWallet) error {
+
+	if _, err := shop.NewValuation(wallet.Valuation,
+		wallet.ValuationTable); err != nil {
+		return fmt.Errorf("%w: %w", errBadRequest, err)
+	}
+
+	seen := make(map[string]bool, len(wallet.Cards))
+	for _, card := range wallet.Cards {
+		if card.CardKey == "" {
+			return fmt.Errorf("%w: cardKey is required", errBadRequest)
+		}
+		if seen[card.CardKey] {
+			return fmt.Errorf("%w: duplicate card: %s", errBadRequest,
+				card.CardKey)
+		}
+		seen[card.CardKey] = true
+	}
+
+	_, err := server.service.Cards.Fill(ctx, wallet.CardKeys())
+	return err
+}
+
+// parseTime parses an RFC 3339 timestamp or a date, as accepted by the
+// Rewards API, in UTC.
+func parseTime(value string) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+	return rewards.ParseDate(value, time.UTC)
+}
@@ -0,0 +1,141 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/internal/rewards/rewardstest"
+	"github.com/ayushh-vermaa/polymer/internal/server"
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTestServer returns a server on a memory repository, fetching cards from
+// a mock Rewards API, along with the repository and the mock.
+func newTestServer(t *testing.T) (*server.Server, *store.Repository,
+	*rewardstest.Server) {
+
+	t.Helper()
+	mock := rewardstest.NewServer(rewardstest.DefaultFixtures(), "test-key")
+	ts := httptest.NewServer(mock)
+	t.Cleanup(ts.Close)
+
+	repo := store.NewMemoryRepository()
+	client := rewards.NewClient(mock.Config(ts.URL))
+	client.Retry = rewards.RetryPolicy{}
+	cards := shop.NewCardCache(repo.Cards, client, 0)
+	return server.New(shop.NewService(repo, cards)), repo, mock
+}
+
+func TestAddCard(t *testing.T) {
+	srv, repo, mock := newTestServer(t)
+	wallet, err := repo.Wallets.InsertWallet(context.Background(),
+		&store.BaseWallet{Name: "personal",
+			Cards: []store.WalletCard{{CardKey: "amex-gold"}}})
+	if err != nil {
+		t.Fatalf("InsertWallet() error = %v", err)
+	}
+	id := wallet.ID.Hex()
+
+	tests := []struct {
+		name         string
+		walletID     string
+		body         string
+		wantStatus   int
+		wantRequests int // Requests made of the Rewards API
+		wantCards    int
+	}{
+		{"missing wallet", primitive.NewObjectID().Hex(),
+			`{"cardKey": "citi-doublecash"}`, http.StatusNotFound, 0, 1},
+		{"invalid wallet ID", "not-an-id", `{"cardKey": "citi-doublecash"}`,
+			http.StatusNotFound, 0, 1},
+		{"no card key", id, `{}`, http.StatusBadRequest, 0, 1},
+		{"card already held", id, `{"cardKey": "amex-gold"}`,
+			http.StatusConflict, 0, 1},
+		{"new card", id, `{"cardKey": "citi-doublecash"}`, http.StatusOK, 1,
+			2},
+		{"unknown card", id, `{"cardKey": "no-such-card"}`,
+			http.StatusUnprocessableEntity, 1, 2},
+	}
+	// The cases run in order, each on the wallet the previous ones left.
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before := mock.Requests()
+			req := httptest.NewRequest(http.MethodPost,
+				"/wallets/"+test.walletID+"/cards",
+				strings.NewReader(test.body))
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+
+			if rec.Code != test.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code,
+					test.wantStatus, rec.Body)
+			}
+			if got := mock.Requests() - before; got != test.wantRequests {
+				t.Errorf("Rewards API requests = %d, want %d", got,
+					test.wantRequests)
+			}
+			stored, err := repo.Wallets.GetWallet(context.Background(), id)
+			if err != nil {
+				t.Fatalf("GetWallet() error = %v", err)
+			}
+			if len(stored.Cards) != test.wantCards {
+				t.Errorf("wallet cards = %d, want %d", len(stored.Cards),
+					test.wantCards)
+			}
+		})
+	}
+}
+
+func TestRemoveCard(t *testing.T) {
+	srv, repo, _ := newTestServer(t)
+	wallet, err := repo.Wallets.InsertWallet(context.Background(),
+		&store.BaseWallet{Name: "personal", Cards: []store.WalletCard{
+			{CardKey: "amex-gold"}, {CardKey: "citi-doublecash"}}})
+	if err != nil {
+		t.Fatalf("InsertWallet() error = %v", err)
+	}
+	id := wallet.ID.Hex()
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantCards  []string
+	}{
+		{"held card", "/wallets/" + id + "/cards/amex-gold", http.StatusOK,
+			[]string{"citi-doublecash"}},
+		{"card not held", "/wallets/" + id + "/cards/amex-gold",
+			http.StatusNotFound, nil},
+		{"missing wallet", "/wallets/" + primitive.NewObjectID().Hex() +
+			"/cards/amex-gold", http.StatusNotFound, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete,
+				test.path, nil))
+			if rec.Code != test.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code,
+					test.wantStatus, rec.Body)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var got store.Wallet
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode wallet: %v", err)
+			}
+			if len(got.Cards) != len(test.wantCards) ||
+				got.Cards[0].CardKey != test.wantCards[0] {
+				t.Errorf("cards = %+v, want %v", got.Cards, test.wantCards)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package shop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/store"
+	"github.com/shopspring/decimal"
+)
+
+// CardAnalysis compares what a card earned over the analyzed year with what it
+// costs to keep.
+type CardAnalysis struct {
+	CardKey           string  `json:"cardKey"`
+	CardName          string  `json:"cardName"`
+	Transactions      int     `json:"transactions"`
+	Spend             float64 `json:"spend"`             // USD spent with the card
+	RewardsEarned     float64 `json:"rewardsEarned"`     // USD of rewards, net of fx fees
+	SignupBonusEarned float64 `json:"signupBonusEarned"` // USD of sign-up bonus earned
+	BonusUnknown      bool    `json:"bonusUnknown"`      // Sign-up bonus amount unreadable
+	FirstYear         bool    `json:"firstYear"`         // Opened during the analyzed year
+	FirstYearFee      float64 `json:"firstYearFee"`      // Annual fee charged the first year
+	OngoingFee        float64 `json:"ongoingFee"`        // Annual fee charged every later year
+	AnnualFee         float64 `json:"annualFee"`         // Fee charged for the analyzed year
+	NetValue          float64 `json:"netValue"`          // Earned minus the fee for the analyzed year
+	OngoingNetValue   float64 `json:"ongoingNetValue"`   // Rewards earned minus the ongoing fee
+	Unprofitable      bool    `json:"unprofitable"`      // OngoingNetValue is negative
+}
+
+// WalletAnalysis is a per-card report of a wallet's rewards against its annual
+// fees over one year.
+type WalletAnalysis struct {
+	From          time.Time       `json:"from"`
+	To            time.Time       `json:"to"`
+	Valuation     string          `json:"valuation"`
+	Cards         []*CardAnalysis `json:"cards"`
+	RewardsEarned float64         `json:"rewardsEarned"`
+	Fees          float64         `json:"fees"`
+	NetValue      float64         `json:"netValue"`
+}
+
+// FirstYearAnnualFee returns the annual fee the card charges its first year.
+func FirstYearAnnualFee(card *rewards.CardDetail) float64 {
+	if card.IsSignupAnnualFeeWaived == 1 {
+		return 0
+	}
+	if card.SignupAnnualFee > 0 {
+		return card.SignupAnnualFee
+	}
+	return card.AnnualFee
+}
+
+// Analyze reports, for the year ending at to, what each card in the wallet
+// earned from its stored transactions against its annual fee. Rewards are
+// revalued under the wallet's Valuation, without any amortized sign-up bonus;
+// sign-up bonuses met during the year count toward the first year instead.
+// The fee is the first-year fee for cards opened during the year (per
+// OpenedOn) and the ongoing fee otherwise. Cards whose rewards do not cover
+// their ongoing fee are flagged as unprofitable. A sign-up bonus whose amount
+// cannot be read is reported as unknown and counts for nothing.
+func (wallet *BaseWallet) Analyze(ctx context.Context,
+	to time.Time) (*WalletAnalysis, error) {
+
+	from := to.AddDate(-1, 0, 0)
+	valuation := wallet.valuation()
+
+	analysis := WalletAnalysis{
+		From:      from,
+		To:        to,
+		Valuation: valuation.Name(),
+	}
+
+	var cardKeys []string
+	for _, card := range wallet.Cards {
+		cardKeys = append(cardKeys, card.CardKey)
+	}
+
+	var history SpendHistory
+	if wallet.History != nil && len(cardKeys) > 0 {
+		historyFrom := from
+		for _, openedOn := range wallet.OpenedOn {
+			if openedOn.Before(historyFrom) {
+				historyFrom = openedOn
+			}
+		}
+		transactions, err := wallet.History.ListTransactions(ctx,
+			&store.TransactionFilter{
+				WalletID: wallet.ID,
+				CardKeys: cardKeys,
+				From:     historyFrom,
+				To:       to,
+			})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load spend history: %w", err)
+		}
+		history = SpendHistory(transactions)
+	}
+
+	rewardsEarned := decimal.Zero
+	fees := decimal.Zero
+	for _, card := range wallet.Cards {
+		cardAnalysis, err := wallet.analyzeCard(card, from, to, history,
+			valuation)
+		if err != nil {
+			return nil, err
+		}
+		analysis.Cards = append(analysis.Cards, cardAnalysis)
+
+		rewardsEarned = rewardsEarned.
+			Add(decimal.NewFromFloat(cardAnalysis.RewardsEarned)).
+			Add(decimal.NewFromFloat(cardAnalysis.SignupBonusEarned))
+		fees = fees.Add(decimal.NewFromFloat(cardAnalysis.AnnualFee))
+	}
+
+	analysis.RewardsEarned = rewardsEarned.InexactFloat64()
+	analysis.Fees = fees.InexactFloat64()
+	analysis.NetValue = rewardsEarned.Sub(fees).InexactFloat64()
+
+	return &analysis, nil
+}
+
+// analyzeCard builds the CardAnalysis of card for [from, to).
+func (wallet *BaseWallet) analyzeCard(card *rewards.CardDetail, from,
+	to time.Time, history SpendHistory,
+	valuation Valuation) (*CardAnalysis, error) {
+
+	cardAnalysis := CardAnalysis{
+		CardKey:      card.CardKey,
+		CardName:     card.CardName,
+		FirstYearFee: FirstYearAnnualFee(card),
+		OngoingFee:   card.AnnualFee,
+	}
+
+	spend := decimal.Zero
+	earned := decimal.Zero
+	for _, transaction := range history {
+		if transaction.CardDetails.CardKey != card.CardKey ||
+			transaction.TransactionAt.Before(from) {
+			continue
+		}
+		amount := decimal.NewFromFloat(transaction.SpendAmount)
+		reward := Revalue(transaction.CardDetails.RewardDetails, card,
+			valuation)
+		cardAnalysis.Transactions++
+		spend = spend.Add(amount)
+		earned = earned.Add(amount.Mul(decimal.NewFromFloat(reward.Value)))
+	}
+	cardAnalysis.Spend = spend.InexactFloat64()
+	cardAnalysis.RewardsEarned = earned.Round(2).InexactFloat64()
+
+	cardAnalysis.AnnualFee = cardAnalysis.OngoingFee
+	openedOn, opened := wallet.OpenedOn[card.CardKey]
+	if opened && !openedOn.Before(from) {
+		cardAnalysis.FirstYear = true
+		cardAnalysis.AnnualFee = cardAnalysis.FirstYearFee
+	}
+
+	if opened && HasSignupBonus(card) {
+		progress, err := TrackSignupBonus(card, openedOn, to, history,
+			valuation)
+		switch {
+		case errors.Is(err, rewards.ErrInvalidSignupBonus):
+			cardAnalysis.BonusUnknown = true
+		case err != nil:
+			return nil, err
+		case progress.Met && !progress.Deadline.Before(from):
+			cardAnalysis.SignupBonusEarned = progress.BonusValue
+		}
+	}
+
+	total := earned.Add(decimal.NewFromFloat(cardAnalysis.SignupBonusEarned))
+	fee := decimal.NewFromFloat(cardAnalysis.AnnualFee)
+	cardAnalysis.NetValue = total.Sub(fee).Round(2).InexactFloat64()
+	ongoing := earned.Sub(decimal.NewFromFloat(cardAnalysis.OngoingFee))
+	cardAnalysis.OngoingNetValue = ongoing.Round(2).InexactFloat64()
+	cardAnalysis.Unprofitable = cardAnalysis.OngoingNetValue < 0
+
+	return &cardAnalysis, nil
+}
+
+// WriteReport writes the analysis to w as a table, one card per row.
+func (analysis *WalletAnalysis) WriteReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Wallet analysis %s to %s (%s valuation)\n",
+		analysis.From.Format(time.DateOnly), analysis.To.Format(time.DateOnly),
+		analysis.Valuation)
+	fmt.Fprintln(tw, "Card\tTxns\tSpend\tEarned\tBonus\tFee\tNet\t"+
+		"Ongoing net\tFlag\t")
+	for _, card := range analysis.Cards {
+		flag := ""
+		if card.Unprofitable {
+			flag = "UNPROFITABLE"
+		}
+		bonus := fmt.Sprintf("%.2f", card.SignupBonusEarned)
+		if card.BonusUnknown {
+			bonus = "?"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\t%.2f\t%s\t%.2f\t%.2f\t%.2f\t%s\t\n",
+			card.CardName, card.Transactions, card.Spend, card.RewardsEarned,
+			bonus, card.AnnualFee, card.NetValue,
+			card.OngoingNetValue,
+			flag)
+	}
+	fmt.Fprintf(tw, "Total\t\t\t%.2f\t\t%.2f\t%.2f\t\t\t\n",
+		analysis.RewardsEarned, analysis.Fees, analysis.NetValue)
+	return tw.Flush()
+}
@@ -1,18 +1,29 @@
 package shop
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/ayushh-vermaa/polymer/internal/rewards"
 	"github.com/ayushh-vermaa/polymer/store"
-
-	"go.mongodb.org/mongo-driver/mongo"
+	"github.com/shopspring/decimal"
 )
 
 // CalculateBonusValue calculates the highest applicable reward value for a
-// given merchant category based on the card's rewards and point value.
-func CalculateBonusValue(categoryID int,
-	card *rewards.CardDetail) *store.RewardDetails {
+// purchase based on the card's rewards and point value. Date limited bonuses
+// only apply to purchases made within their window. A capped bonus only
+// earns its multiplier on the part of the purchase that fits under what is
+// left of its spend limit in history; the rest earns the base rate, and the
+// returned Amount and Value are the blend of both. For foreign purchases the
+// card's foreign transaction fee is recorded and subtracted from Value.
+// Points are priced by valuation, whose name and cents per point are recorded
+// so the value can be recomputed later.
+func CalculateBonusValue(purchase *Purchase, card *rewards.CardDetail,
+	history SpendHistory, valuation Valuation) *store.RewardDetails {
+
+	centsPerPoint := valuation.CentsPerPoint(card)
 
 	// Start with base value
 	bestReward := store.RewardDetails{
@@ -20,60 +31,92 @@ func CalculateBonusValue(categoryID int,
 		Currency:        card.BaseSpendEarnCurrency,
 		CashConvertible: card.BaseSpendEarnIsCash == 1,
 		CashConvValue:   card.BaseSpendEarnCashValue,
-		Value:           card.RewardValue(card.BaseSpendAmount),
+		Value:           pointValue(card.BaseSpendAmount, centsPerPoint),
+		Valuation:       valuation.Name(),
+		CentsPerPoint:   centsPerPoint,
 	}
 
-	for _, bonus := range card.SpendBonusCategory {
-		if !bonus.IsApplicable(categoryID) {
+	for i := range card.SpendBonusCategory {
+		bonus := &card.SpendBonusCategory[i]
+		if !bonus.AppliesTo(purchase.CategoryID, purchase.MCC) ||
+			!bonus.IsInDateLimit(purchase.At) {
 			continue
-		} else if bonus.EarnMultiplier > bestReward.Amount {
-			bestReward.Amount = bonus.EarnMultiplier
-			bestReward.Value = card.RewardValue(bonus.EarnMultiplier)
 		}
+
+		amount := bonus.EarnMultiplier
+		remaining := history.RemainingCap(card.CardKey, bonus, purchase.At)
+		if remaining < purchase.Amount || remaining == 0 {
+			share := 0.0
+			if purchase.Amount > 0 {
+				share = remaining / purchase.Amount
+			}
+			amount = share*bonus.EarnMultiplier +
+				(1-share)*card.BaseSpendAmount
+		}
+
+		if amount > bestReward.Amount {
+			bestReward.Amount = amount
+			bestReward.Value = pointValue(amount, centsPerPoint)
+		}
+	}
+
+	if purchase.IsForeign() {
+		fee := card.FxFeeRate()
+		value := decimal.NewFromFloat(bestReward.Value)
+		bestReward.FxFee = fee
+		bestReward.Value = value.Sub(decimal.NewFromFloat(fee)).InexactFloat64()
 	}
 
 	return &bestReward
 }
 
-func FetchAndStoreCard(client *mongo.Client,
-	cardKey string) *rewards.CardDetail {
+// CardAsOf returns the terms of the card with cardKey at time at, from the
+// card's revision history. Before the first revision was observed, the terms
+// of the first revision are the best known and are returned, and so are the
+// stored card's terms for a card stored without revisions.
+func CardAsOf(ctx context.Context, cards store.CardRepository, cardKey string,
+	at time.Time) (*rewards.CardDetail, error) {
 
-	cardDetailPtr, err := rewards.FetchCardDetail(cardKey)
-	if err != nil {
-		return cardDetailPtr
+	revision, err := cards.GetCardRevisionAt(ctx, cardKey, at)
+	if err == nil {
+		return &revision.CardDetail, nil
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
 	}
 
-	var cardDetail rewards.CardDetail
-	_, err = store.InsertCard(client, cardDetailPtr)
+	revisions, err := cards.ListCardRevisions(ctx, cardKey)
 	if err != nil {
-		return &cardDetail
+		return nil, err
+	}
+	if len(revisions) > 0 {
+		return &revisions[0].CardDetail, nil
 	}
 
-	return &cardDetail
+	stored, err := cards.GetCardsByKeys(ctx, []string{cardKey})
+	if err != nil {
+		return nil, err
+	}
+	card, ok := stored[cardKey]
+	if !ok {
+		return nil, fmt.Errorf("%w: no card stored with key: %s",
+			store.ErrNotFound, cardKey)
+	}
+	return &card.CardDetail, nil
 }
 
-// GetCard takes a cardKey string and tries to find the matching CardDetail in
-// the database and return it. If not found, it feteches from the API and
-// returns the result after storing it in the database.
-func GetCards(client *mongo.Client, cardKeys []string) ([]*rewards.CardDetail,
-	error) {
+// FetchAndStoreCard fetches the CardDetail for cardKey from the API and stores
+// it in the card repository.
+func FetchAndStoreCard(ctx context.Context, cards store.CardRepository,
+	cardKey string) (*rewards.CardDetail, error) {
 
-	cards, err := store.GetCardsByKeys(client, cardKeys)
+	cardDetail, err := rewards.FetchCardDetail(ctx, cardKey)
 	if err != nil {
 		return nil, err
 	}
 
-	var cardDetails []*rewards.CardDetail
-	for cardKey, card := range cards {
-		if card == nil {
-			cardDetail := FetchAndStoreCard(client, cardKey)
-			cardDetails = append(cardDetails, cardDetail)
-			log.Printf("Fetched and stored data for: %s", cardDetail.CardName)
-		} else {
-			cardDetails = append(cardDetails, &card.CardDetail)
-			log.Printf("Found data for: %s", card.CardDetail.CardName)
-		}
+	if _, err := cards.InsertCard(ctx, cardDetail); err != nil {
+		return nil, err
 	}
 
-	return cardDetails, nil
+	return cardDetail, nil
 }
@@ -0,0 +1,251 @@
+package shop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/store"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrNoClient is returned for a card that is not stored by a CardCache
+// without an API client to fetch it with.
+var ErrNoClient = errors.New("card not stored and no Rewards API client")
+
+// CardError is a card that could not be fetched from the API or stored.
+type CardError struct {
+	CardKey string
+	Err     error
+}
+
+func (err *CardError) Error() string {
+	return fmt.Sprintf("card %s: %v", err.CardKey, err.Err)
+}
+
+func (err *CardError) Unwrap() error {
+	return err.Err
+}
+
+// CardErrors lists the cards a CardCache failed to get, in the order they
+// were requested. errors.Is and errors.As look through every one of them.
+type CardErrors []*CardError
+
+func (errs CardErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("failed to get %d card(s): %s", len(errs),
+		strings.Join(messages, "; "))
+}
+
+func (errs CardErrors) Unwrap() []error {
+	unwrapped := make([]error, len(errs))
+	for i, err := range errs {
+		unwrapped[i] = err
+	}
+	return unwrapped
+}
+
+// DefaultCardMaxAge is how long a stored card is served before it is
+// refreshed from the API unless configured otherwise.
+const DefaultCardMaxAge = 24 * time.Hour
+
+// DefaultCacheConcurrency is the number of missing cards a CardCache fetches
+// at once unless configured otherwise.
+const DefaultCacheConcurrency = 8
+
+// CardCache is a read-through cache of card details: cards missing from the
+// repository are fetched from the Rewards API and stored on first use. Stored
+// cards older than MaxAge are served as they are but refreshed in the
+// background. Concurrent requests for the same card share a single API call.
+// Without a Client, only the stored cards are served, and never refreshed.
+// A CardCache is owned by the Service using it; its fields must not change
+// once it is in use.
+//
+// A CardCache must not be copied after first use.
+type CardCache struct {
+	Cards       store.CardRepository
+	Client      *rewards.Client // API client, nil to serve stored cards only
+	Concurrency int             // Cards fetched at once, 0 for the default
+	MaxAge      time.Duration   // Age at which a card is stale, 0 for never
+
+	flights    singleflight.Group // Fetches in progress, by card key
+	refreshing sync.WaitGroup     // Background refreshes
+}
+
+// NewCardCache returns a CardCache in front of cards, fetching from the API
+// with client, if not nil, and refreshing cards older than maxAge.
+func NewCardCache(cards store.CardRepository, client *rewards.Client,
+	maxAge time.Duration) *CardCache {
+
+	return &CardCache{Cards: cards, Client: client, MaxAge: maxAge}
+}
+
+// Get returns the details of the cards with cardKeys, in the same order. The
+// cards not stored yet are fetched concurrently and stored. Cards that fail
+// to fetch or store are left out of the result and reported in a CardErrors
+// error, returned along with the cards that were found.
+func (cache *CardCache) Get(ctx context.Context,
+	cardKeys []string) ([]*rewards.CardDetail, error) {
+
+	found, _, err := cache.load(ctx, cardKeys)
+
+	cards := make([]*rewards.CardDetail, 0, len(cardKeys))
+	for _, cardKey := range cardKeys {
+		if card, ok := found[cardKey]; ok {
+			cards = append(cards, card)
+		}
+	}
+	return cards, err
+}
+
+// Fill fetches and stores the cards with cardKeys that are not stored yet,
+// returning how many were stored. Failures are reported as by Get.
+func (cache *CardCache) Fill(ctx context.Context,
+	cardKeys []string) (int, error) {
+
+	_, fetched, err := cache.load(ctx, cardKeys)
+	return fetched, err
+}
+
+// Wait blocks until the background refreshes started so far are done.
+func (cache *CardCache) Wait() {
+	cache.refreshing.Wait()
+}
+
+// IsStale reports whether card is due for a refresh at time now.
+func (cache *CardCache) IsStale(card *store.Card, now time.Time) bool {
+	return cache.MaxAge > 0 && now.Sub(card.FetchedAt) >= cache.MaxAge
+}
+
+// load returns the details of the cards with cardKeys by card key, fetching
+// and storing those not stored yet, and how many it stored. Stale cards are
+// refreshed in the background. Only failing to read the repository fails the
+// whole load.
+func (cache *CardCache) load(ctx context.Context,
+	cardKeys []string) (map[string]*rewards.CardDetail, int, error) {
+
+	stored, err := cache.Cards.GetCardsByKeys(ctx, cardKeys)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	now := time.Now()
+	found := make(map[string]*rewards.CardDetail, len(cardKeys))
+	var missing []string
+	seen := make(map[string]bool, len(cardKeys))
+	for _, cardKey := range cardKeys {
+		if seen[cardKey] {
+			continue
+		}
+		seen[cardKey] = true
+		card, ok := stored[cardKey]
+		if !ok {
+			missing = append(missing, cardKey)
+			continue
+		}
+		found[cardKey] = &card.CardDetail
+		if cache.Client != nil && cache.IsStale(card, now) {
+			cache.refresh(ctx, cardKey)
+		}
+	}
+	if len(missing) == 0 {
+		return found, 0, nil
+	}
+
+	concurrency := cache.Concurrency
+	if concurrency < 1 {
+		concurrency = DefaultCacheConcurrency
+	}
+	var errs CardErrors
+	for _, result := range fetchCardDetails(ctx, missing, concurrency,
+		cache.fetch) {
+		if result.err != nil {
+			errs = append(errs, &CardError{result.cardKey, result.err})
+			continue
+		}
+		found[result.cardKey] = result.detail
+	}
+
+	fetched := len(missing) - len(errs)
+	if len(errs) > 0 {
+		return found, fetched, errs
+	}
+	return found, fetched, nil
+}
+
+// refresh fetches and stores the card with cardKey in the background.
+func (cache *CardCache) refresh(ctx context.Context, cardKey string) {
+	ctx = context.WithoutCancel(ctx)
+	cache.refreshing.Add(1)
+	go func() {
+		defer cache.refreshing.Done()
+		if _, err := cache.fetch(ctx, cardKey); err != nil {
+			log.Printf("Failed to refresh card %s: %v", cardKey, err)
+		}
+	}()
+}
+
+// fetch fetches the card with cardKey from the API and stores it, unless it
+// was stored fresh in the meantime. Concurrent fetches of a card share one
+// call, which carries on if ctx is done before it finishes.
+func (cache *CardCache) fetch(ctx context.Context,
+	cardKey string) (*rewards.CardDetail, error) {
+
+	flight := cache.flights.DoChan(cardKey, func() (any, error) {
+		return cache.fetchAndStore(context.WithoutCancel(ctx), cardKey)
+	})
+	select {
+	case result := <-flight:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.(*rewards.CardDetail), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetchAndStore fetches the card with cardKey from the API and upserts it,
+// keeping the catalog status of the stored card, if any. A stored card that
+// is no longer stale is returned as it is.
+func (cache *CardCache) fetchAndStore(ctx context.Context,
+	cardKey string) (*rewards.CardDetail, error) {
+
+	stored, err := cache.Cards.GetCardsByKeys(ctx, []string{cardKey})
+	if err != nil {
+		return nil, err
+	}
+	card := store.Card{}
+	if existing, ok := stored[cardKey]; ok {
+		if !cache.IsStale(existing, time.Now()) {
+			return &existing.CardDetail, nil
+		}
+		card = *existing
+	}
+
+	if cache.Client == nil {
+		return nil, ErrNoClient
+	}
+	detail, err := cache.Client.FetchCardDetail(ctx, cardKey)
+	if err != nil {
+		return nil, err
+	}
+
+	card.CardDetail = *detail
+	card.FetchedAt = time.Now()
+	upserted, err := cache.Cards.UpsertCard(ctx, &card)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Fetched and stored data for: %s",
+		upserted.CardDetail.CardName)
+	return &upserted.CardDetail, nil
+}
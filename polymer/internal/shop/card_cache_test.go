@@ -0,0 +1,64 @@
+package shop_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/internal/rewards/rewardstest"
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+func TestCardCacheGet(t *testing.T) {
+	mock := rewardstest.NewServer(rewardstest.DefaultFixtures(), "test-key")
+	ts := httptest.NewServer(mock)
+	defer ts.Close()
+	client := rewards.NewClient(mock.Config(ts.URL))
+	client.Retry = rewards.RetryPolicy{}
+
+	repo := store.NewMemoryRepository()
+	if _, err := repo.Cards.UpsertCard(context.Background(),
+		&store.Card{CardDetail: *goldCard}); err != nil {
+		t.Fatalf("UpsertCard() error = %v", err)
+	}
+
+	for _, concurrency := range []int{0, 1, 2} {
+		cache := shop.NewCardCache(repo.Cards, client, 0)
+		cache.Concurrency = concurrency
+		cardKeys := []string{"citi-doublecash", "no-such-card", "amex-gold",
+			"discover-itmiles", "citi-doublecash", "chase-sapphirepreferred"}
+
+		before := mock.Requests()
+		cards, err := cache.Get(context.Background(), cardKeys)
+		var cardErrs shop.CardErrors
+		if !errors.As(err, &cardErrs) || len(cardErrs) != 1 ||
+			cardErrs[0].CardKey != "no-such-card" {
+			t.Errorf("concurrency %d: Get() error = %v, want no-such-card",
+				concurrency, err)
+		}
+		want := []string{"citi-doublecash", "amex-gold", "discover-itmiles",
+			"citi-doublecash", "chase-sapphirepreferred"}
+		if len(cards) != len(want) {
+			t.Fatalf("concurrency %d: Get() = %d cards, want %d",
+				concurrency, len(cards), len(want))
+		}
+		for i, card := range cards {
+			if card.CardKey != want[i] {
+				t.Errorf("concurrency %d: card %d = %s, want %s",
+					concurrency, i, card.CardKey, want[i])
+			}
+		}
+		// Only the unknown card is fetched after the first round.
+		wantRequests := 1
+		if concurrency == 0 {
+			wantRequests = 4
+		}
+		if got := mock.Requests() - before; got != wantRequests {
+			t.Errorf("concurrency %d: Rewards API requests = %d, want %d",
+				concurrency, got, wantRequests)
+		}
+	}
+}
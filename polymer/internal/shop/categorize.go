@@ -0,0 +1,348 @@
+package shop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// ErrInvalidRule is returned for a categorization rule that cannot match or
+// names an unknown category.
+var ErrInvalidRule = errors.New("invalid category rule")
+
+// Where the category of a merchant came from.
+const (
+	CategorySourceOverride   = "override"   // A rule of the wallet
+	CategorySourceDomain     = "domain"     // The stored merchant domain
+	CategorySourceMCC        = "mcc"        // The merchant category code
+	CategorySourceRule       = "rule"       // A shared categorization rule
+	CategorySourceClassifier = "classifier" // The trained classifier
+)
+
+// CategoryExplanation tells how the category of a merchant was decided.
+type CategoryExplanation struct {
+	Source  string `json:"source"`
+	Domain  string `json:"domain,omitempty"`  // Stored domain found
+	RuleID  string `json:"ruleID,omitempty"`  // Rule that fired
+	Rule    string `json:"rule,omitempty"`    // Name of the rule that fired
+	Match   string `json:"match,omitempty"`   // How the rule matched
+	Field   string `json:"field,omitempty"`   // Merchant field it matched
+	Matched string `json:"matched,omitempty"` // Pattern or keyword matched
+
+	// Confidence score of the classifier's prediction
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// source returns the source of the explanation, or "" if it is nil.
+func (explanation *CategoryExplanation) source() string {
+	if explanation == nil {
+		return ""
+	}
+	return explanation.Source
+}
+
+// String describes the explanation in a line of text.
+func (explanation *CategoryExplanation) String() string {
+	switch explanation.Source {
+	case CategorySourceDomain:
+		return "stored domain " + explanation.Domain
+	case CategorySourceOverride, CategorySourceRule:
+		return fmt.Sprintf(`%s %q: %s %s "%s"`, explanation.Source,
+			explanation.Rule, explanation.Field, explanation.Match,
+			explanation.Matched)
+	case CategorySourceClassifier:
+		return fmt.Sprintf("%s, confidence %.2f", explanation.Source,
+			explanation.Confidence)
+	default:
+		return explanation.Source
+	}
+}
+
+// Categorize decides the spend category of merchant for the wallet with
+// walletID, from its domain name, descriptor and MCC. It tries, in order:
+// the rules of the wallet, which override everything else; the stored
+// merchant domain; the category covering the merchant's MCC; the shared
+// rules; and last the service's classifier. The category found, or
+// store.UncategorizedID if none is, carries the explanation of which of these
+// decided it.
+func (service *Service) Categorize(ctx context.Context, walletID string,
+	merchant store.MerchantDetails) (*DomainCategory, error) {
+
+	repo := service.Repo
+	stored, err := repo.CategoryRules.ListCategoryRules(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category rules: %w", err)
+	}
+	var overrides, shared []*store.CategoryRule
+	for _, rule := range stored {
+		if rule.WalletID != "" {
+			overrides = append(overrides, rule)
+		} else {
+			shared = append(shared, rule)
+		}
+	}
+
+	fired, err := matchRules(overrides, &merchant)
+	if err != nil {
+		return nil, err
+	}
+	if fired != nil {
+		return fired.category(CategorySourceOverride, merchant.MCC), nil
+	}
+
+	category := &DomainCategory{ID: store.UncategorizedID, MCC: merchant.MCC}
+	if merchant.DomainName != "" {
+		category, err = lookupDomainCategory(ctx, repo.Domains,
+			merchant.DomainName)
+		if err != nil {
+			return nil, err
+		}
+		if merchant.MCC != "" {
+			category.MCC = merchant.MCC
+		}
+	}
+	if category.ID < 0 {
+		category.fromMCC()
+	}
+	if category.ID >= 0 {
+		return category, nil
+	}
+
+	fired, err = matchRules(shared, &merchant)
+	if err != nil {
+		return nil, err
+	}
+	if fired != nil {
+		return fired.category(CategorySourceRule, category.MCC), nil
+	}
+
+	if predicted, ok := service.classify(merchant.DomainName,
+		merchant.Descriptor); ok {
+		predicted.MCC = category.MCC
+		return predicted, nil
+	}
+	return category, nil
+}
+
+// firedRule is a rule that matched a merchant, with what it matched.
+type firedRule struct {
+	rule    *store.CategoryRule
+	matched string
+}
+
+// category returns the category rule assigns, explained as coming from
+// source, with the merchant category code mcc.
+func (fired *firedRule) category(source, mcc string) *DomainCategory {
+	rule := fired.rule
+	category := &DomainCategory{
+		ID:  rule.CategoryID,
+		MCC: mcc,
+		Explanation: &CategoryExplanation{
+			Source:  source,
+			RuleID:  rule.ID.Hex(),
+			Rule:    rule.Name,
+			Match:   rule.Match,
+			Field:   rule.Field,
+			Matched: fired.matched,
+		},
+	}
+	if known, ok := rewards.DefaultTaxonomy().Category(rule.CategoryID); ok {
+		category.Name = known.Name
+	}
+	return category
+}
+
+// matchRules returns the first of rules that matches merchant, or nil if
+// none does.
+func matchRules(rules []*store.CategoryRule,
+	merchant *store.MerchantDetails) (*firedRule, error) {
+
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	domain, _ := NormalizeDomain(merchant.DomainName)
+	descriptor := normalizeDescriptor(merchant.Descriptor)
+	for _, rule := range rules {
+		value := domain
+		if rule.Field == store.RuleFieldDescriptor {
+			value = descriptor
+		}
+		if value == "" {
+			continue
+		}
+		matched, ok, err := matchRule(rule.BaseCategoryRule, value)
+		if err != nil {
+			return nil, fmt.Errorf("category rule %s: %w", rule.ID.Hex(), err)
+		}
+		if ok {
+			return &firedRule{rule: rule, matched: matched}, nil
+		}
+	}
+	return nil, nil
+}
+
+// matchRule reports whether rule matches the merchant field value, and the
+// pattern or keyword that matched it.
+func matchRule(rule *store.BaseCategoryRule,
+	value string) (string, bool, error) {
+
+	switch rule.Match {
+	case store.RuleMatchExact:
+		return rule.Pattern, value == rule.Pattern, nil
+	case store.RuleMatchSuffix:
+		return rule.Pattern, value == rule.Pattern ||
+			strings.HasSuffix(value, "."+rule.Pattern), nil
+	case store.RuleMatchRegex:
+		if rule.Regexp == nil {
+			return "", false, fmt.Errorf("%w: invalid pattern: %q",
+				ErrInvalidRule, rule.Pattern)
+		}
+		return rule.Pattern, rule.Regexp.MatchString(value), nil
+	case store.RuleMatchKeywords:
+		for _, keyword := range rule.Keywords {
+			if strings.Contains(value, keyword) {
+				return keyword, true, nil
+			}
+		}
+		return "", false, nil
+	default:
+		return "", false, fmt.Errorf("%w: unknown match: %q", ErrInvalidRule,
+			rule.Match)
+	}
+}
+
+// normalizeDescriptor returns a statement descriptor in the form rules
+// match it in: lowercase, with runs of spaces collapsed.
+func normalizeDescriptor(descriptor string) string {
+	return strings.Join(strings.Fields(strings.ToLower(descriptor)), " ")
+}
+
+// NormalizeCategoryRule checks that rule can match and names a category of
+// the taxonomy, and puts its field, pattern and keywords in the form they
+// are matched in, compiling the pattern of a regex rule. The field defaults
+// to the domain.
+func NormalizeCategoryRule(rule *store.BaseCategoryRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+	if rule.Field == "" {
+		rule.Field = store.RuleFieldDomain
+	}
+	if rule.Field != store.RuleFieldDomain &&
+		rule.Field != store.RuleFieldDescriptor {
+		return fmt.Errorf("%w: %s: unknown field: %q", ErrInvalidRule,
+			rule.Name, rule.Field)
+	}
+	if _, ok := rewards.DefaultTaxonomy().Category(rule.CategoryID); !ok {
+		return fmt.Errorf("%w: %s: unknown category: %d", ErrInvalidRule,
+			rule.Name, rule.CategoryID)
+	}
+
+	if rule.Match == store.RuleMatchKeywords {
+		if rule.Pattern != "" {
+			return fmt.Errorf("%w: %s: keywords rules take no pattern",
+				ErrInvalidRule, rule.Name)
+		}
+		var keywords []string
+		for _, keyword := range rule.Keywords {
+			keyword = normalizeDescriptor(keyword)
+			if keyword != "" && !slices.Contains(keywords, keyword) {
+				keywords = append(keywords, keyword)
+			}
+		}
+		if len(keywords) == 0 {
+			return fmt.Errorf("%w: %s: keywords are required", ErrInvalidRule,
+				rule.Name)
+		}
+		rule.Keywords = keywords
+		return nil
+	}
+
+	if len(rule.Keywords) > 0 {
+		return fmt.Errorf("%w: %s: only keywords rules take keywords",
+			ErrInvalidRule, rule.Name)
+	}
+	if strings.TrimSpace(rule.Pattern) == "" {
+		return fmt.Errorf("%w: %s: pattern is required", ErrInvalidRule,
+			rule.Name)
+	}
+	switch rule.Match {
+	case store.RuleMatchExact, store.RuleMatchSuffix:
+		if rule.Field == store.RuleFieldDescriptor {
+			if rule.Match == store.RuleMatchSuffix {
+				return fmt.Errorf("%w: %s: suffix rules match domains only",
+					ErrInvalidRule, rule.Name)
+			}
+			rule.Pattern = normalizeDescriptor(rule.Pattern)
+			return nil
+		}
+		domain, err := NormalizeDomain(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidRule, rule.Name, err)
+		}
+		rule.Pattern = domain
+	case store.RuleMatchRegex:
+		regex, err := store.CompileRulePattern(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidRule, rule.Name, err)
+		}
+		rule.Regexp = regex
+	default:
+		return fmt.Errorf("%w: %s: unknown match: %q", ErrInvalidRule,
+			rule.Name, rule.Match)
+	}
+	return nil
+}
+
+// ParseCategoryRules reads shared categorization rules from a JSON array of
+// rules, in the order they are to be tried, and normalizes them.
+func ParseCategoryRules(r io.Reader) ([]*store.BaseCategoryRule, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var rules []*store.BaseCategoryRule
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("failed to decode category rules: %w", err)
+	}
+
+	var errs []error
+	for i, rule := range rules {
+		if rule == nil {
+			errs = append(errs, fmt.Errorf("%w: rule %d is null",
+				ErrInvalidRule, i+1))
+			continue
+		}
+		if rule.WalletID != "" {
+			errs = append(errs, fmt.Errorf(
+				"%w: rule %d: shared rules have no wallet", ErrInvalidRule,
+				i+1))
+			continue
+		}
+		if err := NormalizeCategoryRule(rule); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", i+1, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// LoadCategoryRules reads shared categorization rules from the JSON file
+// with the given name, as ParseCategoryRules does.
+func LoadCategoryRules(name string) ([]*store.BaseCategoryRule, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseCategoryRules(file)
+}
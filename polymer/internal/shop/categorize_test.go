@@ -0,0 +1,192 @@
+package shop_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// sharedRules are tried in order, so the Uber Eats regex fires before the
+// broader uber keyword.
+const sharedRules = `[
+	{"name": "Uber Eats", "match": "regex", "field": "descriptor",
+		"pattern": "^uber\\s*\\*?\\s*eats", "categoryID": 1005},
+	{"name": "Uber", "match": "keywords", "field": "descriptor",
+		"keywords": ["UBER"], "categoryID": 1031},
+	{"name": "Starbucks", "match": "exact",
+		"pattern": "https://www.starbucks.com/", "categoryID": 1001}
+]`
+
+// newCategorizeService returns a service whose repository holds the shared
+// rules, the amazon.com domain and a rule of the wallet "override" taking
+// every amazon.com subdomain for electronics.
+func newCategorizeService(t *testing.T) *shop.Service {
+	t.Helper()
+	ctx := context.Background()
+	repo := store.NewMemoryRepository()
+
+	rules, err := shop.ParseCategoryRules(strings.NewReader(sharedRules))
+	if err != nil {
+		t.Fatalf("ParseCategoryRules() error = %v", err)
+	}
+	if _, err := repo.CategoryRules.ReplaceCategoryRules(ctx,
+		rules); err != nil {
+		t.Fatalf("ReplaceCategoryRules() error = %v", err)
+	}
+	override := &store.BaseCategoryRule{Name: "Amazon", WalletID: "override",
+		Match: store.RuleMatchSuffix, Pattern: "amazon.com", CategoryID: 1083}
+	if err := shop.NormalizeCategoryRule(override); err != nil {
+		t.Fatalf("NormalizeCategoryRule() error = %v", err)
+	}
+	if _, err := repo.CategoryRules.InsertCategoryRule(ctx,
+		override); err != nil {
+		t.Fatalf("InsertCategoryRule() error = %v", err)
+	}
+	if _, err := repo.Domains.InsertDomain(ctx, &store.BaseDomain{
+		Name: "amazon.com", CategoryID: 1082,
+		CategoryName: "Online Shopping"}); err != nil {
+		t.Fatalf("InsertDomain() error = %v", err)
+	}
+	return shop.NewService(repo, nil)
+}
+
+func TestCategorize(t *testing.T) {
+	service := newCategorizeService(t)
+
+	tests := []struct {
+		name        string
+		walletID    string
+		merchant    store.MerchantDetails
+		wantID      int
+		wantSource  string
+		wantMatched string
+	}{
+		{"override before the domain", "override",
+			store.MerchantDetails{DomainName: "https://smile.amazon.com/"},
+			1083, shop.CategorySourceOverride, "amazon.com"},
+		{"domain for other wallets", "other",
+			store.MerchantDetails{DomainName: "smile.amazon.com"}, 1082,
+			shop.CategorySourceDomain, ""},
+		{"MCC before shared rules", "other",
+			store.MerchantDetails{DomainName: "starbucks.com", MCC: "5411"},
+			5, shop.CategorySourceMCC, ""},
+		{"exact domain rule", "other",
+			store.MerchantDetails{DomainName: "www.starbucks.com"}, 1001,
+			shop.CategorySourceRule, "starbucks.com"},
+		{"exact rule not matching a subdomain", "other",
+			store.MerchantDetails{DomainName: "shop.starbucks.com"}, -1, "",
+			""},
+		{"earlier rule first, regardless of case", "other",
+			store.MerchantDetails{Descriptor: "UBER   *EATS 8005928996"},
+			1005, shop.CategorySourceRule, `^uber\s*\*?\s*eats`},
+		{"keywords", "other",
+			store.MerchantDetails{Descriptor: "Uber *Trip HELP.UBER.COM"},
+			1031, shop.CategorySourceRule, "uber"},
+		{"nothing matches", "other",
+			store.MerchantDetails{DomainName: "example.org",
+				Descriptor: "CORNER STORE"}, -1, "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			category, err := service.Categorize(context.Background(),
+				test.walletID, test.merchant)
+			if err != nil {
+				t.Fatalf("Categorize() error = %v", err)
+			}
+			if category.ID != test.wantID {
+				t.Errorf("Categorize() = %d, want %d", category.ID,
+					test.wantID)
+			}
+			explanation := category.Explanation
+			if test.wantSource == "" {
+				if explanation != nil {
+					t.Errorf("explanation = %v, want none", explanation)
+				}
+				return
+			}
+			if explanation == nil || explanation.Source != test.wantSource ||
+				explanation.Matched != test.wantMatched {
+				t.Errorf("explanation = %+v, want %s matching %q",
+					explanation, test.wantSource, test.wantMatched)
+			}
+		})
+	}
+}
+
+func TestCategorizeInvalidStoredRule(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewMemoryRepository()
+	if _, err := repo.CategoryRules.InsertCategoryRule(ctx,
+		&store.BaseCategoryRule{Name: "Broken", Match: store.RuleMatchRegex,
+			Field: store.RuleFieldDescriptor, Pattern: "(",
+			CategoryID: 2}); err != nil {
+		t.Fatalf("InsertCategoryRule() error = %v", err)
+	}
+
+	_, err := shop.NewService(repo, nil).Categorize(ctx, "",
+		store.MerchantDetails{Descriptor: "CORNER STORE"})
+	if !errors.Is(err, shop.ErrInvalidRule) {
+		t.Errorf("Categorize() error = %v, want %v", err, shop.ErrInvalidRule)
+	}
+}
+
+func TestNormalizeCategoryRule(t *testing.T) {
+	tests := []struct {
+		name        string
+		rule        store.BaseCategoryRule
+		wantPattern string
+		wantErr     bool
+	}{
+		{"domain", store.BaseCategoryRule{Name: "a", Match: "suffix",
+			Pattern: "https://www.Amazon.com/", CategoryID: 2},
+			"amazon.com", false},
+		{"descriptor", store.BaseCategoryRule{Name: "a", Match: "exact",
+			Field: "descriptor", Pattern: " Corner  STORE ", CategoryID: 2},
+			"corner store", false},
+		{"regex", store.BaseCategoryRule{Name: "a", Match: "regex",
+			Pattern: `^uber\b`, CategoryID: 2}, `^uber\b`, false},
+		{"invalid regex", store.BaseCategoryRule{Name: "a", Match: "regex",
+			Pattern: "(", CategoryID: 2}, "", true},
+		{"no name", store.BaseCategoryRule{Match: "exact", Pattern: "a.com",
+			CategoryID: 2}, "", true},
+		{"unknown category", store.BaseCategoryRule{Name: "a",
+			Match: "exact", Pattern: "a.com", CategoryID: 9999}, "", true},
+		{"unknown field", store.BaseCategoryRule{Name: "a", Match: "exact",
+			Field: "mcc", Pattern: "5812", CategoryID: 2}, "", true},
+		{"suffix descriptor", store.BaseCategoryRule{Name: "a",
+			Match: "suffix", Field: "descriptor", Pattern: "store",
+			CategoryID: 2}, "", true},
+		{"keywords with a pattern", store.BaseCategoryRule{Name: "a",
+			Match: "keywords", Pattern: "a", Keywords: []string{"a"},
+			CategoryID: 2}, "", true},
+		{"unknown match", store.BaseCategoryRule{Name: "a", Match: "glob",
+			Pattern: "*.com", CategoryID: 2}, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := shop.NormalizeCategoryRule(&test.rule)
+			if test.wantErr {
+				if !errors.Is(err, shop.ErrInvalidRule) {
+					t.Errorf("NormalizeCategoryRule() error = %v, want %v",
+						err, shop.ErrInvalidRule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeCategoryRule() error = %v", err)
+			}
+			if test.rule.Pattern != test.wantPattern {
+				t.Errorf("pattern = %q, want %q", test.rule.Pattern,
+					test.wantPattern)
+			}
+			if (test.rule.Regexp != nil) != (test.rule.Match == "regex") {
+				t.Errorf("Regexp = %v for a %s rule", test.rule.Regexp,
+					test.rule.Match)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package shop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ayushh-vermaa/polymer/store"
+	"golang.org/x/net/publicsuffix"
+)
+
+// DefaultMinConfidence is the confidence a classifier prediction needs to be
+// used as a merchant's category unless configured otherwise. On the labeled
+// merchants of the package tests, it keeps every wrong prediction out while
+// accepting most right ones.
+const DefaultMinConfidence = 0.6
+
+// ErrNotEnoughExamples is returned when training a Classifier from examples
+// of fewer than two categories.
+var ErrNotEnoughExamples = errors.New("not enough examples")
+
+// Classifier predicts the spend category of a merchant from the words and
+// character n-grams of its domain name and statement descriptor, with a
+// multinomial naive Bayes model. It is trained from merchants of known
+// category, such as those of the stored transactions, and can be saved as
+// JSON and loaded again.
+type Classifier struct {
+	TrainedAt time.Time          `json:"trainedAt"`
+	Examples  int                `json:"examples"`
+	Classes   []*ClassifierClass `json:"classes"`
+
+	vocabulary int // Distinct features over every class
+}
+
+// ClassifierClass is a category a Classifier predicts, with how often each
+// feature occurs in its training examples.
+type ClassifierClass struct {
+	CategoryID   int            `json:"categoryID"`
+	CategoryName string         `json:"categoryName"`
+	Examples     int            `json:"examples"`
+	Features     map[string]int `json:"features"`
+
+	total int // Occurrences of every feature
+}
+
+// ClassifierExample is a merchant of known category to train a Classifier
+// from.
+type ClassifierExample struct {
+	DomainName   string `json:"domainName,omitempty"`
+	Descriptor   string `json:"descriptor,omitempty"`
+	CategoryID   int    `json:"categoryID"`
+	CategoryName string `json:"categoryName"`
+}
+
+// Prediction is a category predicted for a merchant, with the classifier's
+// confidence in it.
+type Prediction struct {
+	CategoryID   int     `json:"categoryID"`
+	CategoryName string  `json:"categoryName"`
+	Confidence   float64 `json:"confidence"` // Uncalibrated score, 0 to 1
+}
+
+// TrainClassifier trains a Classifier from examples, which must cover at
+// least two categories.
+func TrainClassifier(examples []ClassifierExample) (*Classifier, error) {
+	classifier := &Classifier{TrainedAt: time.Now()}
+	classes := make(map[int]*ClassifierClass)
+	for _, example := range examples {
+		features := merchantFeatures(example.DomainName, example.Descriptor)
+		if example.CategoryID < 0 || len(features) == 0 {
+			continue
+		}
+		class, ok := classes[example.CategoryID]
+		if !ok {
+			class = &ClassifierClass{
+				CategoryID:   example.CategoryID,
+				CategoryName: example.CategoryName,
+				Features:     make(map[string]int),
+			}
+			classes[example.CategoryID] = class
+			classifier.Classes = append(classifier.Classes, class)
+		}
+		class.Examples++
+		for _, feature := range features {
+			class.Features[feature]++
+		}
+		classifier.Examples++
+	}
+	if len(classifier.Classes) < 2 {
+		return nil, fmt.Errorf("%w: examples of %d categories, want at "+
+			"least 2", ErrNotEnoughExamples, len(classifier.Classes))
+	}
+
+	sort.Slice(classifier.Classes, func(i, j int) bool {
+		return classifier.Classes[i].CategoryID <
+			classifier.Classes[j].CategoryID
+	})
+	classifier.count()
+	return classifier, nil
+}
+
+// count computes the feature totals the model is evaluated with.
+func (classifier *Classifier) count() {
+	vocabulary := make(map[string]bool)
+	for _, class := range classifier.Classes {
+		class.total = 0
+		for feature, count := range class.Features {
+			class.total += count
+			vocabulary[feature] = true
+		}
+	}
+	classifier.vocabulary = len(vocabulary)
+}
+
+// Predict returns every category the classifier knows, most likely first,
+// for the merchant with domainName and descriptor, either of which may be
+// empty. It returns nil if none of the merchant's features were seen in
+// training.
+//
+// The confidences are scores summing to 1, not calibrated probabilities. The
+// naive Bayes log-likelihood of each category over the merchant's features
+// seen in training is divided by the number of all its features, seen or
+// not, and the results are turned into shares of 1 by a softmax. Averaging
+// keeps the many overlapping n-grams of a name from making every prediction
+// certain, and counting the unseen features keeps a merchant matching a few
+// n-grams by chance from scoring high. Thresholds such as
+// DefaultMinConfidence are chosen by testing on labeled merchants.
+func (classifier *Classifier) Predict(domainName,
+	descriptor string) []Prediction {
+
+	all := merchantFeatures(domainName, descriptor)
+	var features []string
+	for _, feature := range all {
+		if classifier.knows(feature) {
+			features = append(features, feature)
+		}
+	}
+	if len(features) == 0 {
+		return nil
+	}
+
+	scores := make([]float64, len(classifier.Classes))
+	maxScore := math.Inf(-1)
+	for i, class := range classifier.Classes {
+		prior := math.Log(float64(class.Examples+1) /
+			float64(classifier.Examples+len(classifier.Classes)))
+		denominator := float64(class.total + classifier.vocabulary)
+		var likelihood float64
+		for _, feature := range features {
+			likelihood += math.Log(float64(class.Features[feature]+1) /
+				denominator)
+		}
+		scores[i] = (prior + likelihood) / float64(len(all))
+		maxScore = max(maxScore, scores[i])
+	}
+
+	var sum float64
+	for i := range scores {
+		scores[i] = math.Exp(classifierSharpness * (scores[i] - maxScore))
+		sum += scores[i]
+	}
+	predictions := make([]Prediction, len(classifier.Classes))
+	for i, class := range classifier.Classes {
+		predictions[i] = Prediction{
+			CategoryID:   class.CategoryID,
+			CategoryName: class.CategoryName,
+			Confidence:   scores[i] / sum,
+		}
+	}
+	sort.SliceStable(predictions, func(i, j int) bool {
+		return predictions[i].Confidence > predictions[j].Confidence
+	})
+	return predictions
+}
+
+// classifierSharpness scales the averaged log-likelihoods before the
+// softmax: the higher, the more the best category's score stands out.
+const classifierSharpness = 6
+
+// knows reports whether feature was seen in training.
+func (classifier *Classifier) knows(feature string) bool {
+	for _, class := range classifier.Classes {
+		if class.Features[feature] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadClassifier reads a Classifier saved as JSON from r.
+func ReadClassifier(r io.Reader) (*Classifier, error) {
+	var classifier Classifier
+	if err := json.NewDecoder(r).Decode(&classifier); err != nil {
+		return nil, fmt.Errorf("failed to decode classifier: %w", err)
+	}
+	if len(classifier.Classes) < 2 {
+		return nil, fmt.Errorf("%w: classifier has %d categories",
+			ErrNotEnoughExamples, len(classifier.Classes))
+	}
+	classifier.count()
+	return &classifier, nil
+}
+
+// LoadClassifier reads the Classifier saved in the file with the given name.
+func LoadClassifier(name string) (*Classifier, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadClassifier(file)
+}
+
+// Save writes the classifier as JSON to the file with the given name,
+// replacing it only once it is completely written.
+func (classifier *Classifier) Save(name string) error {
+	file, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := json.NewEncoder(file).Encode(classifier); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to encode classifier: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), name)
+}
+
+// ClassifierExamples returns the categorized stored merchant domains and
+// the merchants of the stored transactions, once each, as training examples.
+// Only transactions whose category was decided by a rule, a stored domain or
+// an MCC are used: those the classifier categorized, or whose categorization
+// was not recorded, would have it learn from its own predictions.
+func ClassifierExamples(ctx context.Context,
+	repo *store.Repository) ([]ClassifierExample, error) {
+
+	var examples []ClassifierExample
+	seen := make(map[ClassifierExample]bool)
+	add := func(example ClassifierExample) {
+		if example.CategoryID < 0 || seen[example] ||
+			(example.DomainName == "" && example.Descriptor == "") {
+			return
+		}
+		seen[example] = true
+		examples = append(examples, example)
+	}
+
+	domains, err := repo.Domains.ListDomains(ctx, &store.DomainFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list domains: %w", err)
+	}
+	for _, domain := range domains {
+		add(ClassifierExample{
+			DomainName:   domain.Name,
+			CategoryID:   domain.CategoryID,
+			CategoryName: domain.CategoryName,
+		})
+	}
+
+	stored, err := repo.Transactions.ListTransactions(ctx,
+		&store.TransactionFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	for _, transaction := range stored {
+		merchant := transaction.MerchantDetails
+		if merchant.CategorySource == "" ||
+			merchant.CategorySource == CategorySourceClassifier {
+			continue
+		}
+		add(ClassifierExample{
+			DomainName:   merchant.DomainName,
+			Descriptor:   merchant.Descriptor,
+			CategoryID:   merchant.CategoryID,
+			CategoryName: merchant.CategoryName,
+		})
+	}
+	return examples, nil
+}
+
+// merchantFeatures returns the features of a merchant's domain name and
+// descriptor: each word, and the character 3- and 4-grams of each word with
+// its surrounding spaces.
+func merchantFeatures(domainName, descriptor string) []string {
+	words := append(domainWords(domainName), descriptorWords(descriptor)...)
+	var features []string
+	for _, word := range words {
+		features = append(features, "w:"+word)
+		padded := []rune(" " + word + " ")
+		for n := 3; n <= 4; n++ {
+			for i := 0; i+n <= len(padded); i++ {
+				features = append(features, "c:"+string(padded[i:i+n]))
+			}
+		}
+	}
+	return features
+}
+
+// domainWords returns the words of a domain name's labels below its public
+// suffix, so that amazon.co.uk and amazon.com give the same words.
+func domainWords(domainName string) []string {
+	if domainName == "" {
+		return nil
+	}
+	host, err := NormalizeDomain(domainName)
+	if err != nil {
+		return descriptorWords(domainName)
+	}
+	suffix, _ := publicsuffix.PublicSuffix(host)
+	host = strings.TrimSuffix(strings.TrimSuffix(host, suffix), ".")
+	return descriptorWords(strings.ReplaceAll(host, ".", " "))
+}
+
+// descriptorWords returns the words of a statement descriptor: its runs of
+// letters, lowercased, without the payment processor prefix ending in "*"
+// that descriptors such as "SQ *BLUE BOTTLE" start with.
+func descriptorWords(descriptor string) []string {
+	if i := strings.Index(descriptor, "*"); i >= 0 && i <= 8 {
+		descriptor = descriptor[i+1:]
+	}
+	words := strings.FieldsFunc(strings.ToLower(descriptor),
+		func(r rune) bool { return !unicode.IsLetter(r) })
+	kept := words[:0]
+	for _, word := range words {
+		if len([]rune(word)) > 1 {
+			kept = append(kept, word)
+		}
+	}
+	return kept
+}
+
+// classify returns the category the service's classifier predicts for the
+// merchant with domainName and descriptor, if it is confident enough.
+func (service *Service) classify(domainName,
+	descriptor string) (*DomainCategory, bool) {
+
+	if service.Classifier == nil {
+		return nil, false
+	}
+	predictions := service.Classifier.Predict(domainName, descriptor)
+	if len(predictions) == 0 ||
+		predictions[0].Confidence < service.MinConfidence {
+		return nil, false
+	}
+	best := predictions[0]
+	return &DomainCategory{
+		ID:   best.CategoryID,
+		Name: best.CategoryName,
+		Explanation: &CategoryExplanation{
+			Source:     CategorySourceClassifier,
+			Confidence: best.Confidence,
+		},
+	}, true
+}
+
+// Accuracy returns the fraction of examples whose category the classifier
+// predicts correctly, or 0 if there are none.
+func (classifier *Classifier) Accuracy(examples []ClassifierExample) float64 {
+	if len(examples) == 0 {
+		return 0
+	}
+	correct := 0
+	for _, example := range examples {
+		predictions := classifier.Predict(example.DomainName,
+			example.Descriptor)
+		if len(predictions) > 0 &&
+			predictions[0].CategoryID == example.CategoryID {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(examples))
+}
@@ -0,0 +1,279 @@
+package shop_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// Categories of the labeled merchants.
+const (
+	restaurants = 1001
+	supermarket = 5
+	rideshare   = 1031
+	airfare     = 9
+	hotels      = 1021
+)
+
+// trainingMerchants are labeled merchants to train a classifier from.
+var trainingMerchants = []shop.ClassifierExample{
+	{DomainName: "chipotle.com", Descriptor: "CHIPOTLE 1234",
+		CategoryID: restaurants},
+	{DomainName: "olivegarden.com", Descriptor: "OLIVE GARDEN 0042",
+		CategoryID: restaurants},
+	{Descriptor: "SQ *JOES PIZZA", CategoryID: restaurants},
+	{Descriptor: "TST* THAI KITCHEN", CategoryID: restaurants},
+	{Descriptor: "BURGER BAR AND GRILL", CategoryID: restaurants},
+	{Descriptor: "TACO CANTINA", CategoryID: restaurants},
+	{DomainName: "safeway.com", Descriptor: "SAFEWAY #1234",
+		CategoryID: supermarket},
+	{DomainName: "kroger.com", Descriptor: "KROGER FUEL AND MARKET",
+		CategoryID: supermarket},
+	{DomainName: "wholefoodsmarket.com", Descriptor: "WHOLEFDS MKT 10234",
+		CategoryID: supermarket},
+	{DomainName: "traderjoes.com", Descriptor: "TRADER JOE S #552",
+		CategoryID: supermarket},
+	{Descriptor: "FRESH MARKET GROCERY", CategoryID: supermarket},
+	{DomainName: "uber.com", Descriptor: "UBER *TRIP", CategoryID: rideshare},
+	{DomainName: "lyft.com", Descriptor: "LYFT *RIDE SUN 10PM",
+		CategoryID: rideshare},
+	{Descriptor: "YELLOW CAB TAXI", CategoryID: rideshare},
+	{Descriptor: "CITY TAXI CO", CategoryID: rideshare},
+	{DomainName: "delta.com", Descriptor: "DELTA AIR LINES",
+		CategoryID: airfare},
+	{DomainName: "united.com", Descriptor: "UNITED AIRLINES",
+		CategoryID: airfare},
+	{DomainName: "southwest.com", Descriptor: "SOUTHWEST AIRLINES",
+		CategoryID: airfare},
+	{DomainName: "aa.com", Descriptor: "AMERICAN AIRLINES",
+		CategoryID: airfare},
+	{DomainName: "marriott.com", Descriptor: "MARRIOTT HOTEL BOSTON",
+		CategoryID: hotels},
+	{DomainName: "hilton.com", Descriptor: "HILTON HOTELS",
+		CategoryID: hotels},
+	{DomainName: "hyatt.com", Descriptor: "HYATT REGENCY",
+		CategoryID: hotels},
+	{Descriptor: "SEASIDE INN AND SUITES", CategoryID: hotels},
+}
+
+// heldOutMerchants are labeled merchants the classifier is not trained on,
+// the last two of categories it knows nothing of.
+var heldOutMerchants = []shop.ClassifierExample{
+	{Descriptor: "SQ *MARIOS PIZZA", CategoryID: restaurants},
+	{Descriptor: "THAI GARDEN KITCHEN", CategoryID: restaurants},
+	{Descriptor: "TACO GRILL", CategoryID: restaurants},
+	{DomainName: "wholefoods.com", CategoryID: supermarket},
+	{Descriptor: "SAFEWAY FUEL 5521", CategoryID: supermarket},
+	{Descriptor: "NEIGHBORHOOD MARKET", CategoryID: supermarket},
+	{Descriptor: "UBER *TRIP HELP.UBER.COM", CategoryID: rideshare},
+	{Descriptor: "CHECKER TAXI", CategoryID: rideshare},
+	{DomainName: "jetblue.com", Descriptor: "JETBLUE AIRWAYS",
+		CategoryID: airfare},
+	{Descriptor: "ALASKA AIRLINES SEATTLE", CategoryID: airfare},
+	{Descriptor: "HILTON GARDEN INN", CategoryID: hotels},
+	{Descriptor: "HOLIDAY INN EXPRESS", CategoryID: hotels},
+	{Descriptor: "MARRIOTT COURTYARD", CategoryID: hotels},
+	{Descriptor: "HOME DEPOT 4410", CategoryID: 1085},
+	{DomainName: "bestbuy.com", CategoryID: 1083},
+}
+
+// trainClassifier returns a classifier trained on trainingMerchants.
+func trainClassifier(t *testing.T) *shop.Classifier {
+	t.Helper()
+	classifier, err := shop.TrainClassifier(trainingMerchants)
+	if err != nil {
+		t.Fatalf("TrainClassifier() error = %v", err)
+	}
+	return classifier
+}
+
+func TestTrainClassifier(t *testing.T) {
+	tests := []struct {
+		name         string
+		examples     []shop.ClassifierExample
+		wantExamples int
+		wantClasses  []int
+		wantErr      error
+	}{
+		{"labeled merchants", trainingMerchants, len(trainingMerchants),
+			[]int{supermarket, airfare, restaurants, hotels, rideshare},
+			nil},
+		{"uncategorized and featureless examples left out",
+			[]shop.ClassifierExample{
+				{Descriptor: "TACO CANTINA", CategoryID: restaurants},
+				{Descriptor: "CITY TAXI", CategoryID: rideshare},
+				{Descriptor: "CORNER STORE", CategoryID: -1},
+				{Descriptor: "#1234 *", CategoryID: airfare},
+			}, 2, []int{restaurants, rideshare}, nil},
+		{"one category", []shop.ClassifierExample{
+			{Descriptor: "TACO CANTINA", CategoryID: restaurants},
+			{Descriptor: "BURGER BAR", CategoryID: restaurants},
+		}, 0, nil, shop.ErrNotEnoughExamples},
+		{"no examples", nil, 0, nil, shop.ErrNotEnoughExamples},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			classifier, err := shop.TrainClassifier(test.examples)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("TrainClassifier() error = %v, want %v", err,
+					test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if classifier.Examples != test.wantExamples {
+				t.Errorf("Examples = %d, want %d", classifier.Examples,
+					test.wantExamples)
+			}
+			var classes []int
+			for _, class := range classifier.Classes {
+				classes = append(classes, class.CategoryID)
+			}
+			if !slices.Equal(classes, test.wantClasses) {
+				t.Errorf("classes = %v, want %v", classes, test.wantClasses)
+			}
+		})
+	}
+}
+
+func TestPredict(t *testing.T) {
+	classifier := trainClassifier(t)
+
+	tests := []struct {
+		name       string
+		domainName string
+		descriptor string
+		want       int // 0 for no prediction
+	}{
+		{"trained merchant", "safeway.com", "", supermarket},
+		{"another country", "united.co.uk", "", airfare},
+		{"processor prefix", "", "SQ *BURGER SHACK", restaurants},
+		{"domain and descriptor", "lyft.com", "LYFT *RIDE", rideshare},
+		{"nothing seen", "", "ZZZ QQQ", 0},
+		{"nothing at all", "", "", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			predictions := classifier.Predict(test.domainName,
+				test.descriptor)
+			if test.want == 0 {
+				if predictions != nil {
+					t.Errorf("Predict() = %+v, want nil", predictions)
+				}
+				return
+			}
+			if len(predictions) != len(classifier.Classes) {
+				t.Fatalf("Predict() = %d predictions, want one per class",
+					len(predictions))
+			}
+			if predictions[0].CategoryID != test.want {
+				t.Errorf("Predict() = %d, want %d",
+					predictions[0].CategoryID, test.want)
+			}
+			var sum float64
+			for i, prediction := range predictions {
+				sum += prediction.Confidence
+				if i > 0 && prediction.Confidence >
+					predictions[i-1].Confidence {
+					t.Errorf("prediction %d more confident than %d", i,
+						i-1)
+				}
+			}
+			if math.Abs(sum-1) > 1e-9 {
+				t.Errorf("confidences sum to %v, want 1", sum)
+			}
+		})
+	}
+}
+
+// TestDefaultMinConfidence checks the threshold on the held-out merchants:
+// every wrong prediction must score below it, and most right ones above.
+func TestDefaultMinConfidence(t *testing.T) {
+	classifier := trainClassifier(t)
+
+	var right, accepted int
+	for _, example := range heldOutMerchants {
+		predictions := classifier.Predict(example.DomainName,
+			example.Descriptor)
+		if len(predictions) == 0 {
+			continue
+		}
+		best := predictions[0]
+		if best.CategoryID != example.CategoryID {
+			if best.Confidence >= shop.DefaultMinConfidence {
+				t.Errorf("%s%s: wrong category %d with confidence %.2f",
+					example.DomainName, example.Descriptor,
+					best.CategoryID, best.Confidence)
+			}
+			continue
+		}
+		right++
+		if best.Confidence >= shop.DefaultMinConfidence {
+			accepted++
+		}
+	}
+	if accepted*3 < right*2 {
+		t.Errorf("%d of %d right predictions accepted, want two thirds",
+			accepted, right)
+	}
+}
+
+func TestCategorizeWithClassifier(t *testing.T) {
+	service := shop.NewService(store.NewMemoryRepository(), nil)
+	service.Classifier = trainClassifier(t)
+
+	tests := []struct {
+		descriptor string
+		want       int
+	}{
+		{"THAI GARDEN KITCHEN", restaurants},
+		{"CHECKER TAXI", rideshare},
+		{"HOME DEPOT 4410", -1},
+	}
+	for _, test := range tests {
+		t.Run(test.descriptor, func(t *testing.T) {
+			category, err := service.Categorize(context.Background(), "",
+				store.MerchantDetails{Descriptor: test.descriptor})
+			if err != nil {
+				t.Fatalf("Categorize() error = %v", err)
+			}
+			if category.ID != test.want {
+				t.Errorf("Categorize() = %d, want %d", category.ID,
+					test.want)
+			}
+			if test.want >= 0 && (category.Explanation == nil ||
+				category.Explanation.Source !=
+					shop.CategorySourceClassifier) {
+				t.Errorf("explanation = %v, want the classifier",
+					category.Explanation)
+			}
+		})
+	}
+}
+
+func TestClassifierSaveLoad(t *testing.T) {
+	classifier := trainClassifier(t)
+	name := filepath.Join(t.TempDir(), "classifier.json")
+	if err := classifier.Save(name); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := shop.LoadClassifier(name)
+	if err != nil {
+		t.Fatalf("LoadClassifier() error = %v", err)
+	}
+
+	for _, example := range heldOutMerchants {
+		want := classifier.Predict(example.DomainName, example.Descriptor)
+		got := loaded.Predict(example.DomainName, example.Descriptor)
+		if len(got) != len(want) || (len(got) > 0 && got[0] != want[0]) {
+			t.Errorf("loaded Predict(%q, %q) = %+v, want %+v",
+				example.DomainName, example.Descriptor, got, want)
+		}
+	}
+}
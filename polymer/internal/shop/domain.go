@@ -0,0 +1,136 @@
+package shop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/ayushh-vermaa/polymer/store"
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// ErrInvalidDomain is returned for a merchant domain or URL that does not
+// name a host.
+var ErrInvalidDomain = errors.New("invalid domain name")
+
+// NormalizeDomain returns the host name a merchant domain or URL refers to,
+// in the form domains are stored in: lowercase ASCII, with international
+// names in punycode, and without the scheme, credentials, port, path or
+// trailing dot, nor a leading "www" label. For example, both
+// "https://www.Amazon.com/dp/123" and "amazon.com." normalize to
+// "amazon.com".
+func NormalizeDomain(raw string) (string, error) {
+	rawURL := strings.TrimSpace(raw)
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "//" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidDomain, err)
+	}
+
+	host := strings.TrimSuffix(u.Hostname(), ".")
+	if host == "" {
+		return "", fmt.Errorf("%w: no host in %q", ErrInvalidDomain, raw)
+	}
+	if net.ParseIP(host) != nil {
+		return host, nil
+	}
+	host, err = idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidDomain, err)
+	}
+
+	rest, ok := strings.CutPrefix(host, "www.")
+	if ok && !isPublicSuffix(rest) {
+		host = rest
+	}
+	return host, nil
+}
+
+// RegistrableDomain returns the part of the normalized host name that was
+// registered with a registrar: the public suffix, such as "com" or "co.uk",
+// plus one label. A host with no registrable domain, such as an IP address
+// or a bare public suffix, is returned as it is.
+func RegistrableDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// DomainCandidates returns the normalized host name and each of its parent
+// domains down to its registrable domain, most specific first. For example,
+// "smile.amazon.co.uk" gives "smile.amazon.co.uk" then "amazon.co.uk".
+func DomainCandidates(host string) []string {
+	registrable := RegistrableDomain(host)
+	candidates := []string{host}
+	for host != registrable {
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			break
+		}
+		host = parent
+		candidates = append(candidates, host)
+	}
+	return candidates
+}
+
+// isPublicSuffix reports whether host is a public suffix, under which names
+// are registered rather than a registrable domain itself.
+func isPublicSuffix(host string) bool {
+	suffix, _ := publicsuffix.PublicSuffix(host)
+	return suffix == host
+}
+
+// LookupDomain finds the stored merchant domain for domainName, which may be
+// any host name or URL of the merchant. A domain stored under domainName
+// exactly is found first. Otherwise domainName is normalized and each
+// candidate host, from the most to the least specific, is looked up as a
+// stored domain, then as an alias of one. It fails with ErrInvalidDomain if
+// domainName names no host, and store.ErrNotFound if nothing matches.
+func LookupDomain(ctx context.Context, domains store.DomainRepository,
+	domainName string) (*store.Domain, error) {
+
+	domain, err := domains.GetDomainByName(ctx, domainName)
+	if !errors.Is(err, store.ErrNotFound) {
+		return domain, err
+	}
+
+	host, err := NormalizeDomain(domainName)
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range DomainCandidates(host) {
+		domain, err := findDomain(ctx, domains, candidate)
+		if !errors.Is(err, store.ErrNotFound) {
+			return domain, err
+		}
+	}
+	return nil, fmt.Errorf("%w: no domain found for: %s", store.ErrNotFound,
+		domainName)
+}
+
+// findDomain returns the domain stored under name, or else the domain name
+// is an alias of.
+func findDomain(ctx context.Context, domains store.DomainRepository,
+	name string) (*store.Domain, error) {
+
+	domain, err := domains.GetDomainByName(ctx, name)
+	if !errors.Is(err, store.ErrNotFound) {
+		return domain, err
+	}
+	alias, err := domains.GetDomainAlias(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return domains.GetDomainByName(ctx, alias.Domain)
+}
@@ -0,0 +1,241 @@
+package shop
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// ErrInvalidDomainRecord is returned for a merchant domain to import whose
+// name, category or MCC is invalid.
+var ErrInvalidDomainRecord = errors.New("invalid domain record")
+
+// Formats of the files merchant domains are imported from and exported to.
+const (
+	DomainFormatCSV  = "csv"  // A header row, then a row per domain
+	DomainFormatJSON = "json" // An array of domain objects
+)
+
+// domainColumns are the columns of a domains CSV file, in the order they are
+// exported. Only the name is required to import a file.
+var domainColumns = []string{"name", "category_id", "category_name", "mcc"}
+
+// DomainFormat returns the format of the domains file with the given name,
+// from its extension, or "" if it is neither CSV nor JSON.
+func DomainFormat(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return DomainFormatCSV
+	case ".json":
+		return DomainFormatJSON
+	default:
+		return ""
+	}
+}
+
+// domainRecord is a merchant domain as read from a file, before it is
+// normalized. A missing category ID is nil.
+type domainRecord struct {
+	Name         string `json:"name"`
+	CategoryID   *int   `json:"categoryID"`
+	CategoryName string `json:"categoryName"`
+	MCC          string `json:"mcc"`
+
+	line int // Line of a CSV record
+}
+
+// ParseDomains reads merchant domains to import from r in the given format,
+// and normalizes them as NormalizeDomainRecord does. Every invalid record is
+// reported, by its line in a CSV file or its position in a JSON array, and a
+// name listed twice is an error.
+func ParseDomains(r io.Reader, format string) ([]*store.BaseDomain, error) {
+	var records []domainRecord
+	var err error
+	switch format {
+	case DomainFormatCSV:
+		records, err = readDomainsCSV(r)
+	case DomainFormatJSON:
+		decoder := json.NewDecoder(r)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&records); err != nil {
+			return nil, fmt.Errorf("failed to decode domains: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown domains format: %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var domains []*store.BaseDomain
+	var errs []error
+	seen := make(map[string]bool)
+	for i, record := range records {
+		position := fmt.Sprintf("domain %d", i+1)
+		if format == DomainFormatCSV {
+			position = fmt.Sprintf("line %d", record.line)
+		}
+		domain, err := NormalizeDomainRecord(record.Name, record.CategoryID,
+			record.CategoryName, record.MCC)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", position, err))
+			continue
+		}
+		if seen[domain.Name] {
+			errs = append(errs, fmt.Errorf("%s: %w: %s is listed twice",
+				position, ErrInvalidDomainRecord, domain.Name))
+			continue
+		}
+		seen[domain.Name] = true
+		domains = append(domains, domain)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+// readDomainsCSV reads the records of a domains CSV file, whose header names
+// its columns in any order.
+func readDomainsCSV(r io.Reader) ([]domainRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read domains: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !slices.Contains(domainColumns, column) {
+			return nil, fmt.Errorf("%w: unknown column: %q, want any of %s",
+				ErrInvalidDomainRecord, column,
+				strings.Join(domainColumns, ", "))
+		}
+		columns[column] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("%w: no name column", ErrInvalidDomainRecord)
+	}
+
+	field := func(row []string, column string) string {
+		if i, ok := columns[column]; ok {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var records []domainRecord
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read domains: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		record := domainRecord{
+			Name:         field(row, "name"),
+			CategoryName: field(row, "category_name"),
+			MCC:          field(row, "mcc"),
+			line:         line,
+		}
+		if value := field(row, "category_id"); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w: invalid category_id: %q",
+					line, ErrInvalidDomainRecord, value)
+			}
+			record.CategoryID = &id
+		}
+		records = append(records, record)
+	}
+}
+
+// NormalizeDomainRecord returns the merchant domain to store for a name,
+// category and MCC read from a file or given by hand. The name is normalized
+// as NormalizeDomain does. The category may be given by ID, by name, or both.
+// Any category ID is accepted, as stored domains keep the IDs the Rewards API
+// gives them and must read back as they were written, and its name defaults
+// to the taxonomy's; a category given by name alone must be in the taxonomy.
+// A nil or negative categoryID with no categoryName leaves the domain
+// uncategorized.
+func NormalizeDomainRecord(name string, categoryID *int, categoryName,
+	mcc string) (*store.BaseDomain, error) {
+
+	host, err := NormalizeDomain(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDomainRecord, err)
+	}
+	domain := &store.BaseDomain{Name: host, CategoryID: store.UncategorizedID,
+		MCC: mcc}
+	if mcc != "" && !rewards.ValidMCC(mcc) {
+		return nil, fmt.Errorf("%w: %s: invalid mcc: %q",
+			ErrInvalidDomainRecord, host, mcc)
+	}
+
+	taxonomy := rewards.DefaultTaxonomy()
+	switch {
+	case categoryID != nil && *categoryID >= 0:
+		domain.CategoryID = *categoryID
+		domain.CategoryName = categoryName
+		if category, ok := taxonomy.Category(*categoryID); ok &&
+			categoryName == "" {
+			domain.CategoryName = category.Name
+		}
+	case categoryName != "":
+		category, ok := taxonomy.CategoryByName(categoryName)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s: unknown category: %q",
+				ErrInvalidDomainRecord, host, categoryName)
+		}
+		domain.CategoryID = category.ID
+		domain.CategoryName = category.Name
+	}
+	return domain, nil
+}
+
+// WriteDomains writes domains to w in the given format, in the form
+// ParseDomains reads them back.
+func WriteDomains(w io.Writer, format string, domains []*store.Domain) error {
+	switch format {
+	case DomainFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(domainColumns); err != nil {
+			return err
+		}
+		for _, domain := range domains {
+			err := writer.Write([]string{domain.Name,
+				strconv.Itoa(domain.CategoryID), domain.CategoryName,
+				domain.MCC})
+			if err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case DomainFormatJSON:
+		baseDomains := make([]*store.BaseDomain, len(domains))
+		for i, domain := range domains {
+			baseDomains[i] = domain.BaseDomain
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(baseDomains)
+	default:
+		return fmt.Errorf("unknown domains format: %q", format)
+	}
+}
@@ -0,0 +1,163 @@
+package shop_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+func TestParseDomains(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		data    string
+		want    []store.BaseDomain
+		wantErr string
+	}{
+		{"csv", shop.DomainFormatCSV,
+			"name,category_id,category_name,mcc\n" +
+				"https://www.Amazon.com/,1082,,5942\n" +
+				"chipotle.com,,Restaurants,\n" +
+				"example.com,,,\n" +
+				"api.example.com,77,Rewards API Only,\n",
+			[]store.BaseDomain{
+				{Name: "amazon.com", CategoryID: 1082,
+					CategoryName: "Online Shopping", MCC: "5942"},
+				{Name: "chipotle.com", CategoryID: 1001,
+					CategoryName: "Restaurants"},
+				{Name: "example.com", CategoryID: store.UncategorizedID},
+				{Name: "api.example.com", CategoryID: 77,
+					CategoryName: "Rewards API Only"},
+			}, ""},
+		{"csv columns in any order", shop.DomainFormatCSV,
+			" MCC , Name\n5812, chipotle.com\n",
+			[]store.BaseDomain{{Name: "chipotle.com",
+				CategoryID: store.UncategorizedID, MCC: "5812"}}, ""},
+		{"empty csv", shop.DomainFormatCSV, "", nil, ""},
+		{"json", shop.DomainFormatJSON,
+			`[{"name": "amazon.com", "categoryID": 1082},
+				{"name": "example.com", "categoryID": -1},
+				{"name": "target.com", "categoryName": "department stores"}]`,
+			[]store.BaseDomain{
+				{Name: "amazon.com", CategoryID: 1082,
+					CategoryName: "Online Shopping"},
+				{Name: "example.com", CategoryID: store.UncategorizedID},
+				{Name: "target.com", CategoryID: 1081,
+					CategoryName: "Department Stores"},
+			}, ""},
+		{"no name column", shop.DomainFormatCSV, "category_id\n2\n", nil,
+			"no name column"},
+		{"unknown column", shop.DomainFormatCSV, "name,categry\na.com,2\n",
+			nil, `unknown column: "categry"`},
+		{"invalid category ID", shop.DomainFormatCSV,
+			"name,category_id\na.com,two\n", nil,
+			`line 2: invalid domain record: invalid category_id: "two"`},
+		{"every invalid record", shop.DomainFormatCSV,
+			"name,category_name,mcc\na.com,Nowhere,\nb.com,,58\n" +
+				"https://,,\nc.com,,\nC.com.,,\n", nil,
+			`line 2: invalid domain record: a.com: unknown category: ` +
+				`"Nowhere"` + "\n" +
+				`line 3: invalid domain record: b.com: invalid mcc: "58"` +
+				"\nline 4: invalid domain record: invalid domain name" +
+				`: no host in "https://"` + "\n" +
+				"line 6: invalid domain record: c.com is listed twice"},
+		{"json position", shop.DomainFormatJSON,
+			`[{"name": "a.com"}, {"name": "a.com"}]`, nil,
+			"domain 2: invalid domain record: a.com is listed twice"},
+		{"unknown json field", shop.DomainFormatJSON,
+			`[{"name": "a.com", "category": 2}]`, nil,
+			`unknown field "category"`},
+		{"unknown format", "xml", "", nil, `unknown domains format: "xml"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			domains, err := shop.ParseDomains(strings.NewReader(test.data),
+				test.format)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(),
+					test.wantErr) {
+					t.Fatalf("ParseDomains() error = %v, want %q", err,
+						test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDomains() error = %v", err)
+			}
+			if len(domains) != len(test.want) {
+				t.Fatalf("ParseDomains() = %d domains, want %d",
+					len(domains), len(test.want))
+			}
+			for i, domain := range domains {
+				if *domain != test.want[i] {
+					t.Errorf("domain %d = %+v, want %+v", i, *domain,
+						test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseDomainsRecordErrors(t *testing.T) {
+	_, err := shop.ParseDomains(strings.NewReader("name\nhttps://\n"),
+		shop.DomainFormatCSV)
+	if !errors.Is(err, shop.ErrInvalidDomainRecord) ||
+		!errors.Is(err, shop.ErrInvalidDomain) {
+		t.Errorf("ParseDomains() error = %v, want %v wrapping %v", err,
+			shop.ErrInvalidDomainRecord, shop.ErrInvalidDomain)
+	}
+}
+
+func TestWriteDomainsRoundTrip(t *testing.T) {
+	domains := []*store.Domain{
+		{BaseDomain: &store.BaseDomain{Name: "amazon.com", CategoryID: 1082,
+			CategoryName: "Online Shopping", MCC: "5942"}},
+		{BaseDomain: &store.BaseDomain{Name: "api.example.com",
+			CategoryID: 77, CategoryName: "Rewards API Only"}},
+		{BaseDomain: &store.BaseDomain{Name: "example.com",
+			CategoryID: store.UncategorizedID}},
+		{BaseDomain: &store.BaseDomain{Name: "comma.com", CategoryID: 2,
+			CategoryName: "Dining, Takeout"}},
+	}
+
+	for _, format := range []string{shop.DomainFormatCSV,
+		shop.DomainFormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := shop.WriteDomains(&buf, format, domains); err != nil {
+				t.Fatalf("WriteDomains() error = %v", err)
+			}
+			read, err := shop.ParseDomains(&buf, format)
+			if err != nil {
+				t.Fatalf("ParseDomains() error = %v", err)
+			}
+			if len(read) != len(domains) {
+				t.Fatalf("read back %d domains, want %d", len(read),
+					len(domains))
+			}
+			for i, domain := range read {
+				if *domain != *domains[i].BaseDomain {
+					t.Errorf("domain %d read back as %+v, want %+v", i,
+						*domain, *domains[i].BaseDomain)
+				}
+			}
+		})
+	}
+}
+
+func TestDomainFormat(t *testing.T) {
+	for name, want := range map[string]string{
+		"domains.csv":  shop.DomainFormatCSV,
+		"DOMAINS.JSON": shop.DomainFormatJSON,
+		"domains.txt":  "",
+		"":             "",
+	} {
+		if got := shop.DomainFormat(name); got != want {
+			t.Errorf("DomainFormat(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
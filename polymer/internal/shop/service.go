@@ -0,0 +1,27 @@
+package shop
+
+import (
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// Service is what the wallet operations share: the repository, the card
+// cache in front of its cards and the classifier categorization falls back
+// to. It is created once by the command or server using it, and its fields
+// must not change once it is in use.
+type Service struct {
+	Repo  *store.Repository
+	Cards *CardCache
+
+	// Classifier predicts the category of merchants nothing else
+	// categorizes, nil for none. Its predictions are only used if at least
+	// MinConfidence confident.
+	Classifier    *Classifier
+	MinConfidence float64
+}
+
+// NewService returns a Service using repo, whose cards are read through
+// cards, with no classifier.
+func NewService(repo *store.Repository, cards *CardCache) *Service {
+	return &Service{Repo: repo, Cards: cards,
+		MinConfidence: DefaultMinConfidence}
+}
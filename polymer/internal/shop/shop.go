@@ -1,52 +1,177 @@
 package shop
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
 	"github.com/ayushh-vermaa/polymer/store"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
+const (
+	HomeCountry  = "US"  // Country the wallet's cards are issued in
+	HomeCurrency = "USD" // Currency the wallet's cards are billed in
+)
+
+// Purchase describes a purchase to select a card for.
+type Purchase struct {
+	CategoryID int       // Merchant category
+	MCC        string    // Merchant category code (ISO 18245), if known
+	Amount     float64   // Spend amount in USD
+	At         time.Time // When the purchase is made
+	Country    string    // Merchant country (ISO 3166-1 alpha-2), if known
+	Currency   string    // Charge currency (ISO 4217), if known
+}
+
+// IsForeign reports whether the purchase is made with a merchant abroad or
+// charged in a foreign currency, and so incurs foreign transaction fees.
+func (purchase *Purchase) IsForeign() bool {
+	country := strings.ToUpper(strings.TrimSpace(purchase.Country))
+	currency := strings.ToUpper(strings.TrimSpace(purchase.Currency))
+	return (country != "" && country != HomeCountry) ||
+		(currency != "" && currency != HomeCurrency)
+}
+
 type DomainCategory struct {
 	ID   int    `json:"categoryID"`
 	Name string `json:"categoryName"`
+	MCC  string `json:"mcc,omitempty"` // Merchant category code, if known
+
+	// How the category was decided, nil if it is unknown
+	Explanation *CategoryExplanation `json:"explanation,omitempty"`
 }
 
-// GetDomainCategoryID gets the categoryID for a given domainName from MongoDB.
-func GetDomainCategory(client *mongo.Client,
-	domainName string) *DomainCategory {
+// GetDomainCategory gets the category for a given domainName from the domain
+// repository, finding the merchant's domain as LookupDomain does. A domain
+// stored with an MCC but no category gets the category covering the MCC. An
+// unknown or invalid domain gets the category the service's classifier
+// predicts for it, if confident enough, and store.UncategorizedID otherwise.
+func (service *Service) GetDomainCategory(ctx context.Context,
+	domainName string) (*DomainCategory, error) {
 
-	domain, _ := store.GetDomainByName(client, domainName)
-	if domain == nil {
-		return &DomainCategory{ID: -1, Name: ""}
+	category, err := lookupDomainCategory(ctx, service.Repo.Domains,
+		domainName)
+	if err != nil {
+		return nil, err
+	}
+	if category.ID < 0 {
+		if predicted, ok := service.classify(domainName, ""); ok {
+			predicted.MCC = category.MCC
+			return predicted, nil
+		}
+	}
+	return category, nil
+}
+
+// lookupDomainCategory gets the category for domainName as GetDomainCategory
+// does, without falling back to the classifier.
+func lookupDomainCategory(ctx context.Context,
+	domains store.DomainRepository, domainName string) (*DomainCategory,
+	error) {
+
+	domain, err := LookupDomain(ctx, domains, domainName)
+	if errors.Is(err, store.ErrNotFound) ||
+		errors.Is(err, ErrInvalidDomain) {
+		return &DomainCategory{ID: store.UncategorizedID}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get domain category: %w", err)
 	}
 
-	return &DomainCategory{
+	category := &DomainCategory{
 		ID:   domain.CategoryID,
 		Name: domain.CategoryName,
+		MCC:  domain.MCC,
+		Explanation: &CategoryExplanation{
+			Source: CategorySourceDomain,
+			Domain: domain.Name,
+		},
+	}
+	if category.ID < 0 {
+		category.Explanation = nil
+		category.fromMCC()
+	}
+	return category, nil
+}
+
+// fromMCC sets the category to the one covering its MCC, if the MCC is in the
+// bundled table.
+func (category *DomainCategory) fromMCC() {
+	mcc, ok := rewards.LookupMCC(category.MCC)
+	if !ok {
+		return
+	}
+	category.ID = mcc.CategoryID()
+	category.Name = mcc.CategoryGroup
+	if known, ok := rewards.DefaultTaxonomy().Category(category.ID); ok {
+		category.Name = known.Name
+	}
+	if category.ID >= 0 {
+		category.Explanation = &CategoryExplanation{Source: CategorySourceMCC}
 	}
 }
 
-func Transact(client *mongo.Client, domainName string, amount float64,
-	wallet *BaseWallet) *store.CardDetails {
+// Recommend selects the best card in the wallet for a purchase of amount at
+// merchant, made at the given time, without recording it. The merchant's
+// category is decided by Categorize for the wallet, and its MCC looked up
+// from its domain name unless merchant has one. It returns the transaction
+// the purchase would be recorded as.
+func (service *Service) Recommend(ctx context.Context,
+	merchant store.MerchantDetails, amount float64, at time.Time,
+	wallet *BaseWallet) (*store.BaseTransaction, error) {
 
-	category := GetDomainCategory(client, domainName)
-	cardDetails := wallet.SelectBest(category.ID)
+	category, err := service.Categorize(ctx, wallet.ID, merchant)
+	if err != nil {
+		return nil, err
+	}
+	merchant.CategoryID = category.ID
+	merchant.CategoryName = category.Name
+	merchant.MCC = category.MCC
+	merchant.CategorySource = category.Explanation.source()
+
+	purchase := Purchase{
+		CategoryID: category.ID,
+		MCC:        category.MCC,
+		Amount:     amount,
+		At:         at,
+		Country:    merchant.Country,
+		Currency:   merchant.Currency,
+	}
+	cardDetails, err := wallet.SelectBest(ctx, &purchase)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store.BaseTransaction{
+		WalletID:        wallet.ID,
+		TransactionAt:   purchase.At,
+		SpendAmount:     amount,
+		MerchantDetails: merchant,
+		CardDetails:     *cardDetails,
+	}, nil
+}
+
+// Transact selects the best card in the wallet for a purchase of amount at
+// merchant and records the resulting transaction in the repository.
+func (service *Service) Transact(ctx context.Context,
+	merchant store.MerchantDetails, amount float64,
+	wallet *BaseWallet) (*store.CardDetails, error) {
+
+	transaction, err := service.Recommend(ctx, merchant, amount, time.Now(),
+		wallet)
+	if err != nil {
+		return nil, err
+	}
+	cardDetails := &transaction.CardDetails
 	log.Printf("Transacting $%.2f with card %q for %.2f%% value back",
 		amount, cardDetails.CardName, cardDetails.RewardDetails.Value*100)
 
-	transaction := store.BaseTransaction{
-		TransactionAt: time.Now(),
-		SpendAmount:   amount,
-		MerchantDetails: store.MerchantDetails{
-			DomainName:   domainName,
-			CategoryID:   category.ID,
-			CategoryName: category.Name,
-		},
-		CardDetails: *cardDetails,
+	_, err = service.Repo.Transactions.InsertTransaction(ctx, transaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store transaction: %w", err)
 	}
-
-	store.InsertTransaction(client, &transaction)
-	return cardDetails
+	return cardDetails, nil
 }
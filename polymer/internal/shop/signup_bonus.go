@@ -0,0 +1,192 @@
+package shop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/store"
+	"github.com/shopspring/decimal"
+)
+
+// SignupBonusProgress tracks a card's progress toward the minimum spend of its
+// sign-up bonus.
+type SignupBonusProgress struct {
+	CardKey       string    `json:"cardKey"`
+	OpenedOn      time.Time `json:"openedOn"`
+	Deadline      time.Time `json:"deadline"`      // Minimum spend must be met before
+	RequiredSpend float64   `json:"requiredSpend"` // Minimum spend in USD
+	Spent         float64   `json:"spent"`         // Spend counted so far in USD
+	BonusValue    float64   `json:"bonusValue"`    // Bonus worth in USD
+	Met           bool      `json:"met"`           // Minimum spend reached
+	Expired       bool      `json:"expired"`       // Deadline passed unmet
+	AtRisk        bool      `json:"atRisk"`        // Current pace misses the deadline
+}
+
+// Remaining returns how much is left to spend to earn the bonus.
+func (progress *SignupBonusProgress) Remaining() float64 {
+	return math.Max(0, progress.RequiredSpend-progress.Spent)
+}
+
+// AmortizedValue returns the per-dollar value a purchase of amount earns
+// toward the bonus: the bonus spread evenly over its minimum spend, for the
+// part of the purchase that still counts toward it.
+func (progress *SignupBonusProgress) AmortizedValue(amount float64) float64 {
+	remaining := progress.Remaining()
+	if progress.Met || progress.Expired || remaining == 0 ||
+		progress.RequiredSpend <= 0 {
+		return 0
+	}
+
+	share := 1.0
+	if amount > remaining {
+		share = remaining / amount
+	}
+
+	perDollar := decimal.NewFromFloat(progress.BonusValue).
+		Div(decimal.NewFromFloat(progress.RequiredSpend))
+	return perDollar.Mul(decimal.NewFromFloat(share)).InexactFloat64()
+}
+
+// HasSignupBonus reports whether card offers a sign-up bonus.
+func HasSignupBonus(card *rewards.CardDetail) bool {
+	return card.IsSignupBonus == 1
+}
+
+// SignupBonusDeadline returns when the sign-up bonus window of a card opened
+// on openedOn closes. Lengths are counted in the card's
+// SignupBonusLengthPeriod of days, months or years, defaulting to months.
+func SignupBonusDeadline(card *rewards.CardDetail,
+	openedOn time.Time) time.Time {
+
+	length := int(math.Round(card.SignupBonusLength))
+	switch period := strings.ToLower(card.SignupBonusLengthPeriod); {
+	case strings.Contains(period, "day"):
+		return openedOn.AddDate(0, 0, length)
+	case strings.Contains(period, "year"):
+		return openedOn.AddDate(length, 0, 0)
+	default:
+		return openedOn.AddDate(0, length, 0)
+	}
+}
+
+// SignupBonusValue returns what the card's sign-up bonus, including any
+// statement credit, is worth in dollars, pricing points with valuation.
+func SignupBonusValue(card *rewards.CardDetail,
+	valuation Valuation) (float64, error) {
+
+	amount, isCash, err := card.ParseSignupBonusAmount()
+	if err != nil {
+		return 0, err
+	}
+
+	value := decimal.NewFromFloat(amount)
+	if !isCash {
+		cents := decimal.NewFromFloat(valuation.CentsPerPoint(card))
+		value = value.Mul(cents).Div(decimal.NewFromInt(100))
+	}
+	value = value.Add(decimal.NewFromFloat(card.SignupStatementCredit))
+
+	return value.InexactFloat64(), nil
+}
+
+// TrackSignupBonus measures the progress as of at toward the sign-up bonus of
+// card, opened on openedOn, counting its transactions in history made between
+// opening and the deadline. A bonus is at risk when the average daily spend
+// since opening, kept up until the deadline, falls short of the minimum.
+func TrackSignupBonus(card *rewards.CardDetail, openedOn, at time.Time,
+	history SpendHistory, valuation Valuation) (*SignupBonusProgress, error) {
+
+	bonusValue, err := SignupBonusValue(card, valuation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to value sign-up bonus of %s: %w",
+			card.CardKey, err)
+	}
+
+	progress := SignupBonusProgress{
+		CardKey:       card.CardKey,
+		OpenedOn:      openedOn,
+		Deadline:      SignupBonusDeadline(card, openedOn),
+		RequiredSpend: card.SignupBonusSpend,
+		BonusValue:    bonusValue,
+	}
+
+	for _, transaction := range history {
+		if transaction.CardDetails.CardKey != card.CardKey ||
+			transaction.TransactionAt.Before(openedOn) ||
+			transaction.TransactionAt.After(at) ||
+			!transaction.TransactionAt.Before(progress.Deadline) {
+			continue
+		}
+		progress.Spent += transaction.SpendAmount
+	}
+
+	progress.Met = progress.Spent >= progress.RequiredSpend
+	progress.Expired = !progress.Met && !at.Before(progress.Deadline)
+	if !progress.Met && !progress.Expired {
+		elapsed := math.Max(1, at.Sub(openedOn).Hours()/24)
+		left := progress.Deadline.Sub(at).Hours() / 24
+		projected := progress.Spent + progress.Spent/elapsed*left
+		progress.AtRisk = projected < progress.RequiredSpend
+	}
+
+	return &progress, nil
+}
+
+// SignupBonusProgress tracks, as of at, the sign-up bonus of every card in the
+// wallet that offers one and has an opening date in OpenedOn. The result is
+// keyed by card key. A card whose bonus amount cannot be read is left out, as
+// if it offered no bonus.
+func (wallet *BaseWallet) SignupBonusProgress(ctx context.Context,
+	at time.Time) (map[string]*SignupBonusProgress, error) {
+
+	var cards []*rewards.CardDetail
+	var cardKeys []string
+	var earliest time.Time
+	for _, card := range wallet.Cards {
+		openedOn, ok := wallet.OpenedOn[card.CardKey]
+		if !ok || !HasSignupBonus(card) {
+			continue
+		}
+		cards = append(cards, card)
+		cardKeys = append(cardKeys, card.CardKey)
+		if earliest.IsZero() || openedOn.Before(earliest) {
+			earliest = openedOn
+		}
+	}
+
+	progressMap := make(map[string]*SignupBonusProgress)
+	if len(cards) == 0 {
+		return progressMap, nil
+	}
+
+	var history SpendHistory
+	if wallet.History != nil {
+		transactions, err := wallet.History.ListTransactions(ctx,
+			&store.TransactionFilter{WalletID: wallet.ID, CardKeys: cardKeys,
+				From: earliest})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load spend history: %w", err)
+		}
+		history = SpendHistory(transactions)
+	}
+
+	for _, card := range cards {
+		progress, err := TrackSignupBonus(card, wallet.OpenedOn[card.CardKey],
+			at, history, wallet.valuation())
+		if errors.Is(err, rewards.ErrInvalidSignupBonus) {
+			log.Printf("Ignoring sign-up bonus of %s: %v", card.CardKey, err)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		progressMap[card.CardKey] = progress
+	}
+
+	return progressMap, nil
+}
@@ -0,0 +1,87 @@
+package shop
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// ResetPeriod returns the bounds [start, end) of the spend limit reset period
+// named by period that contains at. Months, quarters and years follow the
+// calendar in at's location. An empty or unrecognized period is treated as a
+// year, the most common reset period for capped bonuses.
+func ResetPeriod(period string, at time.Time) (time.Time, time.Time) {
+	year, month, _ := at.Date()
+	loc := at.Location()
+
+	switch period = strings.ToLower(strings.TrimSpace(period)); {
+	case strings.Contains(period, "month"):
+		start := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 1, 0)
+	case strings.Contains(period, "quarter"):
+		firstMonth := month - (month-1)%3
+		start := time.Date(year, firstMonth, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 3, 0)
+	default:
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(1, 0, 0)
+	}
+}
+
+// IsCapped reports whether bonus only applies up to a spend limit.
+func IsCapped(bonus *rewards.SpendBonusCategory) bool {
+	return bonus.IsSpendLimit == 1 && bonus.SpendLimit > 0
+}
+
+// SpendHistory is the list of stored transactions that spend limits are
+// measured against.
+type SpendHistory []*store.Transaction
+
+// CappedSpend returns how much was spent with cardKey at merchants bonus
+// applies to, during the reset period of bonus that contains at. Only
+// transactions made up to at, and within the bonus's date limit if it has one,
+// are counted.
+func (history SpendHistory) CappedSpend(cardKey string,
+	bonus *rewards.SpendBonusCategory, at time.Time) float64 {
+
+	start, _ := ResetPeriod(bonus.SpendLimitResetPeriod, at)
+	if bonus.IsDateLimit == 1 {
+		begin, _, err := bonus.DateLimit(at.Location())
+		if err == nil && begin.After(start) {
+			start = begin
+		}
+	}
+
+	spent := 0.0
+	for _, transaction := range history {
+		if transaction.CardDetails.CardKey != cardKey ||
+			transaction.TransactionAt.Before(start) ||
+			transaction.TransactionAt.After(at) {
+			continue
+		}
+		merchant := &transaction.MerchantDetails
+		if bonus.AppliesTo(merchant.CategoryID, merchant.MCC) {
+			spent += transaction.SpendAmount
+		}
+	}
+
+	return spent
+}
+
+// RemainingCap returns how much of the spend limit of bonus is left on cardKey
+// for a purchase made at, or +Inf if the bonus is not capped.
+func (history SpendHistory) RemainingCap(cardKey string,
+	bonus *rewards.SpendBonusCategory, at time.Time) float64 {
+
+	if !IsCapped(bonus) {
+		return math.Inf(1)
+	}
+	remaining := bonus.SpendLimit - history.CappedSpend(cardKey, bonus, at)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
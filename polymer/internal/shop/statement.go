@@ -0,0 +1,783 @@
+package shop
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/store"
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidStatement is returned for a statement whose rows cannot be read
+// with its column mapping profile, or for an invalid profile.
+var ErrInvalidStatement = errors.New("invalid statement")
+
+// DefaultDateLayout is the layout of statement dates unless a profile sets
+// its own.
+const DefaultDateLayout = "01/02/2006"
+
+// StatementProfile maps the columns of an issuer's CSV statement export to
+// the fields of a charge. Columns are named as in the file's header, which
+// is matched regardless of case. A profile has either a signed Amount column
+// or separate Debit and Credit columns.
+type StatementProfile struct {
+	Name        string `json:"name"`
+	Date        string `json:"date"`                 // Transaction date column
+	DateLayout  string `json:"dateLayout,omitempty"` // Go time layout, DefaultDateLayout if empty
+	Description string `json:"description"`          // Statement descriptor column
+	Amount      string `json:"amount,omitempty"`     // Signed amount column
+	Debit       string `json:"debit,omitempty"`      // Charges column, instead of Amount
+	Credit      string `json:"credit,omitempty"`     // Payments and refunds column
+	Card        string `json:"card,omitempty"`       // Column telling the card used, if any
+	SkipLines   int    `json:"skipLines,omitempty"`  // Lines before the header
+
+	// ChargesNegative is set if the Amount column has charges as negative
+	// amounts and payments and refunds as positive ones
+	ChargesNegative bool `json:"chargesNegative,omitempty"`
+
+	// Cards maps values of the Card column, such as the last digits of the
+	// card number, to the card keys of the wallet's cards
+	Cards map[string]string `json:"cards,omitempty"`
+}
+
+// Validate checks that the profile names the columns a charge needs and
+// puts its name in the form profiles are looked up by.
+func (profile *StatementProfile) Validate() error {
+	profile.Name = strings.ToLower(strings.TrimSpace(profile.Name))
+	if profile.Name == "" {
+		return fmt.Errorf("%w: profile name is required", ErrInvalidStatement)
+	}
+	if profile.Date == "" || profile.Description == "" {
+		return fmt.Errorf("%w: profile %s: date and description columns "+
+			"are required", ErrInvalidStatement, profile.Name)
+	}
+	if (profile.Amount == "") == (profile.Debit == "") {
+		return fmt.Errorf("%w: profile %s: exactly one of amount and debit "+
+			"columns is required", ErrInvalidStatement, profile.Name)
+	}
+	if profile.Credit != "" && profile.Debit == "" {
+		return fmt.Errorf("%w: profile %s: a credit column needs a debit "+
+			"column", ErrInvalidStatement, profile.Name)
+	}
+	if profile.ChargesNegative && profile.Amount == "" {
+		return fmt.Errorf("%w: profile %s: chargesNegative needs an amount "+
+			"column", ErrInvalidStatement, profile.Name)
+	}
+	if profile.SkipLines < 0 {
+		return fmt.Errorf("%w: profile %s: negative skipLines",
+			ErrInvalidStatement, profile.Name)
+	}
+	return nil
+}
+
+// DefaultStatementProfiles returns the built-in profiles of the CSV exports
+// of common issuers, by name.
+func DefaultStatementProfiles() map[string]*StatementProfile {
+	profiles := []*StatementProfile{
+		{Name: "amex", Date: "Date", Description: "Description",
+			Amount: "Amount"},
+		{Name: "capitalone", Date: "Transaction Date",
+			DateLayout: time.DateOnly, Description: "Description",
+			Debit: "Debit", Credit: "Credit", Card: "Card No."},
+		{Name: "chase", Date: "Transaction Date", Description: "Description",
+			Amount: "Amount", ChargesNegative: true},
+		{Name: "citi", Date: "Date", Description: "Description",
+			Debit: "Debit", Credit: "Credit"},
+		{Name: "discover", Date: "Trans. Date", Description: "Description",
+			Amount: "Amount"},
+	}
+	byName := make(map[string]*StatementProfile, len(profiles))
+	for _, profile := range profiles {
+		byName[profile.Name] = profile
+	}
+	return byName
+}
+
+// LoadStatementProfiles returns the built-in profiles together with those
+// of the JSON array of profiles in the file with the given name, which
+// replace the built-in profiles of the same name. An empty name returns the
+// built-in profiles only.
+func LoadStatementProfiles(name string) (map[string]*StatementProfile,
+	error) {
+
+	profiles := DefaultStatementProfiles()
+	if name == "" {
+		return profiles, nil
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	var loaded []*StatementProfile
+	if err := decoder.Decode(&loaded); err != nil {
+		return nil, fmt.Errorf("failed to decode statement profiles: %w", err)
+	}
+	var errs []error
+	for i, profile := range loaded {
+		if profile == nil {
+			errs = append(errs, fmt.Errorf("%w: profile %d is null",
+				ErrInvalidStatement, i+1))
+			continue
+		}
+		if err := profile.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("profile %d: %w", i+1, err))
+			continue
+		}
+		profiles[profile.Name] = profile
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// StatementRow is a row of a statement, as read with its profile.
+type StatementRow struct {
+	Line       int       `json:"line"`
+	Date       time.Time `json:"date"` // Local midnight of the transaction date
+	Descriptor string    `json:"descriptor"`
+	Amount     float64   `json:"amount"` // USD charged, negative for credits
+
+	// Card column value, or the card key the profile maps it to
+	Card string `json:"card,omitempty"`
+}
+
+// ParseStatement reads the rows of a CSV statement from r, with the columns
+// mapped by profile. Every row that cannot be read is reported by its line.
+func ParseStatement(r io.Reader, profile *StatementProfile) ([]StatementRow,
+	error) {
+
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewReader(r)
+	// Spreadsheet exports often start with a byte order mark.
+	if bom, err := buffered.Peek(3); err == nil &&
+		string(bom) == "\ufeff" {
+		buffered.Discard(3)
+	}
+	for i := 0; i < profile.SkipLines; i++ {
+		if _, err := buffered.ReadString('\n'); errors.Is(err, io.EOF) {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read statement: %w", err)
+		}
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read statement: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if _, ok := columns[column]; !ok {
+			columns[column] = i
+		}
+	}
+	for _, column := range []string{profile.Date, profile.Description,
+		profile.Amount, profile.Debit, profile.Credit, profile.Card} {
+		if _, ok := columns[strings.ToLower(column)]; column != "" && !ok {
+			return nil, fmt.Errorf("%w: no %q column for profile %s",
+				ErrInvalidStatement, column, profile.Name)
+		}
+	}
+	field := func(record []string, column string) string {
+		i, ok := columns[strings.ToLower(column)]
+		if column == "" || !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	layout := profile.DateLayout
+	if layout == "" {
+		layout = DefaultDateLayout
+	}
+	var rows []StatementRow
+	var errs []error
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read statement: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		line += profile.SkipLines
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		row, err := parseStatementRow(profile, layout,
+			func(column string) string { return field(record, column) })
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		row.Line = line
+		rows = append(rows, *row)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// parseStatementRow reads a statement row whose columns field returns.
+func parseStatementRow(profile *StatementProfile, layout string,
+	field func(column string) string) (*StatementRow, error) {
+
+	value := field(profile.Date)
+	date, err := time.ParseInLocation(layout, value, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid date: %q", ErrInvalidStatement,
+			value)
+	}
+	descriptor := strings.Fields(field(profile.Description))
+	row := &StatementRow{
+		Date:       date,
+		Descriptor: strings.Join(descriptor, " "),
+		Card:       field(profile.Card),
+	}
+	if row.Descriptor == "" {
+		return nil, fmt.Errorf("%w: no description", ErrInvalidStatement)
+	}
+	if cardKey, ok := profile.Cards[row.Card]; ok {
+		row.Card = cardKey
+	}
+
+	if profile.Amount != "" {
+		value = field(profile.Amount)
+		row.Amount, err = parseStatementAmount(value)
+		if err != nil || value == "" {
+			return nil, fmt.Errorf("%w: invalid amount: %q",
+				ErrInvalidStatement, value)
+		}
+		if profile.ChargesNegative {
+			row.Amount = -row.Amount
+		}
+		return row, nil
+	}
+
+	debit, credit := field(profile.Debit), field(profile.Credit)
+	if debit == "" && credit == "" {
+		return nil, fmt.Errorf("%w: no debit or credit", ErrInvalidStatement)
+	}
+	if row.Amount, err = parseStatementAmount(debit); err != nil {
+		return nil, fmt.Errorf("%w: invalid debit: %q", ErrInvalidStatement,
+			debit)
+	}
+	// Credits are positive in some exports and negative in others.
+	refund, err := parseStatementAmount(credit)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid credit: %q", ErrInvalidStatement,
+			credit)
+	}
+	if refund != 0 {
+		row.Amount = -math.Abs(refund)
+	}
+	return row, nil
+}
+
+// parseStatementAmount parses an amount such as "1,234.56", "-$12.00" or
+// "(12.00)", the last being negative. An empty amount is zero.
+func parseStatementAmount(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = value[1 : len(value)-1]
+	}
+	value = strings.NewReplacer("$", "", ",", "", " ", "").Replace(value)
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// StatementOptions configures ImportStatement.
+type StatementOptions struct {
+	// Cards maps Card values of the rows to card keys, in addition to the
+	// mapping of the statement's profile
+	Cards map[string]string
+
+	// Card is the card key of the rows with an empty Card value. It may be
+	// left empty for a wallet of a single card.
+	Card string
+
+	// DryRun evaluates the rows without storing any transaction
+	DryRun bool
+}
+
+// StatementReport describes the outcome of a statement import.
+type StatementReport struct {
+	Rows          int     `json:"rows"`          // Rows of the statement
+	Imported      int     `json:"imported"`      // Charges stored, or that would be
+	Duplicates    int     `json:"duplicates"`    // Charges already stored
+	Credits       int     `json:"credits"`       // Payments and refunds, skipped
+	Uncategorized int     `json:"uncategorized"` // Imported charges of no known category
+	Spend         float64 `json:"spend"`         // USD of imported charges
+	Earned        float64 `json:"earned"`        // USD of rewards from the cards used
+	BestEarned    float64 `json:"bestEarned"`    // USD the recommended cards would earn
+	DryRun        bool    `json:"dryRun"`
+
+	// Transactions are the imported charges in date order, each with the
+	// card the wallet would have recommended for it
+	Transactions []*store.BaseTransaction `json:"transactions"`
+}
+
+// Missed returns the USD of rewards the imported charges would have earned
+// on top of Earned had each been made with the recommended card.
+func (report *StatementReport) Missed() float64 {
+	return decimal.NewFromFloat(report.BestEarned).
+		Sub(decimal.NewFromFloat(report.Earned)).InexactFloat64()
+}
+
+// ImportStatement records the charges of a statement's rows as transactions
+// of wallet, which must be stored. Each row is charged to the card its Card
+// value names, through options.Cards or as a card key of the wallet, or to
+// options.Card if it has none, and every row must name a card of the wallet
+// before any is stored. Payments and refunds are skipped, and so are charges
+// already stored for the wallet on the same day, for the same amount and
+// card and with the same descriptor, so that overlapping statements can be
+// imported.
+//
+// Each charge's merchant is found from its descriptor, as a stored merchant
+// domain named by it where there is one, and categorized with Categorize.
+// The transaction records the reward the card used earned and, as its
+// RecommendedCard, the card SelectBest picks for the charge, if any card
+// earns a reward on it. Charges are evaluated in date order under the card
+// terms in effect on their date, as CardAsOf gives them, or the current
+// terms of a card with no stored terms, so the spend limits of capped
+// bonuses fill up as they would have. A dry run stores nothing but counts
+// the charges it evaluates towards the spend limits of later ones. An
+// import that fails part way can be resumed by importing the statement
+// again.
+func (service *Service) ImportStatement(ctx context.Context,
+	wallet *BaseWallet, rows []StatementRow,
+	options StatementOptions) (*StatementReport, error) {
+
+	repo := service.Repo
+	report := &StatementReport{
+		Rows:         len(rows),
+		DryRun:       options.DryRun,
+		Transactions: []*store.BaseTransaction{},
+	}
+	cards := make(map[string]*rewards.CardDetail, len(wallet.Cards))
+	for _, card := range wallet.Cards {
+		cards[card.CardKey] = card
+	}
+
+	var charges []StatementRow
+	var errs []error
+	for _, row := range rows {
+		if row.Amount <= 0 {
+			report.Credits++
+			continue
+		}
+		cardKey, err := statementCard(row, cards, options)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", row.Line, err))
+			continue
+		}
+		row.Card = cardKey
+		charges = append(charges, row)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if len(charges) == 0 {
+		return report, nil
+	}
+	sort.SliceStable(charges, func(i, j int) bool {
+		return charges[i].Date.Before(charges[j].Date)
+	})
+
+	stored, err := repo.Transactions.ListTransactions(ctx,
+		&store.TransactionFilter{
+			WalletID: wallet.ID,
+			From:     charges[0].Date,
+			To:       charges[len(charges)-1].Date.AddDate(0, 0, 1),
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	// Identical charges on the same day are told apart by counting them.
+	existing := make(map[string]int)
+	for _, transaction := range stored {
+		existing[chargeKey(transaction.TransactionAt,
+			transaction.SpendAmount, transaction.MerchantDetails.Descriptor,
+			transaction.CardDetails.CardKey)]++
+	}
+
+	// A dry run keeps the charges it evaluates in memory, so that they
+	// count towards the spend limits of the charges after them.
+	transactions, history := repo.Transactions, wallet.History
+	if options.DryRun {
+		pending := &pendingTransactions{stored: wallet.History}
+		transactions, history = pending, pending
+	}
+
+	earned, bestEarned := decimal.Zero, decimal.Zero
+	spend := decimal.Zero
+	merchants := make(map[string]*store.MerchantDetails)
+	terms := make(map[string]*rewards.CardDetail)
+	for _, row := range charges {
+		key := chargeKey(row.Date, row.Amount, row.Descriptor, row.Card)
+		if existing[key] > 0 {
+			existing[key]--
+			report.Duplicates++
+			continue
+		}
+
+		descriptor := normalizeDescriptor(row.Descriptor)
+		merchant, ok := merchants[descriptor]
+		if !ok {
+			merchant, err = service.statementMerchant(ctx, wallet.ID,
+				row.Descriptor)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", row.Line, err)
+			}
+			merchants[descriptor] = merchant
+		}
+
+		dated, err := service.walletAsOf(ctx, wallet, row.Date, terms)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", row.Line, err)
+		}
+		dated.History = history
+		transaction, err := evaluateCharge(ctx, dated, row.Card, *merchant,
+			row)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", row.Line, err)
+		}
+		_, err = transactions.InsertTransaction(ctx, transaction)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: failed to store "+
+				"transaction: %w", row.Line, err)
+		}
+
+		amount := decimal.NewFromFloat(row.Amount)
+		spend = spend.Add(amount)
+		earned = earned.Add(amount.Mul(decimal.NewFromFloat(
+			transaction.CardDetails.RewardDetails.Value)))
+		best := &transaction.CardDetails
+		if transaction.RecommendedCard != nil {
+			best = transaction.RecommendedCard
+		}
+		bestEarned = bestEarned.Add(amount.Mul(decimal.NewFromFloat(
+			best.RewardDetails.Value)))
+		if merchant.CategoryID < 0 {
+			report.Uncategorized++
+		}
+		report.Imported++
+		report.Transactions = append(report.Transactions, transaction)
+	}
+	report.Spend = spend.InexactFloat64()
+	report.Earned = earned.Round(2).InexactFloat64()
+	report.BestEarned = bestEarned.Round(2).InexactFloat64()
+	return report, nil
+}
+
+// statementCard returns the key of the wallet card a statement row was
+// charged to.
+func statementCard(row StatementRow, cards map[string]*rewards.CardDetail,
+	options StatementOptions) (string, error) {
+
+	if row.Card == "" {
+		if options.Card != "" {
+			if _, ok := cards[options.Card]; !ok {
+				return "", fmt.Errorf("%w: card %q is not a card of the "+
+					"wallet", ErrInvalidStatement, options.Card)
+			}
+			return options.Card, nil
+		}
+		if len(cards) == 1 {
+			for cardKey := range cards {
+				return cardKey, nil
+			}
+		}
+		return "", fmt.Errorf("%w: no card given for the charge",
+			ErrInvalidStatement)
+	}
+
+	cardKey := row.Card
+	if mapped, ok := options.Cards[row.Card]; ok {
+		cardKey = mapped
+	}
+	if _, ok := cards[cardKey]; !ok {
+		return "", fmt.Errorf("%w: card %q is not a card of the wallet",
+			ErrInvalidStatement, row.Card)
+	}
+	return cardKey, nil
+}
+
+// chargeKey identifies a charge for telling whether it is already stored.
+func chargeKey(at time.Time, amount float64, descriptor,
+	cardKey string) string {
+
+	return fmt.Sprintf("%s|%d|%s|%s", at.Local().Format(time.DateOnly),
+		decimal.NewFromFloat(amount).Shift(2).Round(0).IntPart(),
+		normalizeDescriptor(descriptor), cardKey)
+}
+
+// statementMerchant returns the merchant a statement descriptor names, with
+// the domain found for it, if any, and the category Categorize gives it for
+// the wallet with walletID.
+func (service *Service) statementMerchant(ctx context.Context, walletID,
+	descriptor string) (*store.MerchantDetails, error) {
+
+	merchant := &store.MerchantDetails{Descriptor: descriptor}
+	for _, candidate := range descriptorDomainCandidates(descriptor) {
+		domain, err := LookupDomain(ctx, service.Repo.Domains, candidate)
+		if errors.Is(err, store.ErrNotFound) ||
+			errors.Is(err, ErrInvalidDomain) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to find merchant domain: %w", err)
+		}
+		merchant.DomainName = domain.Name
+		break
+	}
+
+	category, err := service.Categorize(ctx, walletID, *merchant)
+	if err != nil {
+		return nil, err
+	}
+	merchant.CategoryID = category.ID
+	merchant.CategoryName = category.Name
+	merchant.MCC = category.MCC
+	merchant.CategorySource = category.Explanation.source()
+	return merchant, nil
+}
+
+// descriptorDomainCandidates returns the domain names a statement descriptor
+// may name its merchant by, most likely first: the words that look like
+// domain names, such as AMAZON.COM in "AMAZON.COM*2K4", then the first two
+// words joined and the first word as .com domains, both with and without the
+// payment processor prefix.
+func descriptorDomainCandidates(descriptor string) []string {
+	var candidates []string
+	add := func(candidate string) {
+		for _, seen := range candidates {
+			if seen == candidate {
+				return
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	for _, word := range strings.FieldsFunc(descriptor, func(r rune) bool {
+		return r == ' ' || r == '*' || r == '/' || r == '#'
+	}) {
+		if strings.Contains(strings.Trim(word, "."), ".") {
+			if host, err := NormalizeDomain(word); err == nil {
+				add(host)
+			}
+		}
+	}
+
+	wordLists := [][]string{descriptorWords(descriptor)}
+	full := descriptorWords(strings.ReplaceAll(descriptor, "*", " "))
+	if len(full) > 0 && (len(wordLists[0]) == 0 ||
+		full[0] != wordLists[0][0]) {
+		wordLists = append(wordLists, full)
+	}
+	for _, words := range wordLists {
+		if len(words) > 1 {
+			add(words[0] + words[1] + ".com")
+		}
+		if len(words) > 0 {
+			add(words[0] + ".com")
+		}
+	}
+	return candidates
+}
+
+// evaluateCharge returns the transaction a statement charge made with the
+// wallet card with cardKey at merchant is recorded as, with the reward the
+// card earned and the card the wallet would have recommended.
+func evaluateCharge(ctx context.Context, wallet *BaseWallet,
+	cardKey string, merchant store.MerchantDetails,
+	row StatementRow) (*store.BaseTransaction, error) {
+
+	var card *rewards.CardDetail
+	for _, walletCard := range wallet.Cards {
+		if walletCard.CardKey == cardKey {
+			card = walletCard
+		}
+	}
+	if card == nil {
+		return nil, fmt.Errorf("%w: card %q is not a card of the wallet",
+			ErrInvalidStatement, cardKey)
+	}
+	purchase := &Purchase{
+		CategoryID: merchant.CategoryID,
+		MCC:        merchant.MCC,
+		Amount:     row.Amount,
+		At:         row.Date,
+	}
+	recommended, err := wallet.SelectBest(ctx, purchase)
+	if errors.Is(err, ErrNoBeneficialCard) {
+		recommended = nil
+	} else if err != nil {
+		return nil, err
+	}
+	history, err := wallet.spendHistory(ctx, purchase.At)
+	if err != nil {
+		return nil, err
+	}
+	reward := CalculateBonusValue(purchase, card, history, wallet.valuation())
+
+	return &store.BaseTransaction{
+		WalletID:        wallet.ID,
+		TransactionAt:   row.Date,
+		SpendAmount:     row.Amount,
+		MerchantDetails: merchant,
+		CardDetails: store.CardDetails{
+			CardKey:       card.CardKey,
+			CardName:      card.CardName,
+			RewardDetails: *reward,
+		},
+		RecommendedCard: recommended,
+	}, nil
+}
+
+// walletAsOf returns a copy of wallet holding the terms its cards had at
+// time at, or their current terms for cards with no stored terms. Terms are
+// looked up once per card and day, through terms.
+func (service *Service) walletAsOf(ctx context.Context, wallet *BaseWallet,
+	at time.Time, terms map[string]*rewards.CardDetail) (*BaseWallet, error) {
+
+	dated := *wallet
+	dated.Cards = make([]*rewards.CardDetail, 0, len(wallet.Cards))
+	for _, card := range wallet.Cards {
+		key := card.CardKey + "|" + at.Local().Format(time.DateOnly)
+		asOf, ok := terms[key]
+		if !ok {
+			var err error
+			asOf, err = CardAsOf(ctx, service.Repo.Cards, card.CardKey, at)
+			if errors.Is(err, store.ErrNotFound) {
+				asOf = card
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to get terms of card %s: %w",
+					card.CardKey, err)
+			}
+			terms[key] = asOf
+		}
+		dated.Cards = append(dated.Cards, asOf)
+	}
+	return &dated, nil
+}
+
+// pendingTransactions holds the charges of a dry run in memory, listing them
+// along with the stored transactions, if any, so that they count towards
+// the spend limits of later charges.
+type pendingTransactions struct {
+	stored  store.TransactionRepository
+	pending []*store.Transaction
+}
+
+func (transactions *pendingTransactions) InsertTransaction(
+	ctx context.Context,
+	baseTransaction *store.BaseTransaction) (*store.Transaction, error) {
+
+	transaction := store.CreateTransaction(baseTransaction)
+	transactions.pending = append(transactions.pending, &transaction)
+	return &transaction, nil
+}
+
+func (transactions *pendingTransactions) ListTransactions(
+	ctx context.Context,
+	filter *store.TransactionFilter) ([]*store.Transaction, error) {
+
+	var listed []*store.Transaction
+	if transactions.stored != nil {
+		stored, err := transactions.stored.ListTransactions(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		listed = stored
+	}
+	for _, transaction := range transactions.pending {
+		if filter.Matches(transaction.BaseTransaction) {
+			listed = append(listed, transaction)
+		}
+	}
+	return listed, nil
+}
+
+// WriteReport writes the report to w as human readable text, followed by a
+// table of the imported charges not made with the recommended card.
+func (report *StatementReport) WriteReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	title := "Statement import"
+	if report.DryRun {
+		title += " (dry run)"
+	}
+	fmt.Fprintln(tw, title)
+	fmt.Fprintf(tw, "Rows:\t%d\n", report.Rows)
+	fmt.Fprintf(tw, "Imported:\t%d\n", report.Imported)
+	fmt.Fprintf(tw, "Duplicates:\t%d\n", report.Duplicates)
+	fmt.Fprintf(tw, "Credits:\t%d\n", report.Credits)
+	fmt.Fprintf(tw, "Uncategorized:\t%d\n", report.Uncategorized)
+	fmt.Fprintf(tw, "Spend:\t%.2f\n", report.Spend)
+	fmt.Fprintf(tw, "Earned:\t%.2f\n", report.Earned)
+	fmt.Fprintf(tw, "Best:\t%.2f\n", report.BestEarned)
+	fmt.Fprintf(tw, "Missed:\t%.2f\n", report.Missed())
+
+	header := false
+	for _, transaction := range report.Transactions {
+		used := transaction.CardDetails
+		best := transaction.RecommendedCard
+		if best == nil || best.CardKey == used.CardKey {
+			continue
+		}
+		if !header {
+			fmt.Fprintln(tw, "\nDate\tDescriptor\tCategory\tAmount\tUsed\t"+
+				"Recommended\tMissed")
+			header = true
+		}
+		amount := decimal.NewFromFloat(transaction.SpendAmount)
+		missed := amount.Mul(decimal.NewFromFloat(best.RewardDetails.Value).
+			Sub(decimal.NewFromFloat(used.RewardDetails.Value)))
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.2f\t%s\t%s\t%.2f\n",
+			transaction.TransactionAt.Format(time.DateOnly),
+			transaction.MerchantDetails.Descriptor,
+			transaction.MerchantDetails.CategoryName,
+			transaction.SpendAmount, used.CardName, best.CardName,
+			missed.InexactFloat64())
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,282 @@
+package shop_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+func TestParseStatement(t *testing.T) {
+	profiles := shop.DefaultStatementProfiles()
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2026, month, d, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name    string
+		profile *shop.StatementProfile
+		data    string
+		want    []shop.StatementRow
+		wantErr string
+	}{
+		{"charges negative", profiles["chase"],
+			"Transaction Date,Post Date,Description,Category,Type,Amount\n" +
+				"03/02/2026,03/03/2026,CHIPOTLE  1234,Food,Sale,-12.50\n" +
+				"03/05/2026,03/05/2026,PAYMENT THANK YOU,,Payment,500.00\n",
+			[]shop.StatementRow{
+				{Line: 2, Date: day(time.March, 2),
+					Descriptor: "CHIPOTLE 1234", Amount: 12.5},
+				{Line: 3, Date: day(time.March, 5),
+					Descriptor: "PAYMENT THANK YOU", Amount: -500},
+			}, ""},
+		{"debit and credit columns", profiles["capitalone"],
+			"\ufeffTransaction Date,Posted Date,Card No.,Description," +
+				"Category,Debit,Credit\n" +
+				"2026-03-02,2026-03-03,1234,UBER *TRIP,Other,\"1,024.00\",\n" +
+				"2026-03-04,2026-03-04,5678,REFUND,Other,,-20.00\n" +
+				"2026-03-06,2026-03-06,5678,RETURN,Other,,20.00\n",
+			[]shop.StatementRow{
+				{Line: 2, Date: day(time.March, 2), Descriptor: "UBER *TRIP",
+					Amount: 1024, Card: "1234"},
+				{Line: 3, Date: day(time.March, 4), Descriptor: "REFUND",
+					Amount: -20, Card: "5678"},
+				{Line: 4, Date: day(time.March, 6), Descriptor: "RETURN",
+					Amount: -20, Card: "5678"},
+			}, ""},
+		{"skipped lines and mapped cards", &shop.StatementProfile{
+			Name: "bank", Date: "date", Description: "memo",
+			Amount: "amount", Card: "card", SkipLines: 2,
+			Cards: map[string]string{"1234": "amex-gold"}},
+			"Account summary\nExported 03/31/2026\n" +
+				"DATE,MEMO,AMOUNT,CARD\n" +
+				"03/02/2026,SAFEWAY,$40.00,1234\n\n" +
+				"03/03/2026,RETURN,(5.00),9999\n",
+			[]shop.StatementRow{
+				{Line: 4, Date: day(time.March, 2), Descriptor: "SAFEWAY",
+					Amount: 40, Card: "amex-gold"},
+				{Line: 6, Date: day(time.March, 3), Descriptor: "RETURN",
+					Amount: -5, Card: "9999"},
+			}, ""},
+		{"empty", profiles["amex"], "", nil, ""},
+		{"missing column", profiles["amex"], "Date,Description\n", nil,
+			`no "Amount" column for profile amex`},
+		{"every invalid row", profiles["amex"],
+			"Date,Description,Amount\n" +
+				"2026-03-02,SAFEWAY,40.00\n" +
+				"03/03/2026, ,40.00\n" +
+				"03/04/2026,SAFEWAY,forty\n" +
+				"03/05/2026,SAFEWAY,\n",
+			nil, `line 2: invalid statement: invalid date: "2026-03-02"` +
+				"\nline 3: invalid statement: no description\n" +
+				`line 4: invalid statement: invalid amount: "forty"` + "\n" +
+				`line 5: invalid statement: invalid amount: ""`},
+		{"invalid profile", &shop.StatementProfile{Name: "bank",
+			Date: "date", Description: "memo", Amount: "amount",
+			Debit: "debit"}, "", nil,
+			"exactly one of amount and debit columns is required"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows, err := shop.ParseStatement(strings.NewReader(test.data),
+				test.profile)
+			if test.wantErr != "" {
+				if !errors.Is(err, shop.ErrInvalidStatement) ||
+					!strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("ParseStatement() error = %v, want %q", err,
+						test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseStatement() error = %v", err)
+			}
+			if len(rows) != len(test.want) {
+				t.Fatalf("ParseStatement() = %d rows, want %d", len(rows),
+					len(test.want))
+			}
+			for i, row := range rows {
+				want := test.want[i]
+				if row.Line != want.Line || !row.Date.Equal(want.Date) ||
+					row.Descriptor != want.Descriptor ||
+					row.Amount != want.Amount || row.Card != want.Card {
+					t.Errorf("row %d = %+v, want %+v", i, row, want)
+				}
+			}
+		})
+	}
+}
+
+// newStatementService returns a service whose repository knows chipotle.com
+// as a restaurant, and a stored wallet of goldCard and cashCard.
+func newStatementService(t *testing.T) (*shop.Service, *shop.BaseWallet) {
+	t.Helper()
+	repo := store.NewMemoryRepository()
+	if _, err := repo.Domains.InsertDomain(context.Background(),
+		&store.BaseDomain{Name: "chipotle.com", CategoryID: 1001,
+			CategoryName: "Restaurants"}); err != nil {
+		t.Fatalf("InsertDomain() error = %v", err)
+	}
+	wallet := &shop.BaseWallet{ID: "w1",
+		Cards:   []*rewards.CardDetail{goldCard, cashCard},
+		History: repo.Transactions}
+	return shop.NewService(repo, nil), wallet
+}
+
+// chipotle returns a row of a charge at Chipotle on the given day of March.
+func chipotle(line, d int, amount float64, card string) shop.StatementRow {
+	return shop.StatementRow{Line: line,
+		Date:       time.Date(2026, time.March, d, 0, 0, 0, 0, time.Local),
+		Descriptor: "CHIPOTLE 1234", Amount: amount, Card: card}
+}
+
+func TestImportStatementDuplicates(t *testing.T) {
+	ctx := context.Background()
+	service, wallet := newStatementService(t)
+	first := []shop.StatementRow{
+		chipotle(2, 2, 12.5, "amex-gold"),
+		chipotle(3, 2, 12.5, "amex-gold"),
+		chipotle(4, 3, 30, "citi-doublecash"),
+	}
+	if _, err := service.ImportStatement(ctx, wallet, first,
+		shop.StatementOptions{}); err != nil {
+		t.Fatalf("ImportStatement() error = %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		rows           []shop.StatementRow
+		wantImported   int
+		wantDuplicates int
+		wantCredits    int
+	}{
+		{"same statement", first, 0, 3, 0},
+		{"overlapping statement", []shop.StatementRow{
+			chipotle(2, 3, 30, "citi-doublecash"),
+			chipotle(3, 4, 8, "amex-gold"),
+			chipotle(4, 4, -8, "amex-gold"),
+		}, 1, 1, 1},
+		{"one more identical charge", []shop.StatementRow{
+			chipotle(2, 2, 12.5, "amex-gold"),
+			chipotle(3, 2, 12.5, "amex-gold"),
+			chipotle(4, 2, 12.5, "amex-gold"),
+		}, 1, 2, 0},
+		{"same charge on another card", []shop.StatementRow{
+			chipotle(2, 3, 30, "amex-gold"),
+		}, 1, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report, err := service.ImportStatement(ctx, wallet, test.rows,
+				shop.StatementOptions{})
+			if err != nil {
+				t.Fatalf("ImportStatement() error = %v", err)
+			}
+			if report.Imported != test.wantImported ||
+				report.Duplicates != test.wantDuplicates ||
+				report.Credits != test.wantCredits {
+				t.Errorf("ImportStatement() = %d imported, %d duplicates, "+
+					"%d credits, want %d, %d, %d", report.Imported,
+					report.Duplicates, report.Credits, test.wantImported,
+					test.wantDuplicates, test.wantCredits)
+			}
+		})
+	}
+
+	stored, err := service.Repo.Transactions.ListTransactions(ctx,
+		&store.TransactionFilter{WalletID: wallet.ID})
+	if err != nil {
+		t.Fatalf("ListTransactions() error = %v", err)
+	}
+	if len(stored) != 6 {
+		t.Errorf("%d transactions stored, want 6", len(stored))
+	}
+}
+
+func TestImportStatementDryRun(t *testing.T) {
+	ctx := context.Background()
+	service, wallet := newStatementService(t)
+	// The first charge leaves 100 USD of the Gold card's dining cap, too
+	// little for the Gold card to beat 2% cash back on the second.
+	rows := []shop.StatementRow{
+		chipotle(3, 9, 600, "amex-gold"),
+		chipotle(2, 2, 900, "amex-gold"),
+	}
+
+	report, err := service.ImportStatement(ctx, wallet, rows,
+		shop.StatementOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("ImportStatement() error = %v", err)
+	}
+	if !report.DryRun || report.Imported != 2 ||
+		report.Uncategorized != 0 {
+		t.Errorf("ImportStatement() = %+v, want a dry run of 2 "+
+			"categorized charges", report)
+	}
+	var recommended []string
+	for _, transaction := range report.Transactions {
+		if transaction.RecommendedCard == nil {
+			t.Fatalf("charge on %s has no recommended card",
+				transaction.TransactionAt.Format(time.DateOnly))
+		}
+		recommended = append(recommended,
+			transaction.RecommendedCard.CardKey)
+	}
+	want := []string{goldCard.CardKey, cashCard.CardKey}
+	if strings.Join(recommended, ",") != strings.Join(want, ",") {
+		t.Errorf("recommended cards = %v, want %v", recommended, want)
+	}
+	if report.Missed() <= 0 {
+		t.Errorf("Missed() = %v, want more than nothing", report.Missed())
+	}
+
+	stored, err := service.Repo.Transactions.ListTransactions(ctx,
+		&store.TransactionFilter{WalletID: wallet.ID})
+	if err != nil {
+		t.Fatalf("ListTransactions() error = %v", err)
+	}
+	if len(stored) != 0 {
+		t.Errorf("dry run stored %d transactions", len(stored))
+	}
+}
+
+func TestImportStatementUnknownCard(t *testing.T) {
+	ctx := context.Background()
+	service, wallet := newStatementService(t)
+	rows := []shop.StatementRow{
+		chipotle(2, 2, 12.5, "amex-gold"),
+		chipotle(3, 2, 12.5, "9999"),
+		chipotle(4, 2, 12.5, ""),
+	}
+
+	_, err := service.ImportStatement(ctx, wallet, rows,
+		shop.StatementOptions{})
+	want := `line 3: invalid statement: card "9999" is not a card of the ` +
+		"wallet\nline 4: invalid statement: no card given for the charge"
+	if !errors.Is(err, shop.ErrInvalidStatement) || err.Error() != want {
+		t.Errorf("ImportStatement() error = %v, want %q", err, want)
+	}
+	stored, err := service.Repo.Transactions.ListTransactions(ctx,
+		&store.TransactionFilter{WalletID: wallet.ID})
+	if err != nil {
+		t.Fatalf("ListTransactions() error = %v", err)
+	}
+	if len(stored) != 0 {
+		t.Errorf("%d transactions stored, want none", len(stored))
+	}
+
+	report, err := service.ImportStatement(ctx, wallet, rows,
+		shop.StatementOptions{Card: "citi-doublecash",
+			Cards: map[string]string{"9999": "amex-gold"}})
+	if err != nil {
+		t.Fatalf("ImportStatement() with mapped cards error = %v", err)
+	}
+	if report.Imported != 3 {
+		t.Errorf("ImportStatement() imported %d, want 3", report.Imported)
+	}
+}
@@ -0,0 +1,239 @@
+package shop
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+// DefaultSyncConcurrency is the number of card details a catalog sync
+// fetches at once unless told otherwise.
+const DefaultSyncConcurrency = 4
+
+// SyncOptions configures SyncCatalog.
+type SyncOptions struct {
+	Client      *rewards.Client // API client, the default client if nil
+	Concurrency int             // Card details fetched at once
+}
+
+// CardChange is a stored card whose detail changed in a catalog sync.
+type CardChange struct {
+	CardKey string   `json:"cardKey"`
+	Fields  []string `json:"fields"` // JSON names of the changed fields
+}
+
+// SyncError is a card a catalog sync failed to fetch or store.
+type SyncError struct {
+	CardKey string `json:"cardKey"`
+	Error   string `json:"error"`
+}
+
+// SyncReport describes the outcome of a catalog sync. The card keys in it
+// are in card list order, except Delisted which is ordered by card key.
+type SyncReport struct {
+	StartedAt  time.Time    `json:"startedAt"`
+	FinishedAt time.Time    `json:"finishedAt"`
+	Listed     int          `json:"listed"`    // Cards in the card list
+	Added      []string     `json:"added"`     // Cards stored for the first time
+	Changed    []CardChange `json:"changed"`   // Stored cards whose detail changed
+	Unchanged  int          `json:"unchanged"` // Stored cards left as they were
+	Inactive   []string     `json:"inactive"`  // Listed cards closed to applications
+	Delisted   []string     `json:"delisted"`  // Cards no longer listed, newly marked
+	Errors     []SyncError  `json:"errors"`
+}
+
+// fetchResult is the detail fetched for a listed card, or the error fetching
+// it.
+type fetchResult struct {
+	cardKey string
+	detail  *rewards.CardDetail
+	err     error
+}
+
+// SyncCatalog brings the stored cards in line with the Rewards API catalog.
+// It fetches the card list and the detail of every listed card, with at most
+// options.Concurrency requests at once, and upserts each card by card key,
+// recording which fields changed. Listed cards get the active or, when
+// IsActive is 0, the inactive status; stored cards missing from the list are
+// marked delisted. A card that fails to fetch or store is reported and left
+// as it was; only failing to get the card list or the stored cards fails the
+// whole sync. If ctx is done while the cards are stored, the sync stops and
+// the report of what it did so far is returned with ctx's error.
+func SyncCatalog(ctx context.Context, cards store.CardRepository,
+	options SyncOptions) (*SyncReport, error) {
+
+	client := options.Client
+	if client == nil {
+		client = rewards.DefaultClient()
+	}
+	concurrency := options.Concurrency
+	if concurrency < 1 {
+		concurrency = DefaultSyncConcurrency
+	}
+
+	report := SyncReport{
+		StartedAt: time.Now(),
+		Added:     []string{},
+		Changed:   []CardChange{},
+		Inactive:  []string{},
+		Delisted:  []string{},
+		Errors:    []SyncError{},
+	}
+
+	cardList, err := client.FetchCardList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch card list: %w", err)
+	}
+	var cardKeys []string
+	listed := make(map[string]bool)
+	for _, cardIssuer := range *cardList {
+		for _, card := range cardIssuer.Card {
+			if !listed[card.CardKey] {
+				listed[card.CardKey] = true
+				cardKeys = append(cardKeys, card.CardKey)
+			}
+		}
+	}
+	report.Listed = len(cardKeys)
+
+	stored, err := cards.ListCards(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stored cards: %w", err)
+	}
+	storedByKey := make(map[string]*store.Card, len(stored))
+	for _, card := range stored {
+		storedByKey[card.CardDetail.CardKey] = card
+	}
+
+	for _, result := range fetchCardDetails(ctx, cardKeys, concurrency,
+		client.FetchCardDetail) {
+		if err := ctx.Err(); err != nil {
+			report.FinishedAt = time.Now()
+			return &report, err
+		}
+		if result.err != nil {
+			report.addError(result.cardKey, result.err)
+			continue
+		}
+
+		card := store.Card{
+			CardDetail: *result.detail,
+			Status:     store.CardStatusActive,
+			SyncedAt:   report.StartedAt,
+			FetchedAt:  report.StartedAt,
+		}
+		if result.detail.IsActive == 0 {
+			card.Status = store.CardStatusInactive
+		}
+		if _, err := cards.UpsertCard(ctx, &card); err != nil {
+			report.addError(result.cardKey, err)
+			continue
+		}
+		if card.Status == store.CardStatusInactive {
+			report.Inactive = append(report.Inactive, result.cardKey)
+		}
+
+		previous, ok := storedByKey[result.cardKey]
+		if !ok {
+			report.Added = append(report.Added, result.cardKey)
+			continue
+		}
+		fields := rewards.ChangedFields(&previous.CardDetail, result.detail)
+		if len(fields) == 0 {
+			report.Unchanged++
+			continue
+		}
+		report.Changed = append(report.Changed,
+			CardChange{CardKey: result.cardKey, Fields: fields})
+	}
+
+	for _, card := range stored {
+		cardKey := card.CardDetail.CardKey
+		if listed[cardKey] || card.Status == store.CardStatusDelisted {
+			continue
+		}
+		err := cards.SetCardStatus(ctx, cardKey, store.CardStatusDelisted)
+		if err != nil {
+			report.addError(cardKey, err)
+			continue
+		}
+		report.Delisted = append(report.Delisted, cardKey)
+	}
+
+	report.FinishedAt = time.Now()
+	return &report, nil
+}
+
+// fetchCardDetails fetches the detail of every card in cardKeys with fetch,
+// with at most concurrency calls at once, and returns the results in the
+// order of cardKeys. Cards not started before ctx is done get ctx's error.
+func fetchCardDetails(ctx context.Context, cardKeys []string,
+	concurrency int, fetch func(ctx context.Context,
+		cardKey string) (*rewards.CardDetail, error)) []fetchResult {
+
+	results := make([]fetchResult, len(cardKeys))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, cardKey := range cardKeys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				results[i] = fetchResult{cardKey: cardKey, err: ctx.Err()}
+				return
+			}
+			defer func() { <-slots }()
+
+			detail, err := fetch(ctx, cardKey)
+			results[i] = fetchResult{cardKey: cardKey, detail: detail, err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// addError records that the card with cardKey failed to sync.
+func (report *SyncReport) addError(cardKey string, err error) {
+	report.Errors = append(report.Errors,
+		SyncError{CardKey: cardKey, Error: err.Error()})
+}
+
+// Failed reports whether any card failed to sync.
+func (report *SyncReport) Failed() bool {
+	return len(report.Errors) > 0
+}
+
+// WriteReport writes the report as human readable text to w.
+func (report *SyncReport) WriteReport(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Catalog sync %s (%s)\n",
+		report.StartedAt.Format(time.DateTime),
+		report.FinishedAt.Sub(report.StartedAt).Round(time.Millisecond))
+	fmt.Fprintf(&b, "Listed:    %d\n", report.Listed)
+	fmt.Fprintf(&b, "Added:     %d\n", len(report.Added))
+	fmt.Fprintf(&b, "Changed:   %d\n", len(report.Changed))
+	fmt.Fprintf(&b, "Unchanged: %d\n", report.Unchanged)
+	fmt.Fprintf(&b, "Inactive:  %d\n", len(report.Inactive))
+	fmt.Fprintf(&b, "Delisted:  %d\n", len(report.Delisted))
+	fmt.Fprintf(&b, "Errors:    %d\n", len(report.Errors))
+	for _, change := range report.Changed {
+		fmt.Fprintf(&b, "  changed  %s: %s\n", change.CardKey,
+			strings.Join(change.Fields, ", "))
+	}
+	for _, cardKey := range report.Delisted {
+		fmt.Fprintf(&b, "  delisted %s\n", cardKey)
+	}
+	for _, syncErr := range report.Errors {
+		fmt.Fprintf(&b, "  error    %s: %s\n", syncErr.CardKey, syncErr.Error)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,188 @@
+package shop
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/store"
+	"github.com/shopspring/decimal"
+)
+
+// Valuation prices the rewards currency (points, miles or cashback) a card
+// earns.
+type Valuation interface {
+	// Name identifies the valuation in stored transactions.
+	Name() string
+
+	// CentsPerPoint returns the value in cents of one unit of the card's
+	// rewards currency.
+	CentsPerPoint(card *rewards.CardDetail) float64
+}
+
+// Names of the built-in valuations, as accepted by NewValuation.
+const (
+	DefaultValuationName      = "default"
+	CashOutValuationName      = "cash"
+	APIValuationName          = "api"
+	TableValuationName        = "table"
+	ConservativeValuationName = "conservative"
+)
+
+// DefaultConservativeFloor is the value in cents given by a
+// ConservativeValuation to points that cannot be cashed out.
+const DefaultConservativeFloor = 0.5
+
+// NewValuation returns the built-in valuation with the given name. The table
+// maps redemption programs to cents per point and is only used by the table
+// valuation.
+func NewValuation(name string, table map[string]float64) (Valuation, error) {
+	switch name {
+	case "", DefaultValuationName:
+		return DefaultValuation{}, nil
+	case CashOutValuationName:
+		return CashOutValuation{}, nil
+	case APIValuationName:
+		return APIValuation{}, nil
+	case TableValuationName:
+		return NewTableValuation(table, DefaultValuation{}), nil
+	case ConservativeValuationName:
+		return ConservativeValuation{Floor: DefaultConservativeFloor}, nil
+	default:
+		return nil, fmt.Errorf("unknown valuation: %s", name)
+	}
+}
+
+// DefaultValuation values points at their cash-out value when they can be
+// converted to a statement credit, and at one cent otherwise.
+type DefaultValuation struct{}
+
+func (DefaultValuation) Name() string {
+	return DefaultValuationName
+}
+
+func (DefaultValuation) CentsPerPoint(card *rewards.CardDetail) float64 {
+	if card.BaseSpendEarnIsCash == 1 {
+		return card.BaseSpendEarnCashValue
+	}
+	return 1
+}
+
+// CashOutValuation only counts what points can be converted to as a statement
+// credit. Points that cannot be cashed out are worth nothing.
+type CashOutValuation struct{}
+
+func (CashOutValuation) Name() string {
+	return CashOutValuationName
+}
+
+func (CashOutValuation) CentsPerPoint(card *rewards.CardDetail) float64 {
+	if card.BaseSpendEarnIsCash == 1 {
+		return card.BaseSpendEarnCashValue
+	}
+	return 0
+}
+
+// APIValuation uses the Rewards API's subjective point valuation, falling
+// back to DefaultValuation for cards without one.
+type APIValuation struct{}
+
+func (APIValuation) Name() string {
+	return APIValuationName
+}
+
+func (APIValuation) CentsPerPoint(card *rewards.CardDetail) float64 {
+	if card.BaseSpendEarnValuation > 0 {
+		return card.BaseSpendEarnValuation
+	}
+	return DefaultValuation{}.CentsPerPoint(card)
+}
+
+// TableValuation values points from a user supplied table of cents per point
+// keyed by redemption program (CardDetail.BaseSpendEarnType), matched without
+// regard to case. Programs missing from the table use Fallback.
+type TableValuation struct {
+	Table    map[string]float64
+	Fallback Valuation
+}
+
+// NewTableValuation returns a TableValuation for table, normalizing its
+// program names.
+func NewTableValuation(table map[string]float64,
+	fallback Valuation) *TableValuation {
+
+	normalized := make(map[string]float64, len(table))
+	for program, cents := range table {
+		normalized[normalizeProgram(program)] = cents
+	}
+	return &TableValuation{Table: normalized, Fallback: fallback}
+}
+
+func (valuation *TableValuation) Name() string {
+	return TableValuationName
+}
+
+func (valuation *TableValuation) CentsPerPoint(
+	card *rewards.CardDetail) float64 {
+
+	program := normalizeProgram(card.BaseSpendEarnType)
+	if cents, ok := valuation.Table[program]; ok {
+		return cents
+	}
+	if valuation.Fallback == nil {
+		return DefaultValuation{}.CentsPerPoint(card)
+	}
+	return valuation.Fallback.CentsPerPoint(card)
+}
+
+// normalizeProgram folds a redemption program name for table lookups.
+func normalizeProgram(program string) string {
+	return strings.ToLower(strings.Join(strings.Fields(program), " "))
+}
+
+// ConservativeValuation values points at what they are sure to be worth: their
+// cash-out value when they can be cashed out and Floor cents otherwise, but
+// never more than the Rewards API's own valuation.
+type ConservativeValuation struct {
+	Floor float64
+}
+
+func (ConservativeValuation) Name() string {
+	return ConservativeValuationName
+}
+
+func (valuation ConservativeValuation) CentsPerPoint(
+	card *rewards.CardDetail) float64 {
+
+	cents := valuation.Floor
+	if card.BaseSpendEarnIsCash == 1 {
+		cents = card.BaseSpendEarnCashValue
+	}
+	if card.BaseSpendEarnValuation > 0 && card.BaseSpendEarnValuation < cents {
+		cents = card.BaseSpendEarnValuation
+	}
+	return cents
+}
+
+// Revalue recomputes reward, recorded for a purchase with card, under
+// valuation. The recorded earn rate and foreign transaction fee are kept.
+func Revalue(reward store.RewardDetails, card *rewards.CardDetail,
+	valuation Valuation) store.RewardDetails {
+
+	centsPerPoint := valuation.CentsPerPoint(card)
+	value := decimal.NewFromFloat(pointValue(reward.Amount, centsPerPoint))
+
+	reward.Valuation = valuation.Name()
+	reward.CentsPerPoint = centsPerPoint
+	reward.Value = value.Sub(decimal.NewFromFloat(reward.FxFee)).
+		InexactFloat64()
+	return reward
+}
+
+// pointValue gets the value in dollars per dollar of earning rewardAmount
+// points per dollar at centsPerPoint.
+func pointValue(rewardAmount, centsPerPoint float64) float64 {
+	cent, _ := decimal.NewFromString("0.01")
+	mult := decimal.NewFromFloat(rewardAmount).Mul(cent)
+	return decimal.NewFromFloat(centsPerPoint).Mul(mult).InexactFloat64()
+}
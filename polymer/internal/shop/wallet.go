@@ -1,39 +1,128 @@
 package shop
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/ayushh-vermaa/polymer/internal/rewards"
 	"github.com/ayushh-vermaa/polymer/store"
-
-	// For doing exact math with strings
-	"go.mongodb.org/mongo-driver/mongo"
+	"github.com/shopspring/decimal"
 )
 
+// ErrNoBeneficialCard is returned by SelectBest when no card of the wallet
+// earns a positive reward value on the purchase.
+var ErrNoBeneficialCard = errors.New("no card earns a reward on the purchase")
+
 // BaseWallet represents a collection of cards without personal info.
 type BaseWallet struct {
+	// ID is the ID of the stored wallet, if any. Spend history is then
+	// limited to the transactions recorded against it.
+	ID string `json:"id,omitempty"`
+
 	Cards []*rewards.CardDetail `json:"cards"`
+
+	// History supplies the stored transactions that spend limits are
+	// measured against. Without it every capped bonus is treated as unused.
+	History store.TransactionRepository `json:"-"`
+
+	// Valuation prices the points earned by the wallet's cards. Without it
+	// DefaultValuation is used.
+	Valuation Valuation `json:"-"`
+
+	// OpenedOn holds when each card, by card key, was opened. Sign-up
+	// bonuses are only tracked for cards with an opening date.
+	OpenedOn map[string]time.Time `json:"openedOn,omitempty"`
+
+	// PrioritizeSignupBonus makes SelectBest add the amortized value of
+	// at-risk sign-up bonuses to their card's per-dollar value.
+	PrioritizeSignupBonus bool `json:"prioritizeSignupBonus,omitempty"`
 }
 
-// BuildWallet gets cards for a given set of cardKey strings and builds a
-// BaseWallet instance with them.
-func BuildWallet(client *mongo.Client, cardKeys []string) *BaseWallet {
-	var wallet BaseWallet
+// BuildWallet gets cards for a given set of cardKey strings through the
+// service's card cache and builds a BaseWallet instance with them, using the
+// repository's transactions as its spend history. Cards that cannot be got
+// are left out of the wallet and reported in a CardErrors error, returned
+// along with the wallet so that callers can decide whether to go on without
+// them.
+func (service *Service) BuildWallet(ctx context.Context,
+	cardKeys []string) (*BaseWallet, error) {
 
-	cards, err := GetCards(client, cardKeys)
-	if err != nil {
-		log.Printf("Error getting details for cards: %v", err)
-		return &wallet
+	wallet := BaseWallet{History: service.Repo.Transactions}
+
+	cards, err := service.Cards.Get(ctx, cardKeys)
+	var cardErrs CardErrors
+	if err != nil && !errors.As(err, &cardErrs) {
+		return nil, fmt.Errorf("failed to get details for cards: %w", err)
 	}
 
 	wallet.Cards = cards
-	return &wallet
+	return &wallet, err
+}
+
+// LoadWallet builds a BaseWallet from a stored wallet, with its cards, their
+// opening dates and its point valuation. As with BuildWallet, cards that
+// cannot be got are left out and reported in a CardErrors error returned
+// along with the wallet.
+func (service *Service) LoadWallet(ctx context.Context,
+	stored *store.Wallet) (*BaseWallet, error) {
+
+	valuation, err := NewValuation(stored.Valuation, stored.ValuationTable)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := service.BuildWallet(ctx, stored.CardKeys())
+	if wallet == nil {
+		return nil, err
+	}
+	wallet.ID = stored.ID.Hex()
+	wallet.Valuation = valuation
+	wallet.PrioritizeSignupBonus = stored.PrioritizeSignupBonus
+
+	for _, card := range stored.Cards {
+		if card.OpenedOn.IsZero() {
+			continue
+		}
+		if wallet.OpenedOn == nil {
+			wallet.OpenedOn = make(map[string]time.Time)
+		}
+		wallet.OpenedOn[card.CardKey] = card.OpenedOn
+	}
+
+	return wallet, err
+}
+
+// PartialWallet reports whether err, as returned with wallet by BuildWallet
+// or LoadWallet, only reports cards left out of a wallet that still holds
+// others, so that the wallet can be used without them.
+func PartialWallet(wallet *BaseWallet, err error) bool {
+	var cardErrs CardErrors
+	return wallet != nil && len(wallet.Cards) > 0 && errors.As(err, &cardErrs)
 }
 
 // SelectBest finds the card with the highest reward value for the given
-// merchant category. It returns the reward value as a string and the card with
-// the best reward.
-func (wallet *BaseWallet) SelectBest(categoryID int) *store.CardDetails {
+// purchase, taking into account how much of each capped bonus has already
+// been spent this period and, if PrioritizeSignupBonus is set, the sign-up
+// bonuses at risk of being missed. It returns the card with the best reward,
+// or ErrNoBeneficialCard if no card's reward is worth more than nothing.
+func (wallet *BaseWallet) SelectBest(ctx context.Context,
+	purchase *Purchase) (*store.CardDetails, error) {
+
+	history, err := wallet.spendHistory(ctx, purchase.At)
+	if err != nil {
+		return nil, err
+	}
+
+	var signupBonuses map[string]*SignupBonusProgress
+	if wallet.PrioritizeSignupBonus {
+		signupBonuses, err = wallet.SignupBonusProgress(ctx, purchase.At)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	bestCardDetails := store.CardDetails{
 		CardKey:  "",
 		CardName: "",
@@ -46,8 +135,20 @@ func (wallet *BaseWallet) SelectBest(categoryID int) *store.CardDetails {
 		},
 	}
 
+	// A card only wins with a positive value: fees can make a card lose
+	// money on a foreign purchase, and paying with it is no better than
+	// paying with no card of the wallet at all.
 	for _, card := range wallet.Cards {
-		rewardDetails := CalculateBonusValue(categoryID, card)
+		rewardDetails := CalculateBonusValue(purchase, card, history,
+			wallet.valuation())
+		if progress := signupBonuses[card.CardKey]; progress != nil &&
+			progress.AtRisk {
+			rewardDetails.SignupBonusValue = progress.AmortizedValue(
+				purchase.Amount)
+			rewardDetails.Value = decimal.NewFromFloat(rewardDetails.Value).
+				Add(decimal.NewFromFloat(rewardDetails.SignupBonusValue)).
+				InexactFloat64()
+		}
 		if rewardDetails.Value > bestCardDetails.RewardDetails.Value {
 			bestCardDetails = store.CardDetails{
 				CardKey:       card.CardKey,
@@ -56,6 +157,52 @@ func (wallet *BaseWallet) SelectBest(categoryID int) *store.CardDetails {
 			}
 		}
 	}
+	if bestCardDetails.CardKey == "" {
+		return nil, ErrNoBeneficialCard
+	}
+
+	return &bestCardDetails, nil
+}
+
+// valuation returns the wallet's Valuation, or DefaultValuation if it has
+// none.
+func (wallet *BaseWallet) valuation() Valuation {
+	if wallet.Valuation == nil {
+		return DefaultValuation{}
+	}
+	return wallet.Valuation
+}
+
+// spendHistory loads the stored transactions of the wallet's capped cards
+// that fall in the calendar year of at, which covers every monthly,
+// quarterly and yearly reset period containing at.
+func (wallet *BaseWallet) spendHistory(ctx context.Context,
+	at time.Time) (SpendHistory, error) {
+
+	if wallet.History == nil {
+		return nil, nil
+	}
+
+	var cardKeys []string
+	for _, card := range wallet.Cards {
+		for i := range card.SpendBonusCategory {
+			if IsCapped(&card.SpendBonusCategory[i]) {
+				cardKeys = append(cardKeys, card.CardKey)
+				break
+			}
+		}
+	}
+	if len(cardKeys) == 0 {
+		return nil, nil
+	}
+
+	start, end := ResetPeriod("year", at)
+	transactions, err := wallet.History.ListTransactions(ctx,
+		&store.TransactionFilter{WalletID: wallet.ID, CardKeys: cardKeys,
+			From: start, To: end})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load spend history: %w", err)
+	}
 
-	return &bestCardDetails
+	return SpendHistory(transactions), nil
 }
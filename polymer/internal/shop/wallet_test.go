@@ -0,0 +1,218 @@
+package shop_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/internal/shop"
+	"github.com/ayushh-vermaa/polymer/store"
+)
+
+const diningCategoryID = 2
+
+// goldCard earns 4x points on dining up to 1,000 USD a year and 1x on
+// everything else, each point worth a cent by default.
+var goldCard = &rewards.CardDetail{
+	CardKey:         "amex-gold",
+	CardName:        "Gold",
+	BaseSpendAmount: 1,
+	SpendBonusCategory: []rewards.SpendBonusCategory{{
+		SpendBonusCategoryType:     "Category",
+		SpendBonusCategoryName:     "Dining",
+		SpendBonusCategoryID:       diningCategoryID,
+		SpendBonusCategoryGroup:    "Dining",
+		SpendBonusSubcategoryGroup: "All Dining",
+		EarnMultiplier:             4,
+		IsSpendLimit:               1,
+		SpendLimit:                 1000,
+		SpendLimitResetPeriod:      "year",
+	}},
+}
+
+// cashCard earns 2% cash back on everything.
+var cashCard = &rewards.CardDetail{
+	CardKey:                "citi-doublecash",
+	CardName:               "Double Cash",
+	BaseSpendAmount:        2,
+	BaseSpendEarnIsCash:    1,
+	BaseSpendEarnCashValue: 1,
+}
+
+func TestSelectBest(t *testing.T) {
+	ctx := context.Background()
+	march := func(d int) time.Time {
+		return time.Date(2026, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+	dining := func(walletID string, amount float64,
+		at time.Time) *store.BaseTransaction {
+
+		return &store.BaseTransaction{
+			WalletID:      walletID,
+			TransactionAt: at,
+			SpendAmount:   amount,
+			MerchantDetails: store.MerchantDetails{
+				CategoryID: diningCategoryID},
+			CardDetails: store.CardDetails{CardKey: goldCard.CardKey},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		cards    []*rewards.CardDetail
+		history  []*store.BaseTransaction
+		purchase shop.Purchase
+		want     string
+		wantErr  error
+	}{
+		{"bonus category",
+			[]*rewards.CardDetail{goldCard, cashCard}, nil,
+			shop.Purchase{CategoryID: diningCategoryID, Amount: 100,
+				At: march(10)},
+			goldCard.CardKey, nil},
+		{"outside bonus category",
+			[]*rewards.CardDetail{goldCard, cashCard}, nil,
+			shop.Purchase{CategoryID: -1, Amount: 100, At: march(10)},
+			cashCard.CardKey, nil},
+		{"cap used up",
+			[]*rewards.CardDetail{goldCard, cashCard},
+			[]*store.BaseTransaction{dining("w1", 1000, march(1))},
+			shop.Purchase{CategoryID: diningCategoryID, Amount: 100,
+				At: march(10)},
+			cashCard.CardKey, nil},
+		{"cap partly left",
+			[]*rewards.CardDetail{goldCard, cashCard},
+			[]*store.BaseTransaction{dining("w1", 950, march(1))},
+			shop.Purchase{CategoryID: diningCategoryID, Amount: 100,
+				At: march(10)},
+			goldCard.CardKey, nil},
+		{"cap reset for the new year",
+			[]*rewards.CardDetail{goldCard, cashCard},
+			[]*store.BaseTransaction{dining("w1", 1000,
+				time.Date(2025, time.December, 30, 0, 0, 0, 0, time.UTC))},
+			shop.Purchase{CategoryID: diningCategoryID, Amount: 100,
+				At: march(10)},
+			goldCard.CardKey, nil},
+		{"spend of another wallet",
+			[]*rewards.CardDetail{goldCard, cashCard},
+			[]*store.BaseTransaction{dining("w2", 1000, march(1))},
+			shop.Purchase{CategoryID: diningCategoryID, Amount: 100,
+				At: march(10)},
+			goldCard.CardKey, nil},
+		{"no cards", nil, nil,
+			shop.Purchase{CategoryID: -1, Amount: 100, At: march(10)},
+			"", shop.ErrNoBeneficialCard},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			history := store.NewMemoryTransactionRepository()
+			for _, transaction := range test.history {
+				_, err := history.InsertTransaction(ctx, transaction)
+				if err != nil {
+					t.Fatalf("InsertTransaction() error = %v", err)
+				}
+			}
+			wallet := &shop.BaseWallet{ID: "w1", Cards: test.cards,
+				History: history}
+
+			got, err := wallet.SelectBest(ctx, &test.purchase)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("SelectBest() error = %v, want %v", err,
+					test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.CardKey != test.want {
+				t.Errorf("SelectBest() = %s, want %s", got.CardKey, test.want)
+			}
+			if got.RewardDetails.Value <= 0 {
+				t.Errorf("SelectBest() value = %v, want more than nothing",
+					got.RewardDetails.Value)
+			}
+		})
+	}
+}
+
+func TestSelectBestWithoutHistory(t *testing.T) {
+	wallet := &shop.BaseWallet{Cards: []*rewards.CardDetail{goldCard,
+		cashCard}}
+	purchase := &shop.Purchase{CategoryID: diningCategoryID, Amount: 100,
+		At: time.Now()}
+
+	got, err := wallet.SelectBest(context.Background(), purchase)
+	if err != nil {
+		t.Fatalf("SelectBest() error = %v", err)
+	}
+	if got.CardKey != goldCard.CardKey {
+		t.Errorf("SelectBest() = %s, want %s with the cap unused",
+			got.CardKey, goldCard.CardKey)
+	}
+}
+
+// feeCard earns 1x points but charges a 3% foreign transaction fee.
+var feeCard = &rewards.CardDetail{
+	CardKey:         "fee-card",
+	CardName:        "Fee Card",
+	BaseSpendAmount: 1,
+	IsFxFee:         1,
+	FxFee:           3,
+}
+
+func TestSelectBestForeign(t *testing.T) {
+	tests := []struct {
+		name     string
+		cards    []*rewards.CardDetail
+		country  string
+		currency string
+		want     string
+		wantFee  float64
+		wantErr  error
+	}{
+		{"domestic", []*rewards.CardDetail{feeCard}, "", "", feeCard.CardKey,
+			0, nil},
+		{"home country and currency", []*rewards.CardDetail{feeCard}, "us",
+			"usd", feeCard.CardKey, 0, nil},
+		{"fee outweighs the reward abroad", []*rewards.CardDetail{feeCard},
+			"FR", "", "", 0, shop.ErrNoBeneficialCard},
+		{"fee outweighs the reward in a foreign currency",
+			[]*rewards.CardDetail{feeCard}, "", "EUR", "", 0,
+			shop.ErrNoBeneficialCard},
+		{"card without a fee wins abroad",
+			[]*rewards.CardDetail{feeCard, cashCard}, "FR", "EUR",
+			cashCard.CardKey, 0, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wallet := &shop.BaseWallet{Cards: test.cards}
+			purchase := &shop.Purchase{CategoryID: -1, Amount: 100,
+				At: time.Now(), Country: test.country,
+				Currency: test.currency}
+
+			got, err := wallet.SelectBest(context.Background(), purchase)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("SelectBest() error = %v, want %v", err,
+					test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.CardKey != test.want ||
+				got.RewardDetails.FxFee != test.wantFee {
+				t.Errorf("SelectBest() = %s with fee %v, want %s with fee %v",
+					got.CardKey, got.RewardDetails.FxFee, test.want,
+					test.wantFee)
+			}
+		})
+	}
+
+	foreign := &shop.Purchase{CategoryID: -1, Amount: 100, Country: "FR"}
+	reward := shop.CalculateBonusValue(foreign, feeCard, nil,
+		shop.DefaultValuation{})
+	if reward.FxFee != 0.03 || reward.Value != -0.02 {
+		t.Errorf("CalculateBonusValue() fee = %v, value = %v, want 0.03 "+
+			"and -0.02", reward.FxFee, reward.Value)
+	}
+}
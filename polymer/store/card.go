@@ -2,56 +2,97 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/ayushh-vermaa/polymer/internal/rewards"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const CardCollection = "card"
 
+// Catalog statuses of a card, set when the catalog is synced.
+const (
+	CardStatusActive   = "active"   // Listed and open for applications
+	CardStatusInactive = "inactive" // Listed but closed to applications
+	CardStatusDelisted = "delisted" // No longer in the card list
+)
+
 // Card represents the structure of a credit card document in MongoDB.
 type Card struct {
 	*BaseDocument `bson:",inline"`
 	CardDetail    rewards.CardDetail `bson:"card_detail"`
+	Status        string             `bson:"status,omitempty"`     // Catalog status, empty until synced
+	SyncedAt      time.Time          `bson:"synced_at,omitempty"`  // Last catalog sync that listed the card
+	FetchedAt     time.Time          `bson:"fetched_at,omitempty"` // When CardDetail was fetched from the API
 }
 
-// CreateCard creates a Card document from a given CardDetail object.
+// CreateCard creates a Card document from a given CardDetail object, fetched
+// from the API just now.
 func CreateCard(cardDetail *rewards.CardDetail) *Card {
 	card := Card{
 		BaseDocument: &BaseDocument{},
 		CardDetail:   *cardDetail,
+		FetchedAt:    time.Now(),
 	}
 	card.SetID()
 	return &card
 }
 
+// MongoCardRepository is a CardRepository backed by a MongoDB collection.
+type MongoCardRepository struct {
+	store     MongoStore
+	revisions MongoStore
+}
+
+// NewMongoCardRepository returns a MongoCardRepository using the card and
+// card revision collections named in options.
+func NewMongoCardRepository(client *mongo.Client,
+	options MongoOptions) *MongoCardRepository {
+
+	return &MongoCardRepository{
+		store: GetStore(client, options, options.CardCollection),
+		revisions: GetStore(client, options,
+			options.CardRevisionCollection),
+	}
+}
+
 // InsertCard inserts a Card document into the cluster from a given CardDetail
 // object.
-func InsertCard(client *mongo.Client, cardDetail *rewards.CardDetail) (
-	*mongo.InsertOneResult, error) {
+func (repo *MongoCardRepository) InsertCard(ctx context.Context,
+	cardDetail *rewards.CardDetail) (*Card, error) {
 
 	card := CreateCard(cardDetail)
-	store := GetStore(client, CardCollection)
-	return store.InsertDocument(card)
+	if _, err := repo.store.InsertDocument(ctx, card); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, repo.revisions.Timeout)
+	defer cancel()
+	if err := repo.recordRevision(ctx, card); err != nil {
+		return nil, err
+	}
+
+	return card, nil
 }
 
 // GetCardsByKeys retrieves multiple Card documents based on a slice of unique
 // card keys.
-func GetCardsByKeys(client *mongo.Client, cardKeys []string) (map[string]*Card,
-	error) {
+func (repo *MongoCardRepository) GetCardsByKeys(ctx context.Context,
+	cardKeys []string) (map[string]*Card, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
 	defer cancel()
 
 	filter := bson.M{"card_detail.card_key": bson.M{"$in": cardKeys}}
 
-	store := GetStore(client, CardCollection)
-	cursor, err := store.Collection.Find(ctx, filter)
+	cursor, err := repo.store.Collection.Find(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve cards: %w", err)
+		return nil, fmt.Errorf("%w: failed to retrieve cards: %w",
+			ErrQuery, err)
 	}
 	defer cursor.Close(ctx)
 
@@ -59,14 +100,130 @@ func GetCardsByKeys(client *mongo.Client, cardKeys []string) (map[string]*Card,
 	for cursor.Next(ctx) {
 		var card Card
 		if err := cursor.Decode(&card); err != nil {
-			return nil, fmt.Errorf("failed to decode card: %w", err)
+			return nil, fmt.Errorf("%w: failed to decode card: %w",
+				ErrQuery, err)
 		}
 		cardMap[card.CardDetail.CardKey] = &card
 	}
 
 	if err := cursor.Err(); err != nil {
-		return nil, fmt.Errorf("cursor error: %w", err)
+		return nil, fmt.Errorf("%w: cursor error: %w", ErrQuery, err)
 	}
 
 	return cardMap, nil
 }
+
+// UpsertCard stores card in place of any card stored with the same card key,
+// keeping the stored card's ID and creation time. Duplicates of the card left
+// by InsertCard are removed.
+func (repo *MongoCardRepository) UpsertCard(ctx context.Context,
+	card *Card) (*Card, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
+	defer cancel()
+
+	cardKey := card.CardDetail.CardKey
+	byKey := bson.M{"card_detail.card_key": cardKey}
+
+	upserted := *card
+	var stored Card
+	err := repo.store.Collection.FindOne(ctx, byKey,
+		options.FindOne().SetSort(bson.M{"created_at": 1})).Decode(&stored)
+	switch {
+	case err == nil:
+		upserted.BaseDocument = stored.BaseDocument
+	case errors.Is(err, mongo.ErrNoDocuments):
+		upserted.BaseDocument = &BaseDocument{}
+		upserted.SetID()
+		upserted.SetCreatedAt()
+	default:
+		return nil, fmt.Errorf("%w: failed to find card: %w", ErrQuery, err)
+	}
+
+	_, err = repo.store.Collection.ReplaceOne(ctx,
+		bson.M{"_id": upserted.ID}, &upserted,
+		options.Replace().SetUpsert(true))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to upsert card: %w", ErrInsert,
+			err)
+	}
+
+	_, err = repo.store.Collection.DeleteMany(ctx, bson.M{
+		"card_detail.card_key": cardKey,
+		"_id":                  bson.M{"$ne": upserted.ID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to remove duplicate cards: %w",
+			ErrInsert, err)
+	}
+
+	if err := repo.recordRevision(ctx, &upserted); err != nil {
+		return nil, err
+	}
+
+	return &upserted, nil
+}
+
+// ListCards returns every stored card, one per card key, ordered by card key.
+// Of duplicate cards the most recently created is returned.
+func (repo *MongoCardRepository) ListCards(ctx context.Context) ([]*Card,
+	error) {
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
+	defer cancel()
+
+	cursor, err := repo.store.Collection.Find(ctx, bson.M{},
+		options.Find().SetSort(bson.D{
+			{Key: "card_detail.card_key", Value: 1},
+			{Key: "created_at", Value: 1},
+		}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to retrieve cards: %w",
+			ErrQuery, err)
+	}
+	defer cursor.Close(ctx)
+
+	var cards []*Card
+	for cursor.Next(ctx) {
+		var card Card
+		if err := cursor.Decode(&card); err != nil {
+			return nil, fmt.Errorf("%w: failed to decode card: %w",
+				ErrQuery, err)
+		}
+		last := len(cards) - 1
+		if last >= 0 &&
+			cards[last].CardDetail.CardKey == card.CardDetail.CardKey {
+			cards[last] = &card
+			continue
+		}
+		cards = append(cards, &card)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("%w: cursor error: %w", ErrQuery, err)
+	}
+
+	return cards, nil
+}
+
+// SetCardStatus sets the catalog status of every card stored with cardKey.
+func (repo *MongoCardRepository) SetCardStatus(ctx context.Context,
+	cardKey string, status string) error {
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
+	defer cancel()
+
+	result, err := repo.store.Collection.UpdateMany(ctx,
+		bson.M{"card_detail.card_key": cardKey},
+		bson.M{"$set": bson.M{"status": status}})
+	if err != nil {
+		return fmt.Errorf("%w: failed to update card status: %w", ErrInsert,
+			err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: no card found with key: %s", ErrNotFound,
+			cardKey)
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const CardRevisionCollection = "card_revision"
+
+// CardRevision is a version of a card's detail, first observed at ObservedAt.
+// It holds the card's terms until the next revision is observed.
+type CardRevision struct {
+	*BaseDocument `bson:",inline"`
+	CardKey       string             `bson:"card_key" json:"cardKey"`
+	Revision      int                `bson:"revision" json:"revision"` // 1 for the first
+	ObservedAt    time.Time          `bson:"observed_at" json:"observedAt"`
+	CardDetail    rewards.CardDetail `bson:"card_detail" json:"cardDetail"`
+}
+
+// nextCardRevision returns the revision to record for card given the latest
+// recorded revision of it, if any, or nil if the card's detail is unchanged.
+// The revision is observed when the card's detail was fetched, or else now.
+func nextCardRevision(latest *CardRevision, card *Card) *CardRevision {
+	number := 1
+	if latest != nil {
+		if len(rewards.ChangedFields(&latest.CardDetail,
+			&card.CardDetail)) == 0 {
+			return nil
+		}
+		number = latest.Revision + 1
+	}
+
+	observedAt := card.FetchedAt
+	if observedAt.IsZero() {
+		observedAt = time.Now()
+	}
+
+	revision := &CardRevision{
+		BaseDocument: &BaseDocument{},
+		CardKey:      card.CardDetail.CardKey,
+		Revision:     number,
+		ObservedAt:   observedAt,
+		CardDetail:   card.CardDetail,
+	}
+	revision.SetID()
+	revision.SetCreatedAt()
+	return revision
+}
+
+// maxRevisionAttempts bounds how many times recordRevision tries to record a
+// revision that concurrent writers keep taking the number of.
+const maxRevisionAttempts = 3
+
+// recordRevision records card as a new revision in the revision collection if
+// its detail differs from the latest revision. The unique index on card key
+// and revision number (see CreateMongoIndexes) rejects a revision another
+// writer recorded first under the same number, in which case it starts over
+// from the new latest revision.
+func (repo *MongoCardRepository) recordRevision(ctx context.Context,
+	card *Card) error {
+
+	for attempt := 1; ; attempt++ {
+		var latest *CardRevision
+		var stored CardRevision
+		err := repo.revisions.Collection.FindOne(ctx,
+			bson.M{"card_key": card.CardDetail.CardKey},
+			options.FindOne().SetSort(bson.M{"revision": -1})).Decode(&stored)
+		if err == nil {
+			latest = &stored
+		} else if !errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("%w: failed to find card revision: %w",
+				ErrQuery, err)
+		}
+
+		revision := nextCardRevision(latest, card)
+		if revision == nil {
+			return nil
+		}
+		_, err = repo.revisions.Collection.InsertOne(ctx, revision)
+		if mongo.IsDuplicateKeyError(err) && attempt < maxRevisionAttempts {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("%w: failed to insert card revision: %w",
+				ErrInsert, err)
+		}
+		return nil
+	}
+}
+
+// ListCardRevisions returns every recorded revision of the card with
+// cardKey, oldest first.
+func (repo *MongoCardRepository) ListCardRevisions(ctx context.Context,
+	cardKey string) ([]*CardRevision, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, repo.revisions.Timeout)
+	defer cancel()
+
+	cursor, err := repo.revisions.Collection.Find(ctx,
+		bson.M{"card_key": cardKey},
+		options.Find().SetSort(bson.M{"revision": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to retrieve card revisions: %w",
+			ErrQuery, err)
+	}
+
+	var revisions []*CardRevision
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode card revisions: %w",
+			ErrQuery, err)
+	}
+	return revisions, nil
+}
+
+// GetCardRevisionAt returns the revision of the card with cardKey that held
+// at time at: the latest one observed at or before it.
+func (repo *MongoCardRepository) GetCardRevisionAt(ctx context.Context,
+	cardKey string, at time.Time) (*CardRevision, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, repo.revisions.Timeout)
+	defer cancel()
+
+	var revision CardRevision
+	err := repo.revisions.Collection.FindOne(ctx,
+		bson.M{"card_key": cardKey, "observed_at": bson.M{"$lte": at}},
+		options.FindOne().SetSort(bson.M{"observed_at": -1})).
+		Decode(&revision)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: no revision of card %s at %s",
+				ErrNotFound, cardKey, at.Format(time.RFC3339))
+		}
+		return nil, fmt.Errorf("%w: failed to find card revision: %w",
+			ErrQuery, err)
+	}
+	return &revision, nil
+}
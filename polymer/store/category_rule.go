@@ -0,0 +1,255 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const CategoryRuleCollection = "category_rule"
+
+// How a CategoryRule matches a merchant.
+const (
+	RuleMatchExact    = "exact"    // Field equals Pattern
+	RuleMatchSuffix   = "suffix"   // Domain is Pattern or a subdomain of it
+	RuleMatchRegex    = "regex"    // Field matches the regexp Pattern
+	RuleMatchKeywords = "keywords" // Field contains any of Keywords
+)
+
+// The merchant field a CategoryRule matches.
+const (
+	RuleFieldDomain     = "domain"     // Normalized merchant domain
+	RuleFieldDescriptor = "descriptor" // Statement descriptor
+)
+
+// BaseCategoryRule assigns a spend category to the merchants it matches.
+// Rules are tried in order of Position, the rules of a wallet before those
+// shared by every wallet, and the first that matches decides the category.
+type BaseCategoryRule struct {
+	Name       string   `bson:"name" json:"name"`                             // Shown when the rule fires
+	WalletID   string   `bson:"wallet_id" json:"walletID,omitempty"`          // Wallet overridden, empty if shared
+	Position   int      `bson:"position" json:"position"`                     // Order within the wallet or shared rules
+	Match      string   `bson:"match" json:"match"`                           // One of the RuleMatch kinds
+	Field      string   `bson:"field" json:"field,omitempty"`                 // One of the RuleField names
+	Pattern    string   `bson:"pattern,omitempty" json:"pattern,omitempty"`   // For exact, suffix and regex rules
+	Keywords   []string `bson:"keywords,omitempty" json:"keywords,omitempty"` // For keywords rules
+	CategoryID int      `bson:"category_id" json:"categoryID"`
+
+	// Compiled Pattern of a regex rule, set when the rule is created or
+	// read; nil for other rules and for a pattern that does not compile
+	Regexp *regexp.Regexp `bson:"-" json:"-"`
+}
+
+// CompileRulePattern compiles the pattern of a regex rule, which matches
+// regardless of case.
+func CompileRulePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// compilePattern sets the Regexp of a regex rule from its Pattern, leaving
+// it nil if the pattern does not compile.
+func (rule *BaseCategoryRule) compilePattern() {
+	rule.Regexp = nil
+	if rule.Match == RuleMatchRegex {
+		rule.Regexp, _ = CompileRulePattern(rule.Pattern)
+	}
+}
+
+// CategoryRule represents the structure of a category rule document in
+// MongoDB.
+type CategoryRule struct {
+	*BaseDocument     `bson:",inline"`
+	*BaseCategoryRule `bson:",inline"`
+}
+
+// CreateCategoryRule creates a CategoryRule document from the given
+// baseRule.
+func CreateCategoryRule(baseRule *BaseCategoryRule) CategoryRule {
+	rule := CategoryRule{
+		BaseDocument:     &BaseDocument{},
+		BaseCategoryRule: baseRule,
+	}
+	rule.compilePattern()
+	rule.SetID()
+	return rule
+}
+
+// sortCategoryRules sorts rules in the order they are tried: wallet rules
+// before shared ones, then by position and creation time.
+func sortCategoryRules(rules []*CategoryRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if (a.WalletID == "") != (b.WalletID == "") {
+			return a.WalletID != ""
+		}
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		return a.CreatedAt < b.CreatedAt
+	})
+}
+
+// MongoCategoryRuleRepository is a CategoryRuleRepository backed by a
+// MongoDB collection.
+type MongoCategoryRuleRepository struct {
+	store MongoStore
+}
+
+// NewMongoCategoryRuleRepository returns a MongoCategoryRuleRepository using
+// the category rule collection named in options.
+func NewMongoCategoryRuleRepository(client *mongo.Client,
+	options MongoOptions) *MongoCategoryRuleRepository {
+
+	store := GetStore(client, options, options.CategoryRuleCollection)
+	return &MongoCategoryRuleRepository{store: store}
+}
+
+// InsertCategoryRule inserts a CategoryRule document after the other rules
+// of its wallet, or the other shared rules.
+func (repo *MongoCategoryRuleRepository) InsertCategoryRule(
+	ctx context.Context, baseRule *BaseCategoryRule) (*CategoryRule, error) {
+
+	rules, err := repo.find(ctx, bson.M{"wallet_id": baseRule.WalletID})
+	if err != nil {
+		return nil, err
+	}
+
+	base := *baseRule
+	base.Position = nextRulePosition(rules)
+	rule := CreateCategoryRule(&base)
+	if _, err := repo.store.InsertDocument(ctx, rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ReplaceCategoryRules replaces every shared CategoryRule document with
+// baseRules, positioned in the order given.
+func (repo *MongoCategoryRuleRepository) ReplaceCategoryRules(
+	ctx context.Context, baseRules []*BaseCategoryRule) ([]*CategoryRule,
+	error) {
+
+	rules := sharedCategoryRules(baseRules)
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
+	defer cancel()
+
+	_, err := repo.store.Collection.DeleteMany(ctx, bson.M{"wallet_id": ""})
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to remove category rules: %w",
+			ErrInsert, err)
+	}
+	if len(rules) == 0 {
+		return rules, nil
+	}
+
+	documents := make([]any, len(rules))
+	for i, rule := range rules {
+		documents[i] = rule
+	}
+	if _, err := repo.store.Collection.InsertMany(ctx, documents); err != nil {
+		return nil, fmt.Errorf("%w: failed to insert category rules: %w",
+			ErrInsert, err)
+	}
+	return rules, nil
+}
+
+// ListCategoryRules retrieves the CategoryRule documents of the wallet with
+// walletID and the shared ones, in the order they are tried.
+func (repo *MongoCategoryRuleRepository) ListCategoryRules(
+	ctx context.Context, walletID string) ([]*CategoryRule, error) {
+
+	rules, err := repo.find(ctx,
+		bson.M{"wallet_id": bson.M{"$in": []string{"", walletID}}})
+	if err != nil {
+		return nil, err
+	}
+	sortCategoryRules(rules)
+	return rules, nil
+}
+
+// DeleteCategoryRule deletes the CategoryRule document with the given ID.
+func (repo *MongoCategoryRuleRepository) DeleteCategoryRule(
+	ctx context.Context, id string) error {
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: no category rule found with id: %s",
+			ErrNotFound, id)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
+	defer cancel()
+
+	result, err := repo.store.Collection.DeleteOne(ctx,
+		bson.M{"_id": objectID})
+	if err != nil {
+		return fmt.Errorf("%w: failed to delete category rule: %w",
+			ErrInsert, err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("%w: no category rule found with id: %s",
+			ErrNotFound, id)
+	}
+	return nil
+}
+
+// find retrieves the CategoryRule documents matching filter.
+func (repo *MongoCategoryRuleRepository) find(ctx context.Context,
+	filter bson.M) ([]*CategoryRule, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
+	defer cancel()
+
+	cursor, err := repo.store.Collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to retrieve category rules: %w",
+			ErrQuery, err)
+	}
+	defer cursor.Close(ctx)
+
+	var rules []*CategoryRule
+	for cursor.Next(ctx) {
+		var rule CategoryRule
+		if err := cursor.Decode(&rule); err != nil {
+			return nil, fmt.Errorf("%w: failed to decode category rule: %w",
+				ErrQuery, err)
+		}
+		rule.compilePattern()
+		rules = append(rules, &rule)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("%w: cursor error: %w", ErrQuery, err)
+	}
+
+	return rules, nil
+}
+
+// nextRulePosition returns the position following every rule in rules.
+func nextRulePosition(rules []*CategoryRule) int {
+	position := 0
+	for _, rule := range rules {
+		position = max(position, rule.Position+1)
+	}
+	return position
+}
+
+// sharedCategoryRules creates the shared CategoryRule documents to store in
+// place of the shared rules, positioned in the order of baseRules.
+func sharedCategoryRules(baseRules []*BaseCategoryRule) []*CategoryRule {
+	rules := make([]*CategoryRule, len(baseRules))
+	for i, baseRule := range baseRules {
+		base := *baseRule
+		base.WalletID = ""
+		base.Position = i
+		rule := CreateCategoryRule(&base)
+		rule.SetCreatedAt()
+		rules[i] = &rule
+	}
+	return rules
+}
@@ -2,19 +2,30 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const DomainCollection = "domain"
 
+// UncategorizedID is the category ID of a merchant domain with no known
+// spend category. The zero CategoryID is not it, so a domain without a
+// category must be given UncategorizedID explicitly.
+const UncategorizedID = -1
+
 type BaseDomain struct {
-	Name         string `bson:"name"` // e.g. amazon.com
-	CategoryID   int    `bson:"category"`
-	CategoryName string `bson:"category"`
+	Name         string `bson:"name" json:"name"`              // e.g. amazon.com
+	CategoryID   int    `bson:"category_id" json:"categoryID"` // UncategorizedID if none
+	CategoryName string `bson:"category_name" json:"categoryName"`
+	MCC          string `bson:"mcc,omitempty" json:"mcc,omitempty"` // ISO 18245 merchant category code, if known
 }
 
 // Domain represents the structure of a domain document in MongoDB.
@@ -33,39 +44,282 @@ func CreateDomain(baseDomain *BaseDomain) Domain {
 	return domain
 }
 
+// DomainFilter selects stored domains, ordered by name, and the page of them
+// to return. Zero valued fields match every domain.
+type DomainFilter struct {
+	CategoryIDs []int // Category, any of; UncategorizedID for uncategorized
+	Offset      int   // Matching domains skipped
+	Limit       int   // Most domains returned, 0 for no limit
+}
+
+// Matches reports whether domain is selected by the filter, regardless of
+// the page.
+func (filter *DomainFilter) Matches(domain *BaseDomain) bool {
+	return len(filter.CategoryIDs) == 0 ||
+		slices.Contains(filter.CategoryIDs, domain.CategoryID)
+}
+
+// page returns the page of domains, sorted by name, that the filter selects.
+func (filter *DomainFilter) page(domains []*Domain) []*Domain {
+	if filter.Offset >= len(domains) {
+		return nil
+	}
+	domains = domains[max(filter.Offset, 0):]
+	if filter.Limit > 0 && filter.Limit < len(domains) {
+		domains = domains[:filter.Limit]
+	}
+	return domains
+}
+
+// sortDomains sorts domains by name.
+func sortDomains(domains []*Domain) {
+	sort.Slice(domains, func(i, j int) bool {
+		return domains[i].Name < domains[j].Name
+	})
+}
+
+// migrateMongoDomains moves the category of domains stored under the former
+// category field to category_id or category_name, as it is a number or a
+// name, and marks those left without a category ID as uncategorized.
+func migrateMongoDomains(ctx context.Context,
+	collection *mongo.Collection) error {
+
+	updates := []struct {
+		filter bson.M
+		update bson.M
+	}{
+		{
+			bson.M{"category": bson.M{"$type": "number"},
+				"category_id": bson.M{"$exists": false}},
+			bson.M{"$rename": bson.M{"category": "category_id"}},
+		},
+		{
+			bson.M{"category": bson.M{"$type": "string"},
+				"category_name": bson.M{"$exists": false}},
+			bson.M{"$rename": bson.M{"category": "category_name"}},
+		},
+		{
+			bson.M{"category_id": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"category_id": UncategorizedID}},
+		},
+		{
+			bson.M{"category_name": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"category_name": ""}},
+		},
+	}
+	for _, update := range updates {
+		_, err := collection.UpdateMany(ctx, update.filter, update.update)
+		if err != nil {
+			return fmt.Errorf("failed to migrate domain categories: %w", err)
+		}
+	}
+	return nil
+}
+
+// MongoDomainRepository is a DomainRepository backed by a MongoDB collection.
+type MongoDomainRepository struct {
+	store   MongoStore
+	aliases MongoStore
+}
+
+// NewMongoDomainRepository returns a MongoDomainRepository using the domain
+// and domain alias collections named in options.
+func NewMongoDomainRepository(client *mongo.Client,
+	options MongoOptions) *MongoDomainRepository {
+
+	return &MongoDomainRepository{
+		store: GetStore(client, options, options.DomainCollection),
+		aliases: GetStore(client, options,
+			options.DomainAliasCollection),
+	}
+}
+
 // InsertDomain inserts a new Domain document into the MongoDB collection.
-func InsertDomain(client *mongo.Client, baseDomain *BaseDomain) (
-	*mongo.InsertOneResult, error) {
+func (repo *MongoDomainRepository) InsertDomain(ctx context.Context,
+	baseDomain *BaseDomain) (*Domain, error) {
 
 	domain := CreateDomain(baseDomain)
-	store := GetStore(client, DomainCollection)
-	return store.InsertDocument(domain)
+	if _, err := repo.store.InsertDocument(ctx, domain); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("%w: domain already exists: %s",
+				ErrDuplicate, baseDomain.Name)
+		}
+		return nil, err
+	}
+	return &domain, nil
 }
 
-// GetDomainByKey retrieves a Domain document by its unique name.
-func GetDomainByName(client *mongo.Client, name string) (*Domain, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// GetDomainByName retrieves a Domain document by its unique name.
+func (repo *MongoDomainRepository) GetDomainByName(ctx context.Context,
+	name string) (*Domain, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
 	defer cancel()
 
 	filter := bson.M{"name": name}
 
-	store := GetStore(client, DomainCollection)
-	count, err := store.Collection.CountDocuments(ctx, filter)
+	var domain Domain
+	err := repo.store.Collection.FindOne(ctx, filter).Decode(&domain)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check if domain name exists: %w", err)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: no domain found with name: %s",
+				ErrNotFound, name)
+		}
+		return nil, fmt.Errorf("%w: failed to find domain: %w", ErrQuery, err)
+	}
+
+	return &domain, nil
+}
+
+// UpdateDomain replaces the Domain document with the ID of domain.
+func (repo *MongoDomainRepository) UpdateDomain(ctx context.Context,
+	domain *Domain) error {
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
+	defer cancel()
+
+	result, err := repo.store.Collection.ReplaceOne(ctx,
+		bson.M{"_id": domain.ID}, domain)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: domain already exists: %s", ErrDuplicate,
+			domain.Name)
+	} else if err != nil {
+		return fmt.Errorf("%w: failed to update domain: %w", ErrInsert, err)
 	}
-	if count == 0 {
-		return nil, fmt.Errorf("domain name does not exist: %s", name)
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: no domain found with id: %s", ErrNotFound,
+			domain.ID.Hex())
 	}
 
-	var domain Domain
-	err = store.Collection.FindOne(ctx, filter).Decode(&domain)
+	return nil
+}
+
+// DeleteDomain deletes the Domain document with the given name.
+func (repo *MongoDomainRepository) DeleteDomain(ctx context.Context,
+	name string) error {
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
+	defer cancel()
+
+	result, err := repo.store.Collection.DeleteOne(ctx, bson.M{"name": name})
+	if err != nil {
+		return fmt.Errorf("%w: failed to delete domain: %w", ErrInsert, err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("%w: no domain found with name: %s", ErrNotFound,
+			name)
+	}
+	return nil
+}
+
+// ListDomains retrieves the Domain documents selected by filter, ordered by
+// name.
+func (repo *MongoDomainRepository) ListDomains(ctx context.Context,
+	filter *DomainFilter) ([]*Domain, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
+	defer cancel()
+
+	query := bson.M{}
+	if len(filter.CategoryIDs) > 0 {
+		query["category_id"] = bson.M{"$in": filter.CategoryIDs}
+	}
+	findOptions := options.Find().SetSort(bson.M{"name": 1})
+	if filter.Offset > 0 {
+		findOptions.SetSkip(int64(filter.Offset))
+	}
+	if filter.Limit > 0 {
+		findOptions.SetLimit(int64(filter.Limit))
+	}
+
+	cursor, err := repo.store.Collection.Find(ctx, query, findOptions)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("no domain found with name: %s", name)
+		return nil, fmt.Errorf("%w: failed to retrieve domains: %w",
+			ErrQuery, err)
+	}
+	defer cursor.Close(ctx)
+
+	var domains []*Domain
+	for cursor.Next(ctx) {
+		var domain Domain
+		if err := cursor.Decode(&domain); err != nil {
+			return nil, fmt.Errorf("%w: failed to decode domain: %w",
+				ErrQuery, err)
 		}
-		return nil, fmt.Errorf("failed to find domain: %w", err)
+		domains = append(domains, &domain)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("%w: cursor error: %w", ErrQuery, err)
 	}
 
-	return &domain, nil
+	return domains, nil
+}
+
+// upsertDomainUpdate returns the update UpsertDomains makes to the domain
+// named as baseDomain. Every field is set, and an empty MCC unset rather
+// than left as it was, so the stored domain ends up as baseDomain as in the
+// other backends.
+func upsertDomainUpdate(baseDomain *BaseDomain) bson.M {
+	set := bson.M{
+		"name":          baseDomain.Name,
+		"category_id":   baseDomain.CategoryID,
+		"category_name": baseDomain.CategoryName,
+	}
+	update := bson.M{
+		"$set": set,
+		"$setOnInsert": bson.M{
+			"_id":        primitive.NewObjectID(),
+			"created_at": primitive.NewDateTimeFromTime(time.Now()),
+		},
+	}
+	if baseDomain.MCC == "" {
+		update["$unset"] = bson.M{"mcc": ""}
+	} else {
+		set["mcc"] = baseDomain.MCC
+	}
+	return update
+}
+
+// UpsertDomains stores each of baseDomains in place of the Domain document
+// with the same name, or as a new document, in a single bulk write.
+func (repo *MongoDomainRepository) UpsertDomains(ctx context.Context,
+	baseDomains []*BaseDomain) ([]*Domain, error) {
+
+	if len(baseDomains) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
+	defer cancel()
+
+	models := make([]mongo.WriteModel, len(baseDomains))
+	names := make([]string, len(baseDomains))
+	for i, baseDomain := range baseDomains {
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"name": baseDomain.Name}).
+			SetUpdate(upsertDomainUpdate(baseDomain)).
+			SetUpsert(true)
+		names[i] = baseDomain.Name
+	}
+	_, err := repo.store.Collection.BulkWrite(ctx, models,
+		options.BulkWrite().SetOrdered(true))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to upsert domains: %w", ErrInsert,
+			err)
+	}
+
+	cursor, err := repo.store.Collection.Find(ctx,
+		bson.M{"name": bson.M{"$in": names}},
+		options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to retrieve domains: %w",
+			ErrQuery, err)
+	}
+	var domains []*Domain
+	if err := cursor.All(ctx, &domains); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode domains: %w", ErrQuery,
+			err)
+	}
+
+	return domains, nil
 }
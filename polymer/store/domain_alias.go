@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const DomainAliasCollection = "domain_alias"
+
+// DomainAlias maps a domain name, such as amazon.co.uk, to the name of the
+// canonical merchant domain it belongs to, such as amazon.com.
+type DomainAlias struct {
+	*BaseDocument `bson:",inline"`
+	Alias         string `bson:"alias" json:"alias"`
+	Domain        string `bson:"domain" json:"domain"`
+}
+
+// newDomainAlias returns a new DomainAlias mapping alias to domain.
+func newDomainAlias(alias, domain string) *DomainAlias {
+	domainAlias := &DomainAlias{
+		BaseDocument: &BaseDocument{},
+		Alias:        alias,
+		Domain:       domain,
+	}
+	domainAlias.SetID()
+	domainAlias.SetCreatedAt()
+	return domainAlias
+}
+
+// SetDomainAlias maps alias to domain in the alias collection, replacing the
+// domain it was mapped to, if any.
+func (repo *MongoDomainRepository) SetDomainAlias(ctx context.Context,
+	alias, domain string) (*DomainAlias, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, repo.aliases.Timeout)
+	defer cancel()
+
+	upserted := newDomainAlias(alias, domain)
+	var stored DomainAlias
+	err := repo.aliases.Collection.FindOne(ctx,
+		bson.M{"alias": alias}).Decode(&stored)
+	if err == nil {
+		upserted.BaseDocument = stored.BaseDocument
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("%w: failed to find domain alias: %w",
+			ErrQuery, err)
+	}
+
+	_, err = repo.aliases.Collection.ReplaceOne(ctx,
+		bson.M{"_id": upserted.ID}, upserted,
+		options.Replace().SetUpsert(true))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to upsert domain alias: %w",
+			ErrInsert, err)
+	}
+
+	return upserted, nil
+}
+
+// GetDomainAlias retrieves the DomainAlias document for alias.
+func (repo *MongoDomainRepository) GetDomainAlias(ctx context.Context,
+	alias string) (*DomainAlias, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, repo.aliases.Timeout)
+	defer cancel()
+
+	var domainAlias DomainAlias
+	err := repo.aliases.Collection.FindOne(ctx,
+		bson.M{"alias": alias}).Decode(&domainAlias)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: no domain alias found for: %s",
+				ErrNotFound, alias)
+		}
+		return nil, fmt.Errorf("%w: failed to find domain alias: %w",
+			ErrQuery, err)
+	}
+
+	return &domainAlias, nil
+}
+
+// ListDomainAliases returns every DomainAlias document, ordered by alias.
+func (repo *MongoDomainRepository) ListDomainAliases(
+	ctx context.Context) ([]*DomainAlias, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, repo.aliases.Timeout)
+	defer cancel()
+
+	cursor, err := repo.aliases.Collection.Find(ctx, bson.M{},
+		options.Find().SetSort(bson.M{"alias": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to retrieve domain aliases: %w",
+			ErrQuery, err)
+	}
+	defer cursor.Close(ctx)
+
+	var aliases []*DomainAlias
+	for cursor.Next(ctx) {
+		var domainAlias DomainAlias
+		if err := cursor.Decode(&domainAlias); err != nil {
+			return nil, fmt.Errorf("%w: failed to decode domain alias: %w",
+				ErrQuery, err)
+		}
+		aliases = append(aliases, &domainAlias)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("%w: cursor error: %w", ErrQuery, err)
+	}
+
+	return aliases, nil
+}
@@ -0,0 +1,19 @@
+package store
+
+import "errors"
+
+var (
+	// ErrNotFound is returned when no stored document matches a lookup.
+	ErrNotFound = errors.New("document not found")
+
+	// ErrInsert is returned when a document could not be stored, updated or
+	// deleted.
+	ErrInsert = errors.New("failed to insert document")
+
+	// ErrDuplicate is returned when a document would take the unique name
+	// of another stored document.
+	ErrDuplicate = errors.New("duplicate document")
+
+	// ErrQuery is returned when stored documents could not be read.
+	ErrQuery = errors.New("failed to query documents")
+)
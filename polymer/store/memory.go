@@ -0,0 +1,799 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+)
+
+// MemoryCardRepository is a CardRepository that keeps cards in memory, keyed by
+// card key.
+type MemoryCardRepository struct {
+	mu        sync.RWMutex
+	cards     map[string]*Card
+	revisions map[string][]*CardRevision // By card key, oldest first
+}
+
+// NewMemoryCardRepository returns an empty MemoryCardRepository.
+func NewMemoryCardRepository() *MemoryCardRepository {
+	return &MemoryCardRepository{
+		cards:     make(map[string]*Card),
+		revisions: make(map[string][]*CardRevision),
+	}
+}
+
+// InsertCard stores a Card document created from the given CardDetail,
+// replacing any card previously stored under the same card key.
+func (repo *MemoryCardRepository) InsertCard(ctx context.Context,
+	cardDetail *rewards.CardDetail) (*Card, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	card := CreateCard(cardDetail)
+	card.SetCreatedAt()
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.cards[card.CardDetail.CardKey] = card
+	repo.recordRevision(card)
+
+	stored := *card
+	return &stored, nil
+}
+
+// GetCardsByKeys retrieves the stored cards matching the given card keys.
+// Keys without a stored card are absent from the returned map.
+func (repo *MemoryCardRepository) GetCardsByKeys(ctx context.Context,
+	cardKeys []string) (map[string]*Card, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	cardMap := make(map[string]*Card)
+	for _, cardKey := range cardKeys {
+		if card, ok := repo.cards[cardKey]; ok {
+			stored := *card
+			cardMap[cardKey] = &stored
+		}
+	}
+
+	return cardMap, nil
+}
+
+// UpsertCard stores card in place of any card stored with the same card key,
+// keeping the stored card's ID and creation time.
+func (repo *MemoryCardRepository) UpsertCard(ctx context.Context,
+	card *Card) (*Card, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	upserted := *card
+	if stored, ok := repo.cards[card.CardDetail.CardKey]; ok {
+		upserted.BaseDocument = stored.BaseDocument
+	} else {
+		upserted.BaseDocument = &BaseDocument{}
+		upserted.SetID()
+		upserted.SetCreatedAt()
+	}
+	repo.cards[card.CardDetail.CardKey] = &upserted
+	repo.recordRevision(&upserted)
+
+	stored := upserted
+	return &stored, nil
+}
+
+// ListCards returns every stored card ordered by card key.
+func (repo *MemoryCardRepository) ListCards(ctx context.Context) ([]*Card,
+	error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	cards := make([]*Card, 0, len(repo.cards))
+	for _, card := range repo.cards {
+		stored := *card
+		cards = append(cards, &stored)
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		return cards[i].CardDetail.CardKey < cards[j].CardDetail.CardKey
+	})
+
+	return cards, nil
+}
+
+// SetCardStatus sets the catalog status of the card with cardKey.
+func (repo *MemoryCardRepository) SetCardStatus(ctx context.Context,
+	cardKey string, status string) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	card, ok := repo.cards[cardKey]
+	if !ok {
+		return fmt.Errorf("%w: no card found with key: %s", ErrNotFound,
+			cardKey)
+	}
+	updated := *card
+	updated.Status = status
+	repo.cards[cardKey] = &updated
+
+	return nil
+}
+
+// recordRevision records card as a new revision if its detail differs from
+// the latest revision. The caller must hold the write lock.
+func (repo *MemoryCardRepository) recordRevision(card *Card) {
+	cardKey := card.CardDetail.CardKey
+	revisions := repo.revisions[cardKey]
+
+	var latest *CardRevision
+	if len(revisions) > 0 {
+		latest = revisions[len(revisions)-1]
+	}
+	if revision := nextCardRevision(latest, card); revision != nil {
+		repo.revisions[cardKey] = append(revisions, revision)
+	}
+}
+
+// ListCardRevisions returns every recorded revision of the card with
+// cardKey, oldest first.
+func (repo *MemoryCardRepository) ListCardRevisions(ctx context.Context,
+	cardKey string) ([]*CardRevision, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	revisions := make([]*CardRevision, 0, len(repo.revisions[cardKey]))
+	for _, revision := range repo.revisions[cardKey] {
+		stored := *revision
+		revisions = append(revisions, &stored)
+	}
+	return revisions, nil
+}
+
+// GetCardRevisionAt returns the revision of the card with cardKey that held
+// at time at: the latest one observed at or before it.
+func (repo *MemoryCardRepository) GetCardRevisionAt(ctx context.Context,
+	cardKey string, at time.Time) (*CardRevision, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var found *CardRevision
+	for _, revision := range repo.revisions[cardKey] {
+		if revision.ObservedAt.After(at) {
+			continue
+		}
+		if found == nil || !revision.ObservedAt.Before(found.ObservedAt) {
+			found = revision
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: no revision of card %s at %s",
+			ErrNotFound, cardKey, at.Format(time.RFC3339))
+	}
+
+	stored := *found
+	return &stored, nil
+}
+
+// MemoryDomainRepository is a DomainRepository that keeps domains in memory,
+// keyed by name, and their aliases keyed by alias.
+type MemoryDomainRepository struct {
+	mu      sync.RWMutex
+	domains map[string]*Domain
+	aliases map[string]*DomainAlias
+}
+
+// NewMemoryDomainRepository returns an empty MemoryDomainRepository.
+func NewMemoryDomainRepository() *MemoryDomainRepository {
+	return &MemoryDomainRepository{
+		domains: make(map[string]*Domain),
+		aliases: make(map[string]*DomainAlias),
+	}
+}
+
+// InsertDomain stores a Domain document created from the given baseDomain.
+func (repo *MemoryDomainRepository) InsertDomain(ctx context.Context,
+	baseDomain *BaseDomain) (*Domain, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	base := *baseDomain
+	domain := CreateDomain(&base)
+	domain.SetCreatedAt()
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if _, ok := repo.domains[domain.Name]; ok {
+		return nil, fmt.Errorf("%w: domain already exists: %s", ErrDuplicate,
+			domain.Name)
+	}
+	repo.domains[domain.Name] = &domain
+
+	return copyDomain(&domain), nil
+}
+
+// GetDomainByName retrieves a Domain document by its unique name.
+func (repo *MemoryDomainRepository) GetDomainByName(ctx context.Context,
+	name string) (*Domain, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	domain, ok := repo.domains[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: no domain found with name: %s",
+			ErrNotFound, name)
+	}
+
+	return copyDomain(domain), nil
+}
+
+// UpdateDomain replaces the Domain document with the ID of domain.
+func (repo *MemoryDomainRepository) UpdateDomain(ctx context.Context,
+	domain *Domain) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	var stored *Domain
+	for _, candidate := range repo.domains {
+		if candidate.ID == domain.ID {
+			stored = candidate
+			break
+		}
+	}
+	if stored == nil {
+		return fmt.Errorf("%w: no domain found with id: %s", ErrNotFound,
+			domain.ID.Hex())
+	}
+	if other, ok := repo.domains[domain.Name]; ok && other != stored {
+		return fmt.Errorf("%w: domain already exists: %s", ErrDuplicate,
+			domain.Name)
+	}
+	delete(repo.domains, stored.Name)
+	repo.domains[domain.Name] = copyDomain(domain)
+
+	return nil
+}
+
+// DeleteDomain deletes the Domain document with the given name.
+func (repo *MemoryDomainRepository) DeleteDomain(ctx context.Context,
+	name string) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.domains[name]; !ok {
+		return fmt.Errorf("%w: no domain found with name: %s", ErrNotFound,
+			name)
+	}
+	delete(repo.domains, name)
+	return nil
+}
+
+// ListDomains retrieves the Domain documents selected by filter, ordered by
+// name.
+func (repo *MemoryDomainRepository) ListDomains(ctx context.Context,
+	filter *DomainFilter) ([]*Domain, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var domains []*Domain
+	for _, domain := range repo.domains {
+		if filter.Matches(domain.BaseDomain) {
+			domains = append(domains, copyDomain(domain))
+		}
+	}
+	sortDomains(domains)
+
+	return filter.page(domains), nil
+}
+
+// UpsertDomains stores each of baseDomains in place of the Domain document
+// with the same name, keeping its ID and creation time, or as a new document.
+func (repo *MemoryDomainRepository) UpsertDomains(ctx context.Context,
+	baseDomains []*BaseDomain) ([]*Domain, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, baseDomain := range baseDomains {
+		base := *baseDomain
+		upserted := CreateDomain(&base)
+		upserted.SetCreatedAt()
+		if stored, ok := repo.domains[base.Name]; ok {
+			upserted.BaseDocument = stored.BaseDocument
+		}
+		repo.domains[base.Name] = &upserted
+	}
+
+	var domains []*Domain
+	seen := make(map[string]bool)
+	for _, baseDomain := range baseDomains {
+		if !seen[baseDomain.Name] {
+			seen[baseDomain.Name] = true
+			domains = append(domains, copyDomain(repo.domains[baseDomain.Name]))
+		}
+	}
+	sortDomains(domains)
+
+	return domains, nil
+}
+
+// copyDomain returns a copy of domain that shares no mutable state with it.
+func copyDomain(domain *Domain) *Domain {
+	document := *domain.BaseDocument
+	base := *domain.BaseDomain
+	return &Domain{BaseDocument: &document, BaseDomain: &base}
+}
+
+// SetDomainAlias maps alias to domain, replacing the domain it was mapped
+// to, if any.
+func (repo *MemoryDomainRepository) SetDomainAlias(ctx context.Context,
+	alias, domain string) (*DomainAlias, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	upserted := newDomainAlias(alias, domain)
+	if stored, ok := repo.aliases[alias]; ok {
+		upserted.BaseDocument = stored.BaseDocument
+	}
+	repo.aliases[alias] = upserted
+
+	stored := *upserted
+	return &stored, nil
+}
+
+// GetDomainAlias retrieves the DomainAlias document for alias.
+func (repo *MemoryDomainRepository) GetDomainAlias(ctx context.Context,
+	alias string) (*DomainAlias, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	domainAlias, ok := repo.aliases[alias]
+	if !ok {
+		return nil, fmt.Errorf("%w: no domain alias found for: %s",
+			ErrNotFound, alias)
+	}
+
+	stored := *domainAlias
+	return &stored, nil
+}
+
+// ListDomainAliases returns every DomainAlias document, ordered by alias.
+func (repo *MemoryDomainRepository) ListDomainAliases(
+	ctx context.Context) ([]*DomainAlias, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	aliases := make([]*DomainAlias, 0, len(repo.aliases))
+	for _, domainAlias := range repo.aliases {
+		stored := *domainAlias
+		aliases = append(aliases, &stored)
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].Alias < aliases[j].Alias
+	})
+
+	return aliases, nil
+}
+
+// MemoryTransactionRepository is a TransactionRepository that keeps
+// transactions in memory in insertion order.
+type MemoryTransactionRepository struct {
+	mu           sync.RWMutex
+	transactions []*Transaction
+}
+
+// NewMemoryTransactionRepository returns an empty MemoryTransactionRepository.
+func NewMemoryTransactionRepository() *MemoryTransactionRepository {
+	return &MemoryTransactionRepository{}
+}
+
+// InsertTransaction stores a Transaction document created from the given
+// baseTransaction.
+func (repo *MemoryTransactionRepository) InsertTransaction(
+	ctx context.Context, baseTransaction *BaseTransaction) (*Transaction,
+	error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	transaction := CreateTransaction(copyBaseTransaction(baseTransaction))
+	transaction.SetCreatedAt()
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.transactions = append(repo.transactions, &transaction)
+
+	return &Transaction{
+		BaseDocument:    transaction.BaseDocument,
+		BaseTransaction: copyBaseTransaction(transaction.BaseTransaction),
+	}, nil
+}
+
+// copyBaseTransaction returns a copy of transaction that shares no mutable
+// state with it.
+func copyBaseTransaction(transaction *BaseTransaction) *BaseTransaction {
+	base := *transaction
+	if base.RecommendedCard != nil {
+		recommended := *base.RecommendedCard
+		base.RecommendedCard = &recommended
+	}
+	return &base
+}
+
+// ListTransactions retrieves the stored transactions selected by filter,
+// oldest first.
+func (repo *MemoryTransactionRepository) ListTransactions(
+	ctx context.Context, filter *TransactionFilter) ([]*Transaction, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var transactions []*Transaction
+	for _, transaction := range repo.transactions {
+		if filter.Matches(transaction.BaseTransaction) {
+			base := copyBaseTransaction(transaction.BaseTransaction)
+			transactions = append(transactions, &Transaction{
+				BaseDocument:    transaction.BaseDocument,
+				BaseTransaction: base,
+			})
+		}
+	}
+
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].TransactionAt.Before(
+			transactions[j].TransactionAt)
+	})
+
+	return transactions, nil
+}
+
+// MemoryCategoryRuleRepository is a CategoryRuleRepository that keeps rules
+// in memory, in insertion order.
+type MemoryCategoryRuleRepository struct {
+	mu    sync.RWMutex
+	rules []*CategoryRule
+}
+
+// NewMemoryCategoryRuleRepository returns an empty
+// MemoryCategoryRuleRepository.
+func NewMemoryCategoryRuleRepository() *MemoryCategoryRuleRepository {
+	return &MemoryCategoryRuleRepository{}
+}
+
+// InsertCategoryRule stores a CategoryRule document created from the given
+// baseRule, after the other rules of its wallet, or the other shared rules.
+func (repo *MemoryCategoryRuleRepository) InsertCategoryRule(
+	ctx context.Context, baseRule *BaseCategoryRule) (*CategoryRule, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	var scope []*CategoryRule
+	for _, rule := range repo.rules {
+		if rule.WalletID == baseRule.WalletID {
+			scope = append(scope, rule)
+		}
+	}
+
+	base := *baseRule
+	base.Position = nextRulePosition(scope)
+	rule := CreateCategoryRule(&base)
+	rule.SetCreatedAt()
+	repo.rules = append(repo.rules, &rule)
+
+	return copyCategoryRule(&rule), nil
+}
+
+// ReplaceCategoryRules replaces every shared CategoryRule document with
+// baseRules, positioned in the order given.
+func (repo *MemoryCategoryRuleRepository) ReplaceCategoryRules(
+	ctx context.Context, baseRules []*BaseCategoryRule) ([]*CategoryRule,
+	error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rules := sharedCategoryRules(baseRules)
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	var kept []*CategoryRule
+	for _, rule := range repo.rules {
+		if rule.WalletID != "" {
+			kept = append(kept, rule)
+		}
+	}
+	for _, rule := range rules {
+		kept = append(kept, copyCategoryRule(rule))
+	}
+	repo.rules = kept
+
+	return rules, nil
+}
+
+// ListCategoryRules retrieves the CategoryRule documents of the wallet with
+// walletID and the shared ones, in the order they are tried.
+func (repo *MemoryCategoryRuleRepository) ListCategoryRules(
+	ctx context.Context, walletID string) ([]*CategoryRule, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var rules []*CategoryRule
+	for _, rule := range repo.rules {
+		if rule.WalletID == "" || rule.WalletID == walletID {
+			rules = append(rules, copyCategoryRule(rule))
+		}
+	}
+	sortCategoryRules(rules)
+
+	return rules, nil
+}
+
+// DeleteCategoryRule deletes the CategoryRule document with the given ID.
+func (repo *MemoryCategoryRuleRepository) DeleteCategoryRule(
+	ctx context.Context, id string) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for i, rule := range repo.rules {
+		if rule.ID.Hex() == id {
+			repo.rules = append(repo.rules[:i:i], repo.rules[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no category rule found with id: %s", ErrNotFound,
+		id)
+}
+
+// copyCategoryRule returns a copy of rule that shares no mutable state with
+// it.
+func copyCategoryRule(rule *CategoryRule) *CategoryRule {
+	document := *rule.BaseDocument
+	base := *rule.BaseCategoryRule
+	base.Keywords = append([]string(nil), rule.Keywords...)
+	return &CategoryRule{BaseDocument: &document, BaseCategoryRule: &base}
+}
+
+// MemoryWalletRepository is a WalletRepository that keeps wallets in memory,
+// keyed by hex encoded ID.
+type MemoryWalletRepository struct {
+	mu      sync.RWMutex
+	wallets map[string]*Wallet
+}
+
+// NewMemoryWalletRepository returns an empty MemoryWalletRepository.
+func NewMemoryWalletRepository() *MemoryWalletRepository {
+	return &MemoryWalletRepository{wallets: make(map[string]*Wallet)}
+}
+
+// InsertWallet stores a Wallet document created from the given baseWallet.
+func (repo *MemoryWalletRepository) InsertWallet(ctx context.Context,
+	baseWallet *BaseWallet) (*Wallet, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	wallet := CreateWallet(copyBaseWallet(baseWallet))
+	wallet.SetCreatedAt()
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.wallets[wallet.ID.Hex()] = &wallet
+
+	return copyWallet(&wallet), nil
+}
+
+// GetWallet retrieves a Wallet document by its hex encoded ID.
+func (repo *MemoryWalletRepository) GetWallet(ctx context.Context,
+	id string) (*Wallet, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	wallet, ok := repo.wallets[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: no wallet found with id: %s", ErrNotFound,
+			id)
+	}
+
+	return copyWallet(wallet), nil
+}
+
+// UpdateWallet replaces the stored Wallet document with the same ID as wallet.
+func (repo *MemoryWalletRepository) UpdateWallet(ctx context.Context,
+	wallet *Wallet) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	id := wallet.ID.Hex()
+	if _, ok := repo.wallets[id]; !ok {
+		return fmt.Errorf("%w: no wallet found with id: %s", ErrNotFound, id)
+	}
+	repo.wallets[id] = copyWallet(wallet)
+
+	return nil
+}
+
+// AddWalletCard appends card to the cards of the stored wallet with the hex
+// encoded id and returns the updated wallet. It fails with ErrDuplicate if
+// the wallet already holds the card.
+func (repo *MemoryWalletRepository) AddWalletCard(ctx context.Context,
+	id string, card WalletCard) (*Wallet, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	wallet, ok := repo.wallets[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: no wallet found with id: %s", ErrNotFound,
+			id)
+	}
+	if wallet.CardIndex(card.CardKey) >= 0 {
+		return nil, fmt.Errorf("%w: wallet already holds card: %s",
+			ErrDuplicate, card.CardKey)
+	}
+	wallet.Cards = append(wallet.Cards, card)
+
+	return copyWallet(wallet), nil
+}
+
+// RemoveWalletCard removes the card with cardKey from the cards of the
+// stored wallet with the hex encoded id and returns the updated wallet. It
+// fails with ErrNotFound if the wallet does not hold the card.
+func (repo *MemoryWalletRepository) RemoveWalletCard(ctx context.Context,
+	id, cardKey string) (*Wallet, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	wallet, ok := repo.wallets[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: no wallet found with id: %s", ErrNotFound,
+			id)
+	}
+	i := wallet.CardIndex(cardKey)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: wallet does not hold card: %s",
+			ErrNotFound, cardKey)
+	}
+	wallet.Cards = append(wallet.Cards[:i:i], wallet.Cards[i+1:]...)
+
+	return copyWallet(wallet), nil
+}
+
+// copyWallet returns a copy of wallet that shares no mutable state with it.
+func copyWallet(wallet *Wallet) *Wallet {
+	document := *wallet.BaseDocument
+	return &Wallet{
+		BaseDocument: &document,
+		BaseWallet:   copyBaseWallet(wallet.BaseWallet),
+	}
+}
+
+// copyBaseWallet returns a copy of baseWallet that shares no mutable state
+// with it.
+func copyBaseWallet(baseWallet *BaseWallet) *BaseWallet {
+	base := *baseWallet
+	base.Cards = append([]WalletCard(nil), baseWallet.Cards...)
+	if baseWallet.ValuationTable != nil {
+		base.ValuationTable = make(map[string]float64,
+			len(baseWallet.ValuationTable))
+		for program, cents := range baseWallet.ValuationTable {
+			base.ValuationTable[program] = cents
+		}
+	}
+	return &base
+}
@@ -0,0 +1,299 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryCardRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewMemoryCardRepository()
+	for _, card := range []*rewards.CardDetail{
+		{CardKey: "amex-gold", CardName: "Gold"},
+		{CardKey: "citi-doublecash", CardName: "Double Cash"},
+		{CardKey: "amex-gold", CardName: "Gold Card"},
+	} {
+		if _, err := repo.InsertCard(ctx, card); err != nil {
+			t.Fatalf("InsertCard() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		cardKeys []string
+		want     map[string]string
+	}{
+		{"replaced card", []string{"amex-gold"},
+			map[string]string{"amex-gold": "Gold Card"}},
+		{"several cards", []string{"amex-gold", "citi-doublecash"},
+			map[string]string{"amex-gold": "Gold Card",
+				"citi-doublecash": "Double Cash"}},
+		{"missing card left out", []string{"no-such-card", "citi-doublecash"},
+			map[string]string{"citi-doublecash": "Double Cash"}},
+		{"no keys", nil, map[string]string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cards, err := repo.GetCardsByKeys(ctx, test.cardKeys)
+			if err != nil {
+				t.Fatalf("GetCardsByKeys() error = %v", err)
+			}
+			if len(cards) != len(test.want) {
+				t.Fatalf("GetCardsByKeys() = %d cards, want %d", len(cards),
+					len(test.want))
+			}
+			for cardKey, name := range test.want {
+				card, ok := cards[cardKey]
+				if !ok || card.CardDetail.CardName != name {
+					t.Errorf("GetCardsByKeys()[%s] = %+v, want %q", cardKey,
+						card, name)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryDomainRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewMemoryDomainRepository()
+	inserted, err := repo.InsertDomain(ctx,
+		&store.BaseDomain{Name: "amazon.com", CategoryID: 3})
+	if err != nil {
+		t.Fatalf("InsertDomain() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		domainName string
+		wantErr    error
+	}{
+		{"stored", "amazon.com", nil},
+		{"missing", "missing.com", store.ErrNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := repo.GetDomainByName(ctx, test.domainName)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("GetDomainByName() error = %v, want %v", err,
+					test.wantErr)
+			}
+			if err == nil && (got.ID != inserted.ID || got.CategoryID != 3) {
+				t.Errorf("GetDomainByName() = %+v, want %+v", got, inserted)
+			}
+		})
+	}
+
+	inserted.CategoryID = 9
+	if got, _ := repo.GetDomainByName(ctx,
+		"amazon.com"); got.CategoryID != 3 {
+		t.Errorf("InsertDomain() shares state with the stored domain")
+	}
+}
+
+func TestMemoryTransactionRepositoryInsert(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewMemoryTransactionRepository()
+	base := &store.BaseTransaction{
+		SpendAmount: 25,
+		CardDetails: store.CardDetails{CardKey: "amex-gold"},
+	}
+
+	transaction, err := repo.InsertTransaction(ctx, base)
+	if err != nil {
+		t.Fatalf("InsertTransaction() error = %v", err)
+	}
+	if transaction.ID.IsZero() || transaction.SpendAmount != 25 {
+		t.Errorf("InsertTransaction() = %+v, want an ID and the spend",
+			transaction)
+	}
+	base.SpendAmount = 50
+	if transaction.SpendAmount != 25 {
+		t.Errorf("InsertTransaction() shares state with its argument")
+	}
+}
+
+func TestMemoryTransactionRepositoryList(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewMemoryTransactionRepository()
+	day := func(d int) time.Time {
+		return time.Date(2026, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+	for _, base := range []*store.BaseTransaction{
+		{WalletID: "w1", TransactionAt: day(3), SpendAmount: 30,
+			CardDetails: store.CardDetails{CardKey: "amex-gold"}},
+		{WalletID: "w1", TransactionAt: day(1), SpendAmount: 10,
+			CardDetails: store.CardDetails{CardKey: "citi-doublecash"}},
+		{WalletID: "w2", TransactionAt: day(2), SpendAmount: 20,
+			CardDetails: store.CardDetails{CardKey: "amex-gold"}},
+	} {
+		if _, err := repo.InsertTransaction(ctx, base); err != nil {
+			t.Fatalf("InsertTransaction() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter store.TransactionFilter
+		want   []float64
+	}{
+		{"all, oldest first", store.TransactionFilter{}, []float64{10, 20, 30}},
+		{"wallet", store.TransactionFilter{WalletID: "w1"}, []float64{10, 30}},
+		{"cards", store.TransactionFilter{CardKeys: []string{"amex-gold"}},
+			[]float64{20, 30}},
+		{"period", store.TransactionFilter{From: day(2), To: day(3)},
+			[]float64{20}},
+		{"none", store.TransactionFilter{WalletID: "w3"}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transactions, err := repo.ListTransactions(ctx, &test.filter)
+			if err != nil {
+				t.Fatalf("ListTransactions() error = %v", err)
+			}
+			var got []float64
+			for _, transaction := range transactions {
+				got = append(got, transaction.SpendAmount)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("ListTransactions() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMemoryWalletRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewMemoryWalletRepository()
+	wallet, err := repo.InsertWallet(ctx, &store.BaseWallet{
+		Name:  "personal",
+		Cards: []store.WalletCard{{CardKey: "amex-gold"}},
+	})
+	if err != nil {
+		t.Fatalf("InsertWallet() error = %v", err)
+	}
+	id := wallet.ID.Hex()
+	missingID := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name      string
+		do        func() (*store.Wallet, error)
+		wantErr   error
+		wantCards []string
+	}{
+		{"get", func() (*store.Wallet, error) {
+			return repo.GetWallet(ctx, id)
+		}, nil, []string{"amex-gold"}},
+		{"get missing", func() (*store.Wallet, error) {
+			return repo.GetWallet(ctx, missingID)
+		}, store.ErrNotFound, nil},
+		{"add card", func() (*store.Wallet, error) {
+			return repo.AddWalletCard(ctx, id,
+				store.WalletCard{CardKey: "citi-doublecash"})
+		}, nil, []string{"amex-gold", "citi-doublecash"}},
+		{"add held card", func() (*store.Wallet, error) {
+			return repo.AddWalletCard(ctx, id,
+				store.WalletCard{CardKey: "amex-gold"})
+		}, store.ErrDuplicate, nil},
+		{"add card to missing wallet", func() (*store.Wallet, error) {
+			return repo.AddWalletCard(ctx, missingID,
+				store.WalletCard{CardKey: "amex-gold"})
+		}, store.ErrNotFound, nil},
+		{"remove card", func() (*store.Wallet, error) {
+			return repo.RemoveWalletCard(ctx, id, "amex-gold")
+		}, nil, []string{"citi-doublecash"}},
+		{"remove card not held", func() (*store.Wallet, error) {
+			return repo.RemoveWalletCard(ctx, id, "amex-gold")
+		}, store.ErrNotFound, nil},
+		{"update missing", func() (*store.Wallet, error) {
+			missing := store.CreateWallet(&store.BaseWallet{Name: "other"})
+			return nil, repo.UpdateWallet(ctx, &missing)
+		}, store.ErrNotFound, nil},
+	}
+	// The cases run in order, each on the wallet the previous ones left.
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.do()
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("error = %v, want %v", err, test.wantErr)
+			}
+			if err != nil || got == nil {
+				return
+			}
+			if keys := cardKeys(got); !slices.Equal(keys, test.wantCards) {
+				t.Errorf("cards = %v, want %v", keys, test.wantCards)
+			}
+		})
+	}
+
+	got, err := repo.GetWallet(ctx, id)
+	if err != nil {
+		t.Fatalf("GetWallet() error = %v", err)
+	}
+	got.Cards[0].CardKey = "changed"
+	got.Name = "renamed"
+	if stored, _ := repo.GetWallet(ctx, id); stored.Cards[0].CardKey ==
+		"changed" || stored.Name == "renamed" {
+		t.Errorf("GetWallet() shares state with the stored wallet")
+	}
+	if err := repo.UpdateWallet(ctx, got); err != nil {
+		t.Fatalf("UpdateWallet() error = %v", err)
+	}
+	if stored, _ := repo.GetWallet(ctx, id); stored.Name != "renamed" {
+		t.Errorf("GetWallet() after update name = %q, want renamed",
+			stored.Name)
+	}
+}
+
+// cardKeys returns the card keys of wallet in order.
+func cardKeys(wallet *store.Wallet) []string {
+	var keys []string
+	for _, card := range wallet.Cards {
+		keys = append(keys, card.CardKey)
+	}
+	return keys
+}
+
+func TestMemoryCardRevisions(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewMemoryCardRepository()
+	before := time.Now().Add(-time.Hour)
+
+	card := &rewards.CardDetail{CardKey: "amex-gold", CardName: "Gold"}
+	if _, err := repo.InsertCard(ctx, card); err != nil {
+		t.Fatalf("InsertCard() error = %v", err)
+	}
+	if _, err := repo.InsertCard(ctx, card); err != nil {
+		t.Fatalf("InsertCard() error = %v", err)
+	}
+	renamed := *card
+	renamed.CardName = "Gold Card"
+	if _, err := repo.InsertCard(ctx, &renamed); err != nil {
+		t.Fatalf("InsertCard() error = %v", err)
+	}
+
+	revisions, err := repo.ListCardRevisions(ctx, "amex-gold")
+	if err != nil {
+		t.Fatalf("ListCardRevisions() error = %v", err)
+	}
+	if len(revisions) != 2 || revisions[1].Revision != 2 {
+		t.Fatalf("ListCardRevisions() = %d revisions, want 2 for the "+
+			"changed detail only", len(revisions))
+	}
+	got, err := repo.GetCardRevisionAt(ctx, "amex-gold", time.Now())
+	if err != nil || got.CardDetail.CardName != "Gold Card" {
+		t.Errorf("GetCardRevisionAt(now) = %+v, %v, want the latest", got,
+			err)
+	}
+	_, err = repo.GetCardRevisionAt(ctx, "amex-gold", before)
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetCardRevisionAt(before) error = %v, want %v", err,
+			store.ErrNotFound)
+	}
+}
@@ -0,0 +1,172 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CardRepository stores and retrieves Card documents. Like every repository,
+// its methods stop and return the context's error once ctx is done.
+type CardRepository interface {
+	InsertCard(ctx context.Context, cardDetail *rewards.CardDetail) (*Card,
+		error)
+	GetCardsByKeys(ctx context.Context, cardKeys []string) (map[string]*Card,
+		error)
+
+	// UpsertCard stores card in place of any card stored with the same card
+	// key, keeping the stored card's ID and creation time.
+	UpsertCard(ctx context.Context, card *Card) (*Card, error)
+
+	// ListCards returns every stored card, one per card key, ordered by
+	// card key.
+	ListCards(ctx context.Context) ([]*Card, error)
+
+	// SetCardStatus sets the catalog status of the card with cardKey.
+	SetCardStatus(ctx context.Context, cardKey string, status string) error
+
+	// ListCardRevisions returns every recorded revision of the card with
+	// cardKey, oldest first. InsertCard and UpsertCard record a revision
+	// whenever the card's detail differs from its latest one.
+	ListCardRevisions(ctx context.Context, cardKey string) ([]*CardRevision,
+		error)
+
+	// GetCardRevisionAt returns the revision of the card with cardKey that
+	// held at time at.
+	GetCardRevisionAt(ctx context.Context, cardKey string,
+		at time.Time) (*CardRevision, error)
+}
+
+// DomainRepository stores and retrieves Domain documents and the aliases
+// mapping other domain names to them.
+type DomainRepository interface {
+	InsertDomain(ctx context.Context, baseDomain *BaseDomain) (*Domain, error)
+	GetDomainByName(ctx context.Context, name string) (*Domain, error)
+
+	// UpdateDomain replaces the stored domain with the ID of domain. Like
+	// InsertDomain, it fails with ErrDuplicate if another domain is stored
+	// under its name.
+	UpdateDomain(ctx context.Context, domain *Domain) error
+
+	// DeleteDomain deletes the domain stored under name.
+	DeleteDomain(ctx context.Context, name string) error
+
+	// ListDomains returns the page of stored domains selected by filter,
+	// ordered by name.
+	ListDomains(ctx context.Context, filter *DomainFilter) ([]*Domain, error)
+
+	// UpsertDomains stores each of baseDomains in place of the domain stored
+	// under its name, keeping its ID and creation time, or as a new domain.
+	// It returns the stored domains, ordered by name.
+	UpsertDomains(ctx context.Context, baseDomains []*BaseDomain) ([]*Domain,
+		error)
+
+	// SetDomainAlias maps alias to the canonical domain name domain,
+	// replacing the domain it was mapped to, if any.
+	SetDomainAlias(ctx context.Context, alias, domain string) (*DomainAlias,
+		error)
+
+	// GetDomainAlias returns the alias stored for the domain name alias.
+	GetDomainAlias(ctx context.Context, alias string) (*DomainAlias, error)
+
+	// ListDomainAliases returns every stored alias, ordered by alias.
+	ListDomainAliases(ctx context.Context) ([]*DomainAlias, error)
+}
+
+// CategoryRuleRepository stores and retrieves CategoryRule documents.
+type CategoryRuleRepository interface {
+	// InsertCategoryRule stores a rule after the other rules of its wallet,
+	// or the other shared rules if it has no wallet.
+	InsertCategoryRule(ctx context.Context,
+		baseRule *BaseCategoryRule) (*CategoryRule, error)
+
+	// ReplaceCategoryRules replaces every shared rule with baseRules,
+	// positioned in the order given. Wallet rules are kept.
+	ReplaceCategoryRules(ctx context.Context,
+		baseRules []*BaseCategoryRule) ([]*CategoryRule, error)
+
+	// ListCategoryRules returns the rules of the wallet with walletID and
+	// the shared rules, in the order they are tried. An empty walletID
+	// returns only the shared rules.
+	ListCategoryRules(ctx context.Context, walletID string) ([]*CategoryRule,
+		error)
+
+	// DeleteCategoryRule deletes the rule with the given ID.
+	DeleteCategoryRule(ctx context.Context, id string) error
+}
+
+// TransactionRepository stores and retrieves Transaction documents.
+type TransactionRepository interface {
+	InsertTransaction(ctx context.Context,
+		baseTransaction *BaseTransaction) (*Transaction, error)
+	ListTransactions(ctx context.Context,
+		filter *TransactionFilter) ([]*Transaction, error)
+}
+
+// WalletRepository stores and retrieves Wallet documents.
+type WalletRepository interface {
+	InsertWallet(ctx context.Context, baseWallet *BaseWallet) (*Wallet, error)
+	GetWallet(ctx context.Context, id string) (*Wallet, error)
+	UpdateWallet(ctx context.Context, wallet *Wallet) error
+
+	// AddWalletCard appends card to the cards of the wallet with id and
+	// RemoveWalletCard removes the card with cardKey from them, each as a
+	// single atomic change returning the updated wallet, so that concurrent
+	// changes to a wallet's cards are not lost. Adding a card the wallet
+	// holds fails with ErrDuplicate, and removing one it does not hold with
+	// ErrNotFound.
+	AddWalletCard(ctx context.Context, id string,
+		card WalletCard) (*Wallet, error)
+	RemoveWalletCard(ctx context.Context, id, cardKey string) (*Wallet, error)
+}
+
+// Repository groups the repositories of a single storage backend.
+type Repository struct {
+	Cards         CardRepository
+	Domains       DomainRepository
+	CategoryRules CategoryRuleRepository
+	Transactions  TransactionRepository
+	Wallets       WalletRepository
+
+	// close releases the connection the repositories share, if any.
+	close func(ctx context.Context) error
+}
+
+// Close releases the database connection the repository was created with.
+// The repository must not be used afterwards.
+func (repo *Repository) Close(ctx context.Context) error {
+	if repo.close == nil {
+		return nil
+	}
+	return repo.close(ctx)
+}
+
+// NewMongoRepository returns a Repository backed by the given MongoDB client,
+// using the database and collections named in options. Closing the
+// Repository disconnects the client.
+func NewMongoRepository(client *mongo.Client,
+	options MongoOptions) *Repository {
+
+	return &Repository{
+		Cards:         NewMongoCardRepository(client, options),
+		Domains:       NewMongoDomainRepository(client, options),
+		CategoryRules: NewMongoCategoryRuleRepository(client, options),
+		Transactions:  NewMongoTransactionRepository(client, options),
+		Wallets:       NewMongoWalletRepository(client, options),
+		close:         client.Disconnect,
+	}
+}
+
+// NewMemoryRepository returns a Repository that keeps every document in
+// process memory. Nothing is persisted once the process exits.
+func NewMemoryRepository() *Repository {
+	return &Repository{
+		Cards:         NewMemoryCardRepository(),
+		Domains:       NewMemoryDomainRepository(),
+		CategoryRules: NewMemoryCategoryRuleRepository(),
+		Transactions:  NewMemoryTransactionRepository(),
+		Wallets:       NewMemoryWalletRepository(),
+	}
+}
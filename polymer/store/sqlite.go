@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // Registers the sqlite3 driver
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sqliteMaxBatch bounds the number of values bound to a single statement,
+// well below the limit SQLite sets on statement parameters.
+const sqliteMaxBatch = 500
+
+// ConnectSQLite opens the SQLite database at path, creating it if needed, and
+// migrates its schema to the latest version. Use ":memory:" for a throwaway
+// database.
+func ConnectSQLite(ctx context.Context, path string) (*sql.DB, error) {
+	// The path is escaped so that a "?" or "#" in it is not taken for the
+	// start of the DSN's parameters.
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000",
+		(&url.URL{Path: path}).EscapedPath())
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	// SQLite serializes writers anyway, and a single connection keeps an
+	// in-memory database alive for the lifetime of the pool.
+	db.SetMaxOpenConns(1)
+
+	if err := MigrateSQLite(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// MigrateSQLite applies every pending migration in sqliteMigrations to db,
+// recording each applied version in the schema_migrations table.
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	description TEXT NOT NULL,
+	applied_at INTEGER NOT NULL
+)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var current int
+	err = db.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(version), 0) FROM schema_migrations",
+	).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, migration := range sqliteMigrations {
+		if migration.version <= current {
+			continue
+		}
+		if err := applySQLiteMigration(ctx, db, migration); err != nil {
+			return fmt.Errorf("failed to apply migration %d (%s): %w",
+				migration.version, migration.description, err)
+		}
+	}
+
+	return nil
+}
+
+// applySQLiteMigration runs a single migration and records it in one
+// transaction, so a failed migration leaves the schema untouched.
+func applySQLiteMigration(ctx context.Context, db *sql.DB,
+	migration sqliteMigration) error {
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if migration.check != nil {
+		if err := migration.check(ctx, tx); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, migration.statements); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations (version, description, applied_at) "+
+			"VALUES (?, ?, ?)",
+		migration.version, migration.description, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// NewSQLiteRepository returns a Repository backed by the given SQLite
+// database, which must already be migrated (see ConnectSQLite). Closing the
+// Repository closes the database.
+func NewSQLiteRepository(db *sql.DB) *Repository {
+	return &Repository{
+		Cards:         NewSQLiteCardRepository(db),
+		Domains:       NewSQLiteDomainRepository(db),
+		CategoryRules: NewSQLiteCategoryRuleRepository(db),
+		Transactions:  NewSQLiteTransactionRepository(db),
+		Wallets:       NewSQLiteWalletRepository(db),
+		close: func(context.Context) error {
+			return db.Close()
+		},
+	}
+}
+
+// sqliteTime returns t as stored in SQLite, in unix milliseconds, with the
+// zero time stored as 0.
+func sqliteTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// sqliteTimeValue returns the time stored in SQLite as value by sqliteTime.
+func sqliteTimeValue(value int64) time.Time {
+	if value == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(value).UTC()
+}
+
+// sqlitePlaceholders returns n comma separated "?" placeholders.
+func sqlitePlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// sqliteDocument converts stored id and created_at columns back into a
+// BaseDocument.
+func sqliteDocument(id string, createdAt int64) (*BaseDocument, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document id %q: %w", id, err)
+	}
+	return &BaseDocument{
+		ID:        objectID,
+		CreatedAt: primitive.DateTime(createdAt),
+	}, nil
+}
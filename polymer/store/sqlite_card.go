@@ -0,0 +1,506 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+)
+
+// SQLiteCardRepository is a CardRepository backed by a SQLite database. The
+// scalar fields of a CardDetail live in the card table and each nested list in
+// its own child table, ordered by position.
+type SQLiteCardRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteCardRepository returns a SQLiteCardRepository using the given
+// database.
+func NewSQLiteCardRepository(db *sql.DB) *SQLiteCardRepository {
+	return &SQLiteCardRepository{db: db}
+}
+
+// InsertCard inserts a Card document, together with its benefits, spend bonus
+// categories and annual spend bonuses, from a given CardDetail object.
+func (repo *SQLiteCardRepository) InsertCard(ctx context.Context,
+	cardDetail *rewards.CardDetail) (*Card, error) {
+
+	card := CreateCard(cardDetail)
+	card.SetCreatedAt()
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	defer tx.Rollback()
+
+	if err := insertSQLiteCard(ctx, tx, card); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	if err := recordSQLiteCardRevision(ctx, tx, card); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	return card, nil
+}
+
+// insertSQLiteCard writes card and its nested lists using tx.
+func insertSQLiteCard(ctx context.Context, tx *sql.Tx, card *Card) error {
+	id := card.ID.Hex()
+	detail := &card.CardDetail
+
+	columns := append(append([]string{}, cardDocumentColumns...),
+		cardDetailColumns...)
+	values := append([]any{id, int64(card.CreatedAt), card.Status,
+		sqliteTime(card.SyncedAt), sqliteTime(card.FetchedAt)},
+		cardDetailValues(detail)...)
+	query := fmt.Sprintf("INSERT INTO card (%s) VALUES (%s)",
+		strings.Join(columns, ", "), sqlitePlaceholders(len(columns)))
+	_, err := tx.ExecContext(ctx, query, values...)
+	if err != nil {
+		return err
+	}
+
+	for i, benefit := range detail.Benefit {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO card_benefit (card_id, position, benefit_title, "+
+				"benefit_desc) VALUES (?, ?, ?, ?)",
+			id, i, benefit.BenefitTitle, benefit.BenefitDesc)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, bonus := range detail.SpendBonusCategory {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO card_spend_bonus_category (card_id, position, "+
+				"spend_bonus_category_type, spend_bonus_category_name, "+
+				"spend_bonus_category_id, spend_bonus_category_group, "+
+				"spend_bonus_subcategory_group, spend_bonus_desc, "+
+				"earn_multiplier, is_date_limit, limit_begin_date, "+
+				"limit_end_date, is_spend_limit, spend_limit, "+
+				"spend_limit_reset_period) "+
+				"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			id, i, bonus.SpendBonusCategoryType, bonus.SpendBonusCategoryName,
+			bonus.SpendBonusCategoryID, bonus.SpendBonusCategoryGroup,
+			bonus.SpendBonusSubcategoryGroup, bonus.SpendBonusDesc,
+			bonus.EarnMultiplier, bonus.IsDateLimit, bonus.LimitBeginDate,
+			bonus.LimitEndDate, bonus.IsSpendLimit, bonus.SpendLimit,
+			bonus.SpendLimitResetPeriod)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, annualSpend := range detail.AnnualSpend {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO card_annual_spend (card_id, position, "+
+				"annual_spend_desc) VALUES (?, ?, ?)",
+			id, i, annualSpend.AnnualSpendDesc)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetCardsByKeys retrieves multiple Card documents based on a slice of unique
+// card keys.
+func (repo *SQLiteCardRepository) GetCardsByKeys(ctx context.Context,
+	cardKeys []string) (map[string]*Card, error) {
+
+	cardMap := make(map[string]*Card)
+	if len(cardKeys) == 0 {
+		return cardMap, nil
+	}
+
+	args := make([]any, len(cardKeys))
+	for i, cardKey := range cardKeys {
+		args[i] = cardKey
+	}
+
+	// SQLite bounds the number of parameters of a statement, so the cards
+	// are read in batches of keys.
+	for start := 0; start < len(args); start += sqliteMaxBatch {
+		batch := args[start:min(start+sqliteMaxBatch, len(args))]
+		query := fmt.Sprintf(
+			"SELECT %s FROM card WHERE card_key IN (%s) ORDER BY created_at",
+			sqliteCardColumns(), sqlitePlaceholders(len(batch)))
+		cards, err := querySQLiteCards(ctx, repo.db, query, batch...)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to retrieve cards: %w",
+				ErrQuery, err)
+		}
+
+		for _, card := range cards {
+			cardMap[card.CardDetail.CardKey] = card
+		}
+	}
+
+	return cardMap, nil
+}
+
+// UpsertCard stores card in place of every card stored with the same card
+// key, keeping the ID and creation time of the first one stored.
+func (repo *SQLiteCardRepository) UpsertCard(ctx context.Context,
+	card *Card) (*Card, error) {
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	defer tx.Rollback()
+
+	upserted := *card
+	var id string
+	var createdAt int64
+	err = tx.QueryRowContext(ctx,
+		"SELECT id, created_at FROM card WHERE card_key = ? "+
+			"ORDER BY created_at LIMIT 1", card.CardDetail.CardKey,
+	).Scan(&id, &createdAt)
+	switch {
+	case err == nil:
+		upserted.BaseDocument, err = sqliteDocument(id, createdAt)
+		if err != nil {
+			return nil, err
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		upserted.BaseDocument = &BaseDocument{}
+		upserted.SetID()
+		upserted.SetCreatedAt()
+	default:
+		return nil, fmt.Errorf("%w: failed to find card: %w", ErrQuery, err)
+	}
+
+	// The nested lists are deleted with the card rows.
+	_, err = tx.ExecContext(ctx, "DELETE FROM card WHERE card_key = ?",
+		card.CardDetail.CardKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	if err := insertSQLiteCard(ctx, tx, &upserted); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	if err := recordSQLiteCardRevision(ctx, tx, &upserted); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	return &upserted, nil
+}
+
+// ListCards returns every stored card, one per card key, ordered by card key.
+// Of duplicate cards the most recently created is returned.
+func (repo *SQLiteCardRepository) ListCards(ctx context.Context) ([]*Card,
+	error) {
+
+	query := fmt.Sprintf(
+		"SELECT %s FROM card WHERE id IN (SELECT id FROM card AS latest "+
+			"WHERE latest.card_key = card.card_key "+
+			"ORDER BY created_at DESC LIMIT 1) ORDER BY card_key",
+		sqliteCardColumns())
+	cards, err := querySQLiteCards(ctx, repo.db, query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to retrieve cards: %w",
+			ErrQuery, err)
+	}
+
+	return cards, nil
+}
+
+// SetCardStatus sets the catalog status of every card stored with cardKey.
+func (repo *SQLiteCardRepository) SetCardStatus(ctx context.Context,
+	cardKey string, status string) error {
+
+	result, err := repo.db.ExecContext(ctx,
+		"UPDATE card SET status = ? WHERE card_key = ?", status, cardKey)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInsert, err)
+	} else if updated == 0 {
+		return fmt.Errorf("%w: no card found with key: %s", ErrNotFound,
+			cardKey)
+	}
+
+	return nil
+}
+
+// cardDocumentColumns lists the card table columns holding the fields of a
+// Card other than its CardDetail, in the order read by querySQLiteCards.
+var cardDocumentColumns = []string{"id", "created_at", "status", "synced_at",
+	"fetched_at"}
+
+// sqliteCardColumns returns the comma separated cardDocumentColumns and
+// cardDetailColumns, as selected by the queries of querySQLiteCards.
+func sqliteCardColumns() string {
+	return strings.Join(cardDocumentColumns, ", ") + ", " +
+		strings.Join(cardDetailColumns, ", ")
+}
+
+// querySQLiteCards runs a query selecting cardDocumentColumns and
+// cardDetailColumns from the card table and loads the nested lists of every
+// returned card.
+func querySQLiteCards(ctx context.Context, db *sql.DB, query string,
+	args ...any) ([]*Card, error) {
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read every card before loading the nested lists, since the pool may
+	// only hold a single connection.
+	var cards []*Card
+	for rows.Next() {
+		var id string
+		var createdAt, syncedAt, fetchedAt int64
+		card := Card{}
+		dest := append([]any{&id, &createdAt, &card.Status, &syncedAt,
+			&fetchedAt}, cardDetailFields(&card.CardDetail)...)
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to decode card: %w", err)
+		}
+		document, err := sqliteDocument(id, createdAt)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		card.BaseDocument = document
+		card.SyncedAt = sqliteTimeValue(syncedAt)
+		card.FetchedAt = sqliteTimeValue(fetchedAt)
+		cards = append(cards, &card)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, card := range cards {
+		if err := loadSQLiteCardLists(ctx, db, card); err != nil {
+			return nil, err
+		}
+	}
+
+	return cards, nil
+}
+
+// loadSQLiteCardLists fills the nested lists of card from the child tables.
+func loadSQLiteCardLists(ctx context.Context, db *sql.DB, card *Card) error {
+	id := card.ID.Hex()
+	detail := &card.CardDetail
+
+	rows, err := db.QueryContext(ctx,
+		"SELECT benefit_title, benefit_desc FROM card_benefit "+
+			"WHERE card_id = ? ORDER BY position", id)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve card benefits: %w", err)
+	}
+	for rows.Next() {
+		var benefit rewards.Benefit
+		if err := rows.Scan(&benefit.BenefitTitle,
+			&benefit.BenefitDesc); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to decode card benefit: %w", err)
+		}
+		detail.Benefit = append(detail.Benefit, benefit)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = db.QueryContext(ctx,
+		"SELECT spend_bonus_category_type, spend_bonus_category_name, "+
+			"spend_bonus_category_id, spend_bonus_category_group, "+
+			"spend_bonus_subcategory_group, spend_bonus_desc, "+
+			"earn_multiplier, is_date_limit, limit_begin_date, "+
+			"limit_end_date, is_spend_limit, spend_limit, "+
+			"spend_limit_reset_period FROM card_spend_bonus_category "+
+			"WHERE card_id = ? ORDER BY position", id)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve spend bonus categories: %w", err)
+	}
+	for rows.Next() {
+		var bonus rewards.SpendBonusCategory
+		err := rows.Scan(&bonus.SpendBonusCategoryType,
+			&bonus.SpendBonusCategoryName, &bonus.SpendBonusCategoryID,
+			&bonus.SpendBonusCategoryGroup, &bonus.SpendBonusSubcategoryGroup,
+			&bonus.SpendBonusDesc, &bonus.EarnMultiplier, &bonus.IsDateLimit,
+			&bonus.LimitBeginDate, &bonus.LimitEndDate, &bonus.IsSpendLimit,
+			&bonus.SpendLimit, &bonus.SpendLimitResetPeriod)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to decode spend bonus category: %w", err)
+		}
+		detail.SpendBonusCategory = append(detail.SpendBonusCategory, bonus)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = db.QueryContext(ctx,
+		"SELECT annual_spend_desc FROM card_annual_spend "+
+			"WHERE card_id = ? ORDER BY position", id)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve annual spend bonuses: %w", err)
+	}
+	for rows.Next() {
+		var annualSpend rewards.AnnualSpend
+		if err := rows.Scan(&annualSpend.AnnualSpendDesc); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to decode annual spend bonus: %w", err)
+		}
+		detail.AnnualSpend = append(detail.AnnualSpend, annualSpend)
+	}
+	rows.Close()
+	return rows.Err()
+}
+
+// cardDetailColumns lists the card table columns holding the scalar fields of
+// a rewards.CardDetail, in the order used by cardDetailValues and
+// cardDetailFields.
+var cardDetailColumns = []string{
+	"card_key",
+	"card_issuer",
+	"card_name",
+	"card_network",
+	"card_type",
+	"card_url",
+	"annual_fee",
+	"fx_fee",
+	"is_fx_fee",
+	"credit_range",
+	"base_spend_amount",
+	"base_spend_earn_type",
+	"base_spend_earn_category",
+	"base_spend_earn_currency",
+	"base_spend_earn_valuation",
+	"base_spend_earn_is_cash",
+	"base_spend_earn_cash_value",
+	"is_signup_bonus",
+	"signup_bonus_amount",
+	"signup_bonus_type",
+	"signup_bonus_category",
+	"sign_up_bonus_item",
+	"signup_bonus_spend",
+	"signup_bonus_length",
+	"signup_bonus_length_period",
+	"signup_annual_fee",
+	"is_signup_annual_fee_waived",
+	"signup_statement_credit",
+	"signup_bonus_desc",
+	"trusted_traveler",
+	"is_trusted_traveler",
+	"lounge_access",
+	"is_lounge_access",
+	"free_hotel_night",
+	"is_free_hotel_night",
+	"free_checked_bag",
+	"is_free_checked_bag",
+	"is_active",
+}
+
+// cardDetailValues returns the scalar fields of cardDetail in the order of
+// cardDetailColumns.
+func cardDetailValues(cardDetail *rewards.CardDetail) []any {
+	return []any{
+		cardDetail.CardKey,
+		cardDetail.CardIssuer,
+		cardDetail.CardName,
+		cardDetail.CardNetwork,
+		cardDetail.CardType,
+		cardDetail.CardUrl,
+		cardDetail.AnnualFee,
+		cardDetail.FxFee,
+		cardDetail.IsFxFee,
+		cardDetail.CreditRange,
+		cardDetail.BaseSpendAmount,
+		cardDetail.BaseSpendEarnType,
+		cardDetail.BaseSpendEarnCategory,
+		cardDetail.BaseSpendEarnCurrency,
+		cardDetail.BaseSpendEarnValuation,
+		cardDetail.BaseSpendEarnIsCash,
+		cardDetail.BaseSpendEarnCashValue,
+		cardDetail.IsSignupBonus,
+		cardDetail.SignupBonusAmount,
+		cardDetail.SignupBonusType,
+		cardDetail.SignupBonusCategory,
+		cardDetail.SignUpBonusItem,
+		cardDetail.SignupBonusSpend,
+		cardDetail.SignupBonusLength,
+		cardDetail.SignupBonusLengthPeriod,
+		cardDetail.SignupAnnualFee,
+		cardDetail.IsSignupAnnualFeeWaived,
+		cardDetail.SignupStatementCredit,
+		cardDetail.SignupBonusDesc,
+		cardDetail.TrustedTraveler,
+		cardDetail.IsTrustedTraveler,
+		cardDetail.LoungeAccess,
+		cardDetail.IsLoungeAccess,
+		cardDetail.FreeHotelNight,
+		cardDetail.IsFreeHotelNight,
+		cardDetail.FreeCheckedBag,
+		cardDetail.IsFreeCheckedBag,
+		cardDetail.IsActive,
+	}
+}
+
+// cardDetailFields returns pointers to the scalar fields of cardDetail in the
+// order of cardDetailColumns, for use as scan destinations.
+func cardDetailFields(cardDetail *rewards.CardDetail) []any {
+	return []any{
+		&cardDetail.CardKey,
+		&cardDetail.CardIssuer,
+		&cardDetail.CardName,
+		&cardDetail.CardNetwork,
+		&cardDetail.CardType,
+		&cardDetail.CardUrl,
+		&cardDetail.AnnualFee,
+		&cardDetail.FxFee,
+		&cardDetail.IsFxFee,
+		&cardDetail.CreditRange,
+		&cardDetail.BaseSpendAmount,
+		&cardDetail.BaseSpendEarnType,
+		&cardDetail.BaseSpendEarnCategory,
+		&cardDetail.BaseSpendEarnCurrency,
+		&cardDetail.BaseSpendEarnValuation,
+		&cardDetail.BaseSpendEarnIsCash,
+		&cardDetail.BaseSpendEarnCashValue,
+		&cardDetail.IsSignupBonus,
+		&cardDetail.SignupBonusAmount,
+		&cardDetail.SignupBonusType,
+		&cardDetail.SignupBonusCategory,
+		&cardDetail.SignUpBonusItem,
+		&cardDetail.SignupBonusSpend,
+		&cardDetail.SignupBonusLength,
+		&cardDetail.SignupBonusLengthPeriod,
+		&cardDetail.SignupAnnualFee,
+		&cardDetail.IsSignupAnnualFeeWaived,
+		&cardDetail.SignupStatementCredit,
+		&cardDetail.SignupBonusDesc,
+		&cardDetail.TrustedTraveler,
+		&cardDetail.IsTrustedTraveler,
+		&cardDetail.LoungeAccess,
+		&cardDetail.IsLoungeAccess,
+		&cardDetail.FreeHotelNight,
+		&cardDetail.IsFreeHotelNight,
+		&cardDetail.FreeCheckedBag,
+		&cardDetail.IsFreeCheckedBag,
+		&cardDetail.IsActive,
+	}
+}
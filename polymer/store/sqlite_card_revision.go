@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// recordSQLiteCardRevision records card as a new revision using tx if its
+// detail differs from the latest revision. A revision's detail is kept as JSON
+// in the card_revision table.
+func recordSQLiteCardRevision(ctx context.Context, tx *sql.Tx,
+	card *Card) error {
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+cardRevisionColumns+" FROM card_revision "+
+			"WHERE card_key = ? ORDER BY revision DESC LIMIT 1",
+		card.CardDetail.CardKey)
+	if err != nil {
+		return fmt.Errorf("%w: failed to find card revision: %w", ErrQuery,
+			err)
+	}
+	revisions, err := scanSQLiteCardRevisions(rows)
+	if err != nil {
+		return err
+	}
+
+	var latest *CardRevision
+	if len(revisions) > 0 {
+		latest = revisions[0]
+	}
+	revision := nextCardRevision(latest, card)
+	if revision == nil {
+		return nil
+	}
+
+	detail, err := json.Marshal(&revision.CardDetail)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO card_revision ("+cardRevisionColumns+") "+
+			"VALUES (?, ?, ?, ?, ?, ?)",
+		revision.ID.Hex(), int64(revision.CreatedAt), revision.CardKey,
+		revision.Revision, sqliteTime(revision.ObservedAt), string(detail))
+	return err
+}
+
+// cardRevisionColumns lists the card_revision columns in scan order.
+const cardRevisionColumns = "id, created_at, card_key, revision, " +
+	"observed_at, card_detail"
+
+// scanSQLiteCardRevisions reads every card revision from rows and closes
+// them.
+func scanSQLiteCardRevisions(rows *sql.Rows) ([]*CardRevision, error) {
+	defer rows.Close()
+
+	var revisions []*CardRevision
+	for rows.Next() {
+		var id, detail string
+		var createdAt, observedAt int64
+		var revision CardRevision
+		if err := rows.Scan(&id, &createdAt, &revision.CardKey,
+			&revision.Revision, &observedAt, &detail); err != nil {
+			return nil, fmt.Errorf("%w: failed to decode card revision: %w",
+				ErrQuery, err)
+		}
+
+		document, err := sqliteDocument(id, createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrQuery, err)
+		}
+		revision.BaseDocument = document
+		revision.ObservedAt = sqliteTimeValue(observedAt)
+		if err := json.Unmarshal([]byte(detail),
+			&revision.CardDetail); err != nil {
+			return nil, fmt.Errorf("%w: failed to decode card detail: %w",
+				ErrQuery, err)
+		}
+		revisions = append(revisions, &revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrQuery, err)
+	}
+	return revisions, nil
+}
+
+// ListCardRevisions returns every recorded revision of the card with
+// cardKey, oldest first.
+func (repo *SQLiteCardRepository) ListCardRevisions(ctx context.Context,
+	cardKey string) ([]*CardRevision, error) {
+
+	rows, err := repo.db.QueryContext(ctx,
+		"SELECT "+cardRevisionColumns+" FROM card_revision "+
+			"WHERE card_key = ? ORDER BY revision", cardKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to retrieve card revisions: %w",
+			ErrQuery, err)
+	}
+	return scanSQLiteCardRevisions(rows)
+}
+
+// GetCardRevisionAt returns the revision of the card with cardKey that held
+// at time at: the latest one observed at or before it.
+func (repo *SQLiteCardRepository) GetCardRevisionAt(ctx context.Context,
+	cardKey string, at time.Time) (*CardRevision, error) {
+
+	rows, err := repo.db.QueryContext(ctx,
+		"SELECT "+cardRevisionColumns+" FROM card_revision "+
+			"WHERE card_key = ? AND observed_at <= ? "+
+			"ORDER BY observed_at DESC, revision DESC LIMIT 1",
+		cardKey, at.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to find card revision: %w",
+			ErrQuery, err)
+	}
+	revisions, err := scanSQLiteCardRevisions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("%w: no revision of card %s at %s",
+			ErrNotFound, cardKey, at.Format(time.RFC3339))
+	}
+	return revisions[0], nil
+}
@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// categoryRuleColumns are the columns of the category_rule table after id
+// and created_at.
+const categoryRuleColumns = "name, wallet_id, position, match, field, " +
+	"pattern, keywords, category_id"
+
+// SQLiteCategoryRuleRepository is a CategoryRuleRepository backed by a
+// SQLite database.
+type SQLiteCategoryRuleRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteCategoryRuleRepository returns a SQLiteCategoryRuleRepository
+// using the given database.
+func NewSQLiteCategoryRuleRepository(
+	db *sql.DB) *SQLiteCategoryRuleRepository {
+
+	return &SQLiteCategoryRuleRepository{db: db}
+}
+
+// InsertCategoryRule inserts a new CategoryRule document into the
+// category_rule table, after the other rules of its wallet, or the other
+// shared rules.
+func (repo *SQLiteCategoryRuleRepository) InsertCategoryRule(
+	ctx context.Context, baseRule *BaseCategoryRule) (*CategoryRule, error) {
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	defer tx.Rollback()
+
+	base := *baseRule
+	err = tx.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(position) + 1, 0) FROM category_rule "+
+			"WHERE wallet_id = ?", base.WalletID,
+	).Scan(&base.Position)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to find rule position: %w",
+			ErrQuery, err)
+	}
+
+	rule := CreateCategoryRule(&base)
+	rule.SetCreatedAt()
+	if err := insertSQLiteCategoryRule(ctx, tx, &rule); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	return &rule, nil
+}
+
+// ReplaceCategoryRules replaces every shared CategoryRule document with
+// baseRules, positioned in the order given.
+func (repo *SQLiteCategoryRuleRepository) ReplaceCategoryRules(
+	ctx context.Context, baseRules []*BaseCategoryRule) ([]*CategoryRule,
+	error) {
+
+	rules := sharedCategoryRules(baseRules)
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM category_rule WHERE wallet_id = ''")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	for _, rule := range rules {
+		if err := insertSQLiteCategoryRule(ctx, tx, rule); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	return rules, nil
+}
+
+// ListCategoryRules retrieves the CategoryRule documents of the wallet with
+// walletID and the shared ones, in the order they are tried.
+func (repo *SQLiteCategoryRuleRepository) ListCategoryRules(
+	ctx context.Context, walletID string) ([]*CategoryRule, error) {
+
+	rows, err := repo.db.QueryContext(ctx,
+		"SELECT id, created_at, "+categoryRuleColumns+" FROM category_rule "+
+			"WHERE wallet_id IN ('', ?)", walletID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to retrieve category rules: %w",
+			ErrQuery, err)
+	}
+	defer rows.Close()
+
+	var rules []*CategoryRule
+	for rows.Next() {
+		var id, keywords string
+		var createdAt int64
+		var base BaseCategoryRule
+		if err := rows.Scan(&id, &createdAt, &base.Name, &base.WalletID,
+			&base.Position, &base.Match, &base.Field, &base.Pattern,
+			&keywords, &base.CategoryID); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrQuery, err)
+		}
+		if err := json.Unmarshal([]byte(keywords),
+			&base.Keywords); err != nil {
+			return nil, fmt.Errorf("%w: failed to decode keywords: %w",
+				ErrQuery, err)
+		}
+		document, err := sqliteDocument(id, createdAt)
+		if err != nil {
+			return nil, err
+		}
+		base.compilePattern()
+		rules = append(rules,
+			&CategoryRule{BaseDocument: document, BaseCategoryRule: &base})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrQuery, err)
+	}
+
+	sortCategoryRules(rules)
+	return rules, nil
+}
+
+// DeleteCategoryRule deletes the CategoryRule document with the given ID.
+func (repo *SQLiteCategoryRuleRepository) DeleteCategoryRule(
+	ctx context.Context, id string) error {
+
+	result, err := repo.db.ExecContext(ctx,
+		"DELETE FROM category_rule WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("%w: failed to delete category rule: %w",
+			ErrInsert, err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: no category rule found with id: %s",
+			ErrNotFound, id)
+	}
+	return nil
+}
+
+// insertSQLiteCategoryRule inserts rule into the category_rule table.
+func insertSQLiteCategoryRule(ctx context.Context, tx *sql.Tx,
+	rule *CategoryRule) error {
+
+	keywords, err := json.Marshal(rule.Keywords)
+	if err != nil {
+		return err
+	}
+	if rule.Keywords == nil {
+		keywords = []byte("[]")
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO category_rule (id, created_at, "+categoryRuleColumns+
+			") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		rule.ID.Hex(), int64(rule.CreatedAt), rule.Name, rule.WalletID,
+		rule.Position, rule.Match, rule.Field, rule.Pattern,
+		string(keywords), rule.CategoryID)
+	return err
+}
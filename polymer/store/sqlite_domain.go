@@ -0,0 +1,324 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// SQLiteDomainRepository is a DomainRepository backed by a SQLite database.
+type SQLiteDomainRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteDomainRepository returns a SQLiteDomainRepository using the given
+// database.
+func NewSQLiteDomainRepository(db *sql.DB) *SQLiteDomainRepository {
+	return &SQLiteDomainRepository{db: db}
+}
+
+// InsertDomain inserts a new Domain document into the domain table.
+func (repo *SQLiteDomainRepository) InsertDomain(ctx context.Context,
+	baseDomain *BaseDomain) (*Domain, error) {
+
+	domain := CreateDomain(baseDomain)
+	domain.SetCreatedAt()
+
+	_, err := repo.db.ExecContext(ctx,
+		"INSERT INTO domain (id, created_at, name, category_id, "+
+			"category_name, mcc) VALUES (?, ?, ?, ?, ?, ?)",
+		domain.ID.Hex(), int64(domain.CreatedAt), domain.Name,
+		domain.CategoryID, domain.CategoryName, domain.MCC)
+	if isSQLiteUniqueError(err) {
+		return nil, fmt.Errorf("%w: domain already exists: %s", ErrDuplicate,
+			domain.Name)
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	return &domain, nil
+}
+
+// GetDomainByName retrieves a Domain document by its unique name.
+func (repo *SQLiteDomainRepository) GetDomainByName(ctx context.Context,
+	name string) (*Domain, error) {
+
+	domain, err := scanSQLiteDomain(repo.db.QueryRowContext(ctx,
+		"SELECT "+domainColumns+" FROM domain WHERE name = ?", name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: no domain found with name: %s",
+				ErrNotFound, name)
+		}
+		return nil, fmt.Errorf("%w: failed to find domain: %w", ErrQuery, err)
+	}
+	return domain, nil
+}
+
+// UpdateDomain replaces the Domain document with the ID of domain.
+func (repo *SQLiteDomainRepository) UpdateDomain(ctx context.Context,
+	domain *Domain) error {
+
+	result, err := repo.db.ExecContext(ctx,
+		"UPDATE domain SET name = ?, category_id = ?, category_name = ?, "+
+			"mcc = ? WHERE id = ?",
+		domain.Name, domain.CategoryID, domain.CategoryName, domain.MCC,
+		domain.ID.Hex())
+	if isSQLiteUniqueError(err) {
+		return fmt.Errorf("%w: domain already exists: %s", ErrDuplicate,
+			domain.Name)
+	} else if err != nil {
+		return fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInsert, err)
+	} else if updated == 0 {
+		return fmt.Errorf("%w: no domain found with id: %s", ErrNotFound,
+			domain.ID.Hex())
+	}
+
+	return nil
+}
+
+// DeleteDomain deletes the Domain document with the given name.
+func (repo *SQLiteDomainRepository) DeleteDomain(ctx context.Context,
+	name string) error {
+
+	result, err := repo.db.ExecContext(ctx,
+		"DELETE FROM domain WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("%w: failed to delete domain: %w", ErrInsert, err)
+	}
+	if deleted, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInsert, err)
+	} else if deleted == 0 {
+		return fmt.Errorf("%w: no domain found with name: %s", ErrNotFound,
+			name)
+	}
+	return nil
+}
+
+// ListDomains retrieves the Domain documents selected by filter, ordered by
+// name.
+func (repo *SQLiteDomainRepository) ListDomains(ctx context.Context,
+	filter *DomainFilter) ([]*Domain, error) {
+
+	query := "SELECT " + domainColumns + " FROM domain"
+	var args []any
+	if len(filter.CategoryIDs) > 0 {
+		query += fmt.Sprintf(" WHERE category_id IN (%s)",
+			sqlitePlaceholders(len(filter.CategoryIDs)))
+		for _, categoryID := range filter.CategoryIDs {
+			args = append(args, categoryID)
+		}
+	}
+	query += " ORDER BY name"
+	if filter.Limit > 0 || filter.Offset > 0 {
+		// A negative limit is no limit to SQLite.
+		query += " LIMIT ? OFFSET ?"
+		limit := -1
+		if filter.Limit > 0 {
+			limit = filter.Limit
+		}
+		args = append(args, limit, max(filter.Offset, 0))
+	}
+
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to retrieve domains: %w",
+			ErrQuery, err)
+	}
+	defer rows.Close()
+
+	var domains []*Domain
+	for rows.Next() {
+		domain, err := scanSQLiteDomain(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to decode domain: %w",
+				ErrQuery, err)
+		}
+		domains = append(domains, domain)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrQuery, err)
+	}
+
+	return domains, nil
+}
+
+// UpsertDomains stores each of baseDomains in place of the Domain document
+// with the same name, or as a new document, in a single transaction.
+func (repo *SQLiteDomainRepository) UpsertDomains(ctx context.Context,
+	baseDomains []*BaseDomain) ([]*Domain, error) {
+
+	if len(baseDomains) == 0 {
+		return nil, nil
+	}
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	defer tx.Rollback()
+
+	statement, err := tx.PrepareContext(ctx,
+		"INSERT INTO domain (id, created_at, name, category_id, "+
+			"category_name, mcc) VALUES (?, ?, ?, ?, ?, ?) "+
+			"ON CONFLICT (name) DO UPDATE SET "+
+			"category_id = excluded.category_id, "+
+			"category_name = excluded.category_name, mcc = excluded.mcc")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	defer statement.Close()
+
+	names := make([]any, len(baseDomains))
+	for i, baseDomain := range baseDomains {
+		domain := CreateDomain(baseDomain)
+		domain.SetCreatedAt()
+		_, err := statement.ExecContext(ctx, domain.ID.Hex(),
+			int64(domain.CreatedAt), domain.Name, domain.CategoryID,
+			domain.CategoryName, domain.MCC)
+		if err != nil {
+			return nil, fmt.Errorf("%w: domain %s: %w", ErrInsert,
+				domain.Name, err)
+		}
+		names[i] = domain.Name
+	}
+
+	// SQLite bounds the number of parameters of a statement, so the stored
+	// domains are read back in batches.
+	var domains []*Domain
+	for start := 0; start < len(names); start += sqliteMaxBatch {
+		batch := names[start:min(start+sqliteMaxBatch, len(names))]
+		rows, err := tx.QueryContext(ctx,
+			fmt.Sprintf("SELECT %s FROM domain WHERE name IN (%s)",
+				domainColumns, sqlitePlaceholders(len(batch))), batch...)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to retrieve domains: %w",
+				ErrQuery, err)
+		}
+		for rows.Next() {
+			domain, err := scanSQLiteDomain(rows)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("%w: failed to decode domain: %w",
+					ErrQuery, err)
+			}
+			domains = append(domains, domain)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrQuery, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	sortDomains(domains)
+	return domains, nil
+}
+
+// domainColumns are the columns scanSQLiteDomain reads, in order.
+const domainColumns = "id, created_at, name, category_id, category_name, mcc"
+
+// scanSQLiteDomain reads a Domain document from the domainColumns of row.
+func scanSQLiteDomain(row interface{ Scan(...any) error }) (*Domain, error) {
+	var id string
+	var createdAt int64
+	var baseDomain BaseDomain
+	err := row.Scan(&id, &createdAt, &baseDomain.Name,
+		&baseDomain.CategoryID, &baseDomain.CategoryName, &baseDomain.MCC)
+	if err != nil {
+		return nil, err
+	}
+
+	document, err := sqliteDocument(id, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	return &Domain{BaseDocument: document, BaseDomain: &baseDomain}, nil
+}
+
+// SetDomainAlias maps alias to domain in the domain_alias table, replacing the
+// domain it was mapped to, if any.
+func (repo *SQLiteDomainRepository) SetDomainAlias(ctx context.Context,
+	alias, domain string) (*DomainAlias, error) {
+
+	domainAlias := newDomainAlias(alias, domain)
+	_, err := repo.db.ExecContext(ctx,
+		"INSERT INTO domain_alias (id, created_at, alias, domain) "+
+			"VALUES (?, ?, ?, ?) "+
+			"ON CONFLICT (alias) DO UPDATE SET domain = excluded.domain",
+		domainAlias.ID.Hex(), int64(domainAlias.CreatedAt), alias, domain)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	return repo.GetDomainAlias(ctx, alias)
+}
+
+// GetDomainAlias retrieves the DomainAlias document for alias.
+func (repo *SQLiteDomainRepository) GetDomainAlias(ctx context.Context,
+	alias string) (*DomainAlias, error) {
+
+	var id string
+	var createdAt int64
+	var domainAlias DomainAlias
+	err := repo.db.QueryRowContext(ctx,
+		"SELECT id, created_at, alias, domain FROM domain_alias "+
+			"WHERE alias = ?", alias,
+	).Scan(&id, &createdAt, &domainAlias.Alias, &domainAlias.Domain)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: no domain alias found for: %s",
+				ErrNotFound, alias)
+		}
+		return nil, fmt.Errorf("%w: failed to find domain alias: %w",
+			ErrQuery, err)
+	}
+
+	domainAlias.BaseDocument, err = sqliteDocument(id, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	return &domainAlias, nil
+}
+
+// ListDomainAliases returns every DomainAlias document, ordered by alias.
+func (repo *SQLiteDomainRepository) ListDomainAliases(
+	ctx context.Context) ([]*DomainAlias, error) {
+
+	rows, err := repo.db.QueryContext(ctx,
+		"SELECT id, created_at, alias, domain FROM domain_alias "+
+			"ORDER BY alias")
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to retrieve domain aliases: %w",
+			ErrQuery, err)
+	}
+	defer rows.Close()
+
+	var aliases []*DomainAlias
+	for rows.Next() {
+		var id string
+		var createdAt int64
+		var domainAlias DomainAlias
+		if err := rows.Scan(&id, &createdAt, &domainAlias.Alias,
+			&domainAlias.Domain); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrQuery, err)
+		}
+		domainAlias.BaseDocument, err = sqliteDocument(id, createdAt)
+		if err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, &domainAlias)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrQuery, err)
+	}
+
+	return aliases, nil
+}
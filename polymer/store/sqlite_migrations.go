@@ -0,0 +1,349 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// sqliteMigration is a single, versioned change to the SQLite schema.
+type sqliteMigration struct {
+	version     int
+	description string
+	statements  string
+
+	// Checks the data can be migrated before the statements run, if not nil
+	check func(ctx context.Context, tx *sql.Tx) error
+}
+
+// sqliteMigrations lists every schema change in the order it must be applied.
+// Applied migrations must never be edited; append a new one instead.
+var sqliteMigrations = []sqliteMigration{
+	{
+		version:     1,
+		description: "create card tables",
+		statements: `
+CREATE TABLE card (
+	id TEXT PRIMARY KEY,
+	created_at INTEGER NOT NULL,
+	card_key TEXT NOT NULL DEFAULT '',
+	card_issuer TEXT NOT NULL DEFAULT '',
+	card_name TEXT NOT NULL DEFAULT '',
+	card_network TEXT NOT NULL DEFAULT '',
+	card_type TEXT NOT NULL DEFAULT '',
+	card_url TEXT NOT NULL DEFAULT '',
+	annual_fee REAL NOT NULL DEFAULT 0,
+	fx_fee REAL NOT NULL DEFAULT 0,
+	is_fx_fee INTEGER NOT NULL DEFAULT 0,
+	credit_range TEXT NOT NULL DEFAULT '',
+	base_spend_amount REAL NOT NULL DEFAULT 0,
+	base_spend_earn_type TEXT NOT NULL DEFAULT '',
+	base_spend_earn_category TEXT NOT NULL DEFAULT '',
+	base_spend_earn_currency TEXT NOT NULL DEFAULT '',
+	base_spend_earn_valuation REAL NOT NULL DEFAULT 0,
+	base_spend_earn_is_cash INTEGER NOT NULL DEFAULT 0,
+	base_spend_earn_cash_value REAL NOT NULL DEFAULT 0,
+	is_signup_bonus INTEGER NOT NULL DEFAULT 0,
+	signup_bonus_amount TEXT NOT NULL DEFAULT '',
+	signup_bonus_type TEXT NOT NULL DEFAULT '',
+	signup_bonus_category TEXT NOT NULL DEFAULT '',
+	sign_up_bonus_item TEXT NOT NULL DEFAULT '',
+	signup_bonus_spend REAL NOT NULL DEFAULT 0,
+	signup_bonus_length REAL NOT NULL DEFAULT 0,
+	signup_bonus_length_period TEXT NOT NULL DEFAULT '',
+	signup_annual_fee REAL NOT NULL DEFAULT 0,
+	is_signup_annual_fee_waived INTEGER NOT NULL DEFAULT 0,
+	signup_statement_credit REAL NOT NULL DEFAULT 0,
+	signup_bonus_desc TEXT NOT NULL DEFAULT '',
+	trusted_traveler TEXT NOT NULL DEFAULT '',
+	is_trusted_traveler INTEGER NOT NULL DEFAULT 0,
+	lounge_access TEXT NOT NULL DEFAULT '',
+	is_lounge_access INTEGER NOT NULL DEFAULT 0,
+	free_hotel_night TEXT NOT NULL DEFAULT '',
+	is_free_hotel_night INTEGER NOT NULL DEFAULT 0,
+	free_checked_bag TEXT NOT NULL DEFAULT '',
+	is_free_checked_bag INTEGER NOT NULL DEFAULT 0,
+	is_active INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX card_card_key ON card (card_key);
+
+CREATE TABLE card_benefit (
+	card_id TEXT NOT NULL REFERENCES card (id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	benefit_title TEXT NOT NULL DEFAULT '',
+	benefit_desc TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (card_id, position)
+);
+
+CREATE TABLE card_spend_bonus_category (
+	card_id TEXT NOT NULL REFERENCES card (id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	spend_bonus_category_type TEXT NOT NULL DEFAULT '',
+	spend_bonus_category_name TEXT NOT NULL DEFAULT '',
+	spend_bonus_category_id INTEGER NOT NULL DEFAULT 0,
+	spend_bonus_category_group TEXT NOT NULL DEFAULT '',
+	spend_bonus_subcategory_group TEXT NOT NULL DEFAULT '',
+	spend_bonus_desc TEXT NOT NULL DEFAULT '',
+	earn_multiplier REAL NOT NULL DEFAULT 0,
+	is_date_limit INTEGER NOT NULL DEFAULT 0,
+	limit_begin_date TEXT NOT NULL DEFAULT '',
+	limit_end_date TEXT NOT NULL DEFAULT '',
+	is_spend_limit INTEGER NOT NULL DEFAULT 0,
+	spend_limit REAL NOT NULL DEFAULT 0,
+	spend_limit_reset_period TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (card_id, position)
+);
+
+CREATE TABLE card_annual_spend (
+	card_id TEXT NOT NULL REFERENCES card (id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	annual_spend_desc TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (card_id, position)
+);
+`,
+	},
+	{
+		version:     2,
+		description: "create domain table",
+		statements: `
+CREATE TABLE domain (
+	id TEXT PRIMARY KEY,
+	created_at INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	category_id INTEGER NOT NULL DEFAULT 0,
+	category_name TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX domain_name ON domain (name);
+`,
+	},
+	{
+		version:     3,
+		description: "create transaction table",
+		statements: `
+CREATE TABLE "transaction" (
+	id TEXT PRIMARY KEY,
+	created_at INTEGER NOT NULL,
+	transaction_at INTEGER NOT NULL,
+	spend_amount REAL NOT NULL DEFAULT 0,
+	merchant_name TEXT NOT NULL DEFAULT '',
+	merchant_category_id INTEGER NOT NULL DEFAULT 0,
+	merchant_category_name TEXT NOT NULL DEFAULT '',
+	card_key TEXT NOT NULL DEFAULT '',
+	card_name TEXT NOT NULL DEFAULT '',
+	reward_amount REAL NOT NULL DEFAULT 0,
+	reward_currency TEXT NOT NULL DEFAULT '',
+	reward_cash_convertible INTEGER NOT NULL DEFAULT 0,
+	reward_cash_conv_value REAL NOT NULL DEFAULT 0,
+	reward_value REAL NOT NULL DEFAULT 0
+);
+CREATE INDEX transaction_card_key ON "transaction" (card_key, transaction_at);
+`,
+	},
+	{
+		version:     4,
+		description: "add foreign transaction details",
+		statements: `
+ALTER TABLE "transaction" ADD COLUMN merchant_country TEXT NOT NULL DEFAULT '';
+ALTER TABLE "transaction" ADD COLUMN merchant_currency TEXT NOT NULL DEFAULT '';
+ALTER TABLE "transaction" ADD COLUMN reward_fx_fee REAL NOT NULL DEFAULT 0;
+`,
+	},
+	{
+		version:     5,
+		description: "add reward valuation",
+		statements: `
+ALTER TABLE "transaction" ADD COLUMN reward_valuation TEXT NOT NULL DEFAULT '';
+ALTER TABLE "transaction" ADD COLUMN reward_cents_per_point REAL NOT NULL DEFAULT 0;
+`,
+	},
+	{
+		version:     6,
+		description: "add sign-up bonus value",
+		statements: `
+ALTER TABLE "transaction" ADD COLUMN reward_signup_bonus_value REAL NOT NULL DEFAULT 0;
+`,
+	},
+	{
+		version:     7,
+		description: "create wallet tables",
+		statements: `
+CREATE TABLE wallet (
+	id TEXT PRIMARY KEY,
+	created_at INTEGER NOT NULL,
+	name TEXT NOT NULL DEFAULT '',
+	valuation TEXT NOT NULL DEFAULT '',
+	valuation_table TEXT NOT NULL DEFAULT '{}',
+	prioritize_signup_bonus INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE wallet_card (
+	wallet_id TEXT NOT NULL REFERENCES wallet (id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	card_key TEXT NOT NULL,
+	opened_on INTEGER,
+	PRIMARY KEY (wallet_id, position)
+);
+
+ALTER TABLE "transaction" ADD COLUMN wallet_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX transaction_wallet_id ON "transaction" (wallet_id, transaction_at);
+`,
+	},
+	{
+		version:     8,
+		description: "add card catalog status",
+		statements: `
+ALTER TABLE card ADD COLUMN status TEXT NOT NULL DEFAULT '';
+ALTER TABLE card ADD COLUMN synced_at INTEGER NOT NULL DEFAULT 0;
+`,
+	},
+	{
+		version:     9,
+		description: "create card revision table",
+		statements: `
+CREATE TABLE card_revision (
+	id TEXT PRIMARY KEY,
+	created_at INTEGER NOT NULL,
+	card_key TEXT NOT NULL,
+	revision INTEGER NOT NULL,
+	observed_at INTEGER NOT NULL,
+	card_detail TEXT NOT NULL,
+	UNIQUE (card_key, revision)
+);
+CREATE INDEX card_revision_observed_at ON card_revision (card_key, observed_at);
+`,
+	},
+	{
+		version:     10,
+		description: "add card fetch time",
+		statements: `
+ALTER TABLE card ADD COLUMN fetched_at INTEGER NOT NULL DEFAULT 0;
+`,
+	},
+	{
+		version:     11,
+		description: "add merchant category codes",
+		statements: `
+ALTER TABLE domain ADD COLUMN mcc TEXT NOT NULL DEFAULT '';
+ALTER TABLE "transaction" ADD COLUMN merchant_mcc TEXT NOT NULL DEFAULT '';
+`,
+	},
+	{
+		version:     12,
+		description: "create domain alias table",
+		statements: `
+CREATE TABLE domain_alias (
+	id TEXT PRIMARY KEY,
+	created_at INTEGER NOT NULL,
+	alias TEXT NOT NULL UNIQUE,
+	domain TEXT NOT NULL
+);
+`,
+	},
+	{
+		version:     13,
+		description: "create category rule table",
+		statements: `
+CREATE TABLE category_rule (
+	id TEXT PRIMARY KEY,
+	created_at INTEGER NOT NULL,
+	name TEXT NOT NULL DEFAULT '',
+	wallet_id TEXT NOT NULL DEFAULT '',
+	position INTEGER NOT NULL,
+	match TEXT NOT NULL,
+	field TEXT NOT NULL DEFAULT '',
+	pattern TEXT NOT NULL DEFAULT '',
+	keywords TEXT NOT NULL DEFAULT '[]',
+	category_id INTEGER NOT NULL
+);
+CREATE INDEX category_rule_wallet_id ON category_rule (wallet_id, position);
+
+ALTER TABLE "transaction" ADD COLUMN merchant_descriptor TEXT NOT NULL DEFAULT '';
+`,
+	},
+	{
+		version:     14,
+		description: "make domain names unique",
+		check:       checkUniqueDomainNames,
+		statements: `
+DROP INDEX domain_name;
+CREATE UNIQUE INDEX domain_name ON domain (name);
+CREATE INDEX domain_category_id ON domain (category_id, name);
+`,
+	},
+	{
+		version:     15,
+		description: "add recommended cards to transactions",
+		statements: `
+ALTER TABLE "transaction" ADD COLUMN recommended_card_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE "transaction" ADD COLUMN recommended_card_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE "transaction" ADD COLUMN recommended_reward_amount REAL NOT NULL DEFAULT 0;
+ALTER TABLE "transaction" ADD COLUMN recommended_reward_currency TEXT NOT NULL DEFAULT '';
+ALTER TABLE "transaction" ADD COLUMN recommended_reward_cash_convertible INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE "transaction" ADD COLUMN recommended_reward_cash_conv_value REAL NOT NULL DEFAULT 0;
+ALTER TABLE "transaction" ADD COLUMN recommended_reward_value REAL NOT NULL DEFAULT 0;
+ALTER TABLE "transaction" ADD COLUMN recommended_reward_fx_fee REAL NOT NULL DEFAULT 0;
+ALTER TABLE "transaction" ADD COLUMN recommended_reward_valuation TEXT NOT NULL DEFAULT '';
+ALTER TABLE "transaction" ADD COLUMN recommended_reward_cents_per_point REAL NOT NULL DEFAULT 0;
+ALTER TABLE "transaction" ADD COLUMN recommended_reward_signup_bonus_value REAL NOT NULL DEFAULT 0;
+`,
+	},
+	{
+		version:     16,
+		description: "record how transaction merchants were categorized",
+		statements: `
+ALTER TABLE "transaction" ADD COLUMN merchant_category_source TEXT NOT NULL DEFAULT '';
+`,
+	},
+	{
+		version:     17,
+		description: "default domain categories to uncategorized",
+		statements: `
+CREATE TABLE domain_new (
+	id TEXT PRIMARY KEY,
+	created_at INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	category_id INTEGER NOT NULL DEFAULT -1,
+	category_name TEXT NOT NULL DEFAULT '',
+	mcc TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO domain_new (id, created_at, name, category_id, category_name, mcc)
+	SELECT id, created_at, name, category_id, category_name, mcc FROM domain;
+DROP TABLE domain;
+ALTER TABLE domain_new RENAME TO domain;
+CREATE UNIQUE INDEX domain_name ON domain (name);
+CREATE INDEX domain_category_id ON domain (category_id, name);
+`,
+	},
+}
+
+// checkUniqueDomainNames fails with ErrDuplicate, listing the names, if any
+// domain name is stored more than once, as older versions allowed. Which of
+// the duplicates to keep is left to the user rather than guessed.
+func checkUniqueDomainNames(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, "SELECT name, COUNT(*) FROM domain "+
+		"GROUP BY name HAVING COUNT(*) > 1 ORDER BY name")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var duplicates []string
+	for rows.Next() {
+		var name string
+		var count int
+		if err := rows.Scan(&name, &count); err != nil {
+			return err
+		}
+		duplicates = append(duplicates, fmt.Sprintf("%s (%d)", name, count))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("%w: domain names stored more than once, delete "+
+			"the extra ones and try again: %s", ErrDuplicate,
+			strings.Join(duplicates, ", "))
+	}
+	return nil
+}
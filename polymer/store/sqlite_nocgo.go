@@ -0,0 +1,13 @@
+//go:build !cgo
+
+package store
+
+import "strings"
+
+// isSQLiteUniqueError reports whether err is the violation of a UNIQUE
+// constraint or index. Without cgo the driver's error type is not available,
+// so the message SQLite gives such violations is matched instead.
+func isSQLiteUniqueError(err error) bool {
+	return err != nil &&
+		strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
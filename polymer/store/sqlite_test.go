@@ -0,0 +1,451 @@
+//go:build cgo
+
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayushh-vermaa/polymer/internal/rewards"
+	"github.com/ayushh-vermaa/polymer/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newSQLiteRepository returns a Repository backed by a fresh in-memory
+// SQLite database, closed when the test ends.
+func newSQLiteRepository(t *testing.T) *store.Repository {
+	t.Helper()
+	db, err := store.ConnectSQLite(context.Background(), ":memory:")
+	if err != nil {
+		t.Fatalf("ConnectSQLite() error = %v", err)
+	}
+	repo := store.NewSQLiteRepository(db)
+	t.Cleanup(func() { repo.Close(context.Background()) })
+	return repo
+}
+
+// schemaVersions returns the migration versions recorded in db.
+func schemaVersions(t *testing.T, db *sql.DB) []int {
+	t.Helper()
+	rows, err := db.Query("SELECT version FROM schema_migrations " +
+		"ORDER BY version")
+	if err != nil {
+		t.Fatalf("failed to read schema_migrations: %v", err)
+	}
+	defer rows.Close()
+	var versions []int
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			t.Fatalf("failed to read schema_migrations: %v", err)
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+func TestMigrateSQLite(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "polymer?#.db")
+
+	db, err := store.ConnectSQLite(ctx, path)
+	if err != nil {
+		t.Fatalf("ConnectSQLite() error = %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("ConnectSQLite() did not create %s: %v", path, err)
+	}
+	versions := schemaVersions(t, db)
+	if len(versions) == 0 || versions[0] != 1 ||
+		versions[len(versions)-1] != len(versions) {
+		t.Fatalf("migrations applied = %v, want 1 to the latest in turn",
+			versions)
+	}
+	repo := store.NewSQLiteRepository(db)
+	if _, err := repo.Domains.InsertDomain(ctx,
+		&store.BaseDomain{Name: "amazon.com", CategoryID: 3}); err != nil {
+		t.Fatalf("InsertDomain() error = %v", err)
+	}
+	if err := repo.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Reopening the database finds it migrated, with its data.
+	db, err = store.ConnectSQLite(ctx, path)
+	if err != nil {
+		t.Fatalf("ConnectSQLite() reopening error = %v", err)
+	}
+	defer db.Close()
+	if err := store.MigrateSQLite(ctx, db); err != nil {
+		t.Fatalf("MigrateSQLite() error = %v", err)
+	}
+	if got := schemaVersions(t, db); !slices.Equal(got, versions) {
+		t.Errorf("migrations after reopening = %v, want %v", got, versions)
+	}
+	domain, err := store.NewSQLiteRepository(db).Domains.GetDomainByName(ctx,
+		"amazon.com")
+	if err != nil || domain.CategoryID != 3 {
+		t.Errorf("GetDomainByName() = %+v, %v, want the stored domain",
+			domain, err)
+	}
+}
+
+func TestMigrateSQLiteDuplicateDomains(t *testing.T) {
+	ctx := context.Background()
+	db, err := store.ConnectSQLite(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("ConnectSQLite() error = %v", err)
+	}
+	defer db.Close()
+
+	// Take the database back to before domain names were unique, undoing
+	// the later migrations, and store duplicates as older versions could.
+	statements := []string{
+		"DELETE FROM schema_migrations WHERE version >= 14",
+		"DROP INDEX domain_category_id",
+		"DROP INDEX domain_name",
+		"CREATE INDEX domain_name ON domain (name)",
+		"INSERT INTO domain (id, created_at, name) VALUES " +
+			"('a', 1, 'amazon.com'), ('b', 2, 'amazon.com'), " +
+			"('c', 3, 'ebay.com'), ('d', 4, 'target.com'), " +
+			"('e', 5, 'target.com'), ('f', 6, 'target.com')",
+	}
+	for _, column := range []string{"recommended_card_key",
+		"recommended_card_name", "recommended_reward_amount",
+		"recommended_reward_currency", "recommended_reward_cash_convertible",
+		"recommended_reward_cash_conv_value", "recommended_reward_value",
+		"recommended_reward_fx_fee", "recommended_reward_valuation",
+		"recommended_reward_cents_per_point",
+		"recommended_reward_signup_bonus_value",
+		"merchant_category_source"} {
+		statements = append(statements,
+			`ALTER TABLE "transaction" DROP COLUMN `+column)
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			t.Fatalf("failed to set up the old schema: %v", err)
+		}
+	}
+
+	err = store.MigrateSQLite(ctx, db)
+	if !errors.Is(err, store.ErrDuplicate) ||
+		!strings.Contains(err.Error(), "amazon.com (2), target.com (3)") {
+		t.Fatalf("MigrateSQLite() error = %v, want the duplicates listed",
+			err)
+	}
+	var domains int
+	if err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM domain").Scan(&domains); err != nil {
+		t.Fatalf("failed to count domains: %v", err)
+	}
+	if domains != 6 {
+		t.Errorf("domains after the failed migration = %d, want all 6",
+			domains)
+	}
+	if got := schemaVersions(t, db); got[len(got)-1] != 13 {
+		t.Errorf("migrations applied = %v, want up to 13", got)
+	}
+
+	// Once the duplicates are dealt with, the migration goes through.
+	if _, err := db.ExecContext(ctx,
+		"DELETE FROM domain WHERE id IN ('b', 'e', 'f')"); err != nil {
+		t.Fatalf("failed to delete duplicates: %v", err)
+	}
+	if err := store.MigrateSQLite(ctx, db); err != nil {
+		t.Fatalf("MigrateSQLite() error = %v", err)
+	}
+}
+
+func TestSQLiteCardRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteRepository(t)
+
+	// More keys than SQLite binds to a single statement, of which some are
+	// stored.
+	var allKeys []string
+	for i := range 40000 {
+		allKeys = append(allKeys, fmt.Sprintf("card-%05d", i))
+	}
+	for _, cardKey := range allKeys[:600] {
+		if _, err := repo.Cards.InsertCard(ctx, &rewards.CardDetail{
+			CardKey: cardKey, CardName: "Card " + cardKey}); err != nil {
+			t.Fatalf("InsertCard() error = %v", err)
+		}
+	}
+	if _, err := repo.Cards.InsertCard(ctx, &rewards.CardDetail{
+		CardKey: "card-00000", CardName: "Replaced",
+		SpendBonusCategory: []rewards.SpendBonusCategory{{
+			SpendBonusCategoryName: "Dining", SpendBonusCategoryID: 2,
+			EarnMultiplier: 4}},
+	}); err != nil {
+		t.Fatalf("InsertCard() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		cardKeys []string
+		want     int
+	}{
+		{"no keys", nil, 0},
+		{"missing card left out", []string{"card-00001", "no-such-card"}, 1},
+		{"more keys than a statement binds", allKeys, 600},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cards, err := repo.Cards.GetCardsByKeys(ctx, test.cardKeys)
+			if err != nil {
+				t.Fatalf("GetCardsByKeys() error = %v", err)
+			}
+			if len(cards) != test.want {
+				t.Errorf("GetCardsByKeys() = %d cards, want %d", len(cards),
+					test.want)
+			}
+		})
+	}
+
+	cards, err := repo.Cards.GetCardsByKeys(ctx, []string{"card-00000"})
+	if err != nil {
+		t.Fatalf("GetCardsByKeys() error = %v", err)
+	}
+	card := cards["card-00000"]
+	if card == nil || card.CardDetail.CardName != "Replaced" ||
+		len(card.CardDetail.SpendBonusCategory) != 1 ||
+		card.CardDetail.SpendBonusCategory[0].EarnMultiplier != 4 {
+		t.Errorf("GetCardsByKeys() = %+v, want the replaced card with its "+
+			"bonus", card)
+	}
+}
+
+func TestSQLiteDomainRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteRepository(t)
+	inserted, err := repo.Domains.InsertDomain(ctx, &store.BaseDomain{
+		Name: "starbucks.com", CategoryID: 2, CategoryName: "Dining",
+		MCC: "5814"})
+	if err != nil {
+		t.Fatalf("InsertDomain() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		domainName string
+		wantErr    error
+	}{
+		{"stored", "starbucks.com", nil},
+		{"missing", "missing.com", store.ErrNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := repo.Domains.GetDomainByName(ctx, test.domainName)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("GetDomainByName() error = %v, want %v", err,
+					test.wantErr)
+			}
+			if err == nil && (got.ID != inserted.ID ||
+				*got.BaseDomain != *inserted.BaseDomain) {
+				t.Errorf("GetDomainByName() = %+v, want %+v",
+					got.BaseDomain, inserted.BaseDomain)
+			}
+		})
+	}
+}
+
+func TestSQLiteTransactionRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteRepository(t)
+	day := func(d int) time.Time {
+		return time.Date(2026, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+	recommended := &store.CardDetails{CardKey: "citi-doublecash",
+		RewardDetails: store.RewardDetails{Value: 0.02}}
+	for _, base := range []*store.BaseTransaction{
+		{WalletID: "w1", TransactionAt: day(3), SpendAmount: 30,
+			CardDetails:     store.CardDetails{CardKey: "amex-gold"},
+			RecommendedCard: recommended},
+		{WalletID: "w1", TransactionAt: day(1), SpendAmount: 10,
+			CardDetails: store.CardDetails{CardKey: "citi-doublecash"}},
+		{WalletID: "w2", TransactionAt: day(2), SpendAmount: 20,
+			CardDetails: store.CardDetails{CardKey: "amex-gold"}},
+	} {
+		if _, err := repo.Transactions.InsertTransaction(ctx,
+			base); err != nil {
+			t.Fatalf("InsertTransaction() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter store.TransactionFilter
+		want   []float64
+	}{
+		{"all, oldest first", store.TransactionFilter{},
+			[]float64{10, 20, 30}},
+		{"wallet", store.TransactionFilter{WalletID: "w1"},
+			[]float64{10, 30}},
+		{"cards", store.TransactionFilter{CardKeys: []string{"amex-gold"}},
+			[]float64{20, 30}},
+		{"period", store.TransactionFilter{From: day(2), To: day(3)},
+			[]float64{20}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transactions, err := repo.Transactions.ListTransactions(ctx,
+				&test.filter)
+			if err != nil {
+				t.Fatalf("ListTransactions() error = %v", err)
+			}
+			var got []float64
+			for _, transaction := range transactions {
+				got = append(got, transaction.SpendAmount)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("ListTransactions() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	transactions, err := repo.Transactions.ListTransactions(ctx,
+		&store.TransactionFilter{From: day(3)})
+	if err != nil || len(transactions) != 1 {
+		t.Fatalf("ListTransactions() = %d, %v, want 1", len(transactions),
+			err)
+	}
+	got := transactions[0]
+	if !got.TransactionAt.Equal(day(3)) || got.RecommendedCard == nil ||
+		*got.RecommendedCard != *recommended {
+		t.Errorf("ListTransactions() = %+v, want the stored transaction",
+			got.BaseTransaction)
+	}
+}
+
+func TestSQLiteCategoryRuleRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteRepository(t)
+	for _, base := range []*store.BaseCategoryRule{
+		{Name: "Uber", Match: store.RuleMatchRegex,
+			Field: store.RuleFieldDescriptor, Pattern: `^uber\b`,
+			CategoryID: 1031},
+		{Name: "Broken", Match: store.RuleMatchRegex, Pattern: "(",
+			CategoryID: 2},
+		{Name: "Amazon", WalletID: "w1", Match: store.RuleMatchSuffix,
+			Pattern: "amazon.com", CategoryID: 1083},
+	} {
+		if _, err := repo.CategoryRules.InsertCategoryRule(ctx,
+			base); err != nil {
+			t.Fatalf("InsertCategoryRule() error = %v", err)
+		}
+	}
+
+	rules, err := repo.CategoryRules.ListCategoryRules(ctx, "w1")
+	if err != nil {
+		t.Fatalf("ListCategoryRules() error = %v", err)
+	}
+	var names []string
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+	if want := []string{"Amazon", "Uber", "Broken"}; !slices.Equal(names,
+		want) {
+		t.Fatalf("ListCategoryRules() = %v, want %v", names, want)
+	}
+	// Regex patterns come back compiled, matching regardless of case.
+	if rules[1].Regexp == nil || !rules[1].Regexp.MatchString("UBER TRIP") {
+		t.Errorf("Regexp = %v, want the compiled pattern", rules[1].Regexp)
+	}
+	if rules[0].Regexp != nil || rules[2].Regexp != nil {
+		t.Errorf("Regexp set for a suffix rule or an invalid pattern")
+	}
+
+	if err := repo.CategoryRules.DeleteCategoryRule(ctx,
+		rules[0].ID.Hex()); err != nil {
+		t.Fatalf("DeleteCategoryRule() error = %v", err)
+	}
+	err = repo.CategoryRules.DeleteCategoryRule(ctx, rules[0].ID.Hex())
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("DeleteCategoryRule() of a deleted rule error = %v, "+
+			"want %v", err, store.ErrNotFound)
+	}
+	repo.Close(ctx)
+	err = repo.CategoryRules.DeleteCategoryRule(ctx, rules[1].ID.Hex())
+	if !errors.Is(err, store.ErrInsert) {
+		t.Errorf("DeleteCategoryRule() on a closed database error = %v, "+
+			"want %v", err, store.ErrInsert)
+	}
+}
+
+func TestSQLiteDomainFilterAndDelete(t *testing.T) {
+	ctx := context.Background()
+	db, err := store.ConnectSQLite(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("ConnectSQLite() error = %v", err)
+	}
+	repo := store.NewSQLiteRepository(db)
+	defer repo.Close(ctx)
+	if _, err := repo.Domains.UpsertDomains(ctx, []*store.BaseDomain{
+		{Name: "amazon.com", CategoryID: 1082},
+		{Name: "chipotle.com", CategoryID: 1001},
+		{Name: "example.com", CategoryID: store.UncategorizedID},
+	}); err != nil {
+		t.Fatalf("UpsertDomains() error = %v", err)
+	}
+	// A domain stored without a category, as by hand, is uncategorized.
+	if _, err := db.ExecContext(ctx, "INSERT INTO domain "+
+		"(id, created_at, name) VALUES (?, 0, 'bare.com')",
+		primitive.NewObjectID().Hex()); err != nil {
+		t.Fatalf("failed to insert domain: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter store.DomainFilter
+		want   []string
+	}{
+		{"all", store.DomainFilter{}, []string{"amazon.com", "bare.com",
+			"chipotle.com", "example.com"}},
+		{"uncategorized", store.DomainFilter{
+			CategoryIDs: []int{store.UncategorizedID}},
+			[]string{"bare.com", "example.com"}},
+		{"categories", store.DomainFilter{CategoryIDs: []int{1001, 1082}},
+			[]string{"amazon.com", "chipotle.com"}},
+		{"page", store.DomainFilter{Offset: 1, Limit: 2},
+			[]string{"bare.com", "chipotle.com"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			domains, err := repo.Domains.ListDomains(ctx, &test.filter)
+			if err != nil {
+				t.Fatalf("ListDomains() error = %v", err)
+			}
+			var got []string
+			for _, domain := range domains {
+				got = append(got, domain.Name)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("ListDomains() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if err := repo.Domains.DeleteDomain(ctx, "amazon.com"); err != nil {
+		t.Fatalf("DeleteDomain() error = %v", err)
+	}
+	err = repo.Domains.DeleteDomain(ctx, "amazon.com")
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("DeleteDomain() of a deleted domain error = %v, want %v",
+			err, store.ErrNotFound)
+	}
+	db.Close()
+	err = repo.Domains.DeleteDomain(ctx, "chipotle.com")
+	if !errors.Is(err, store.ErrInsert) {
+		t.Errorf("DeleteDomain() on a closed database error = %v, want %v",
+			err, store.ErrInsert)
+	}
+}
@@ -0,0 +1,262 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLiteTransactionRepository is a TransactionRepository backed by a SQLite
+// database. Merchant and card details are flattened into the transaction
+// table.
+type SQLiteTransactionRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteTransactionRepository returns a SQLiteTransactionRepository using
+// the given database.
+func NewSQLiteTransactionRepository(db *sql.DB) *SQLiteTransactionRepository {
+	return &SQLiteTransactionRepository{db: db}
+}
+
+// InsertTransaction inserts a new Transaction document into the transaction
+// table.
+func (repo *SQLiteTransactionRepository) InsertTransaction(
+	ctx context.Context, baseTransaction *BaseTransaction) (*Transaction,
+	error) {
+
+	transaction := CreateTransaction(baseTransaction)
+	transaction.SetCreatedAt()
+
+	columns := append([]string{"id", "created_at"}, transactionColumns...)
+	values := append([]any{transaction.ID.Hex(),
+		int64(transaction.CreatedAt)},
+		transactionValues(transaction.BaseTransaction)...)
+	query := fmt.Sprintf(`INSERT INTO "transaction" (%s) VALUES (%s)`,
+		strings.Join(columns, ", "), sqlitePlaceholders(len(columns)))
+	if _, err := repo.db.ExecContext(ctx, query, values...); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	return &transaction, nil
+}
+
+// ListTransactions retrieves the Transaction documents selected by filter,
+// oldest first.
+func (repo *SQLiteTransactionRepository) ListTransactions(
+	ctx context.Context, filter *TransactionFilter) ([]*Transaction, error) {
+
+	var conditions []string
+	var args []any
+	if filter.WalletID != "" {
+		conditions = append(conditions, "wallet_id = ?")
+		args = append(args, filter.WalletID)
+	}
+	if len(filter.CardKeys) > 0 {
+		conditions = append(conditions, fmt.Sprintf("card_key IN (%s)",
+			sqlitePlaceholders(len(filter.CardKeys))))
+		for _, cardKey := range filter.CardKeys {
+			args = append(args, cardKey)
+		}
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "transaction_at >= ?")
+		args = append(args, filter.From.UnixMilli())
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "transaction_at < ?")
+		args = append(args, filter.To.UnixMilli())
+	}
+
+	query := fmt.Sprintf(`SELECT id, created_at, %s FROM "transaction"`,
+		strings.Join(transactionColumns, ", "))
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY transaction_at"
+
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to retrieve transactions: %w",
+			ErrQuery, err)
+	}
+	defer rows.Close()
+
+	var transactions []*Transaction
+	for rows.Next() {
+		var id string
+		var createdAt int64
+		var base BaseTransaction
+		row := sqliteTransactionRow{transaction: &base}
+		dest := append([]any{&id, &createdAt}, row.fields()...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("%w: failed to decode transaction: %w",
+				ErrQuery, err)
+		}
+		row.finish()
+
+		document, err := sqliteDocument(id, createdAt)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, &Transaction{
+			BaseDocument:    document,
+			BaseTransaction: &base,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrQuery, err)
+	}
+
+	return transactions, nil
+}
+
+// transactionColumns lists the transaction table columns holding the fields of
+// a BaseTransaction, in the order used by transactionValues and
+// sqliteTransactionRow.fields.
+var transactionColumns = []string{
+	"wallet_id",
+	"transaction_at",
+	"spend_amount",
+	"merchant_name",
+	"merchant_category_id",
+	"merchant_category_name",
+	"merchant_country",
+	"merchant_currency",
+	"merchant_mcc",
+	"merchant_descriptor",
+	"merchant_category_source",
+	"card_key",
+	"card_name",
+	"reward_amount",
+	"reward_currency",
+	"reward_cash_convertible",
+	"reward_cash_conv_value",
+	"reward_value",
+	"reward_fx_fee",
+	"reward_valuation",
+	"reward_cents_per_point",
+	"reward_signup_bonus_value",
+	"recommended_card_key",
+	"recommended_card_name",
+	"recommended_reward_amount",
+	"recommended_reward_currency",
+	"recommended_reward_cash_convertible",
+	"recommended_reward_cash_conv_value",
+	"recommended_reward_value",
+	"recommended_reward_fx_fee",
+	"recommended_reward_valuation",
+	"recommended_reward_cents_per_point",
+	"recommended_reward_signup_bonus_value",
+}
+
+// transactionValues returns the fields of transaction in the order of
+// transactionColumns.
+func transactionValues(transaction *BaseTransaction) []any {
+	merchant := transaction.MerchantDetails
+	card := transaction.CardDetails
+	reward := card.RewardDetails
+	var recommended CardDetails
+	if transaction.RecommendedCard != nil {
+		recommended = *transaction.RecommendedCard
+	}
+	recommendedReward := recommended.RewardDetails
+	return []any{
+		transaction.WalletID,
+		transaction.TransactionAt.UnixMilli(),
+		transaction.SpendAmount,
+		merchant.DomainName,
+		merchant.CategoryID,
+		merchant.CategoryName,
+		merchant.Country,
+		merchant.Currency,
+		merchant.MCC,
+		merchant.Descriptor,
+		merchant.CategorySource,
+		card.CardKey,
+		card.CardName,
+		reward.Amount,
+		reward.Currency,
+		reward.CashConvertible,
+		reward.CashConvValue,
+		reward.Value,
+		reward.FxFee,
+		reward.Valuation,
+		reward.CentsPerPoint,
+		reward.SignupBonusValue,
+		recommended.CardKey,
+		recommended.CardName,
+		recommendedReward.Amount,
+		recommendedReward.Currency,
+		recommendedReward.CashConvertible,
+		recommendedReward.CashConvValue,
+		recommendedReward.Value,
+		recommendedReward.FxFee,
+		recommendedReward.Valuation,
+		recommendedReward.CentsPerPoint,
+		recommendedReward.SignupBonusValue,
+	}
+}
+
+// sqliteTransactionRow scans a transaction row into a BaseTransaction,
+// converting the columns that are not stored in their Go representation.
+type sqliteTransactionRow struct {
+	transaction   *BaseTransaction
+	transactionAt int64
+	recommended   CardDetails // Empty card key if none
+}
+
+// fields returns scan destinations in the order of transactionColumns.
+func (row *sqliteTransactionRow) fields() []any {
+	merchant := &row.transaction.MerchantDetails
+	card := &row.transaction.CardDetails
+	reward := &card.RewardDetails
+	recommendedReward := &row.recommended.RewardDetails
+	return []any{
+		&row.transaction.WalletID,
+		&row.transactionAt,
+		&row.transaction.SpendAmount,
+		&merchant.DomainName,
+		&merchant.CategoryID,
+		&merchant.CategoryName,
+		&merchant.Country,
+		&merchant.Currency,
+		&merchant.MCC,
+		&merchant.Descriptor,
+		&merchant.CategorySource,
+		&card.CardKey,
+		&card.CardName,
+		&reward.Amount,
+		&reward.Currency,
+		&reward.CashConvertible,
+		&reward.CashConvValue,
+		&reward.Value,
+		&reward.FxFee,
+		&reward.Valuation,
+		&reward.CentsPerPoint,
+		&reward.SignupBonusValue,
+		&row.recommended.CardKey,
+		&row.recommended.CardName,
+		&recommendedReward.Amount,
+		&recommendedReward.Currency,
+		&recommendedReward.CashConvertible,
+		&recommendedReward.CashConvValue,
+		&recommendedReward.Value,
+		&recommendedReward.FxFee,
+		&recommendedReward.Valuation,
+		&recommendedReward.CentsPerPoint,
+		&recommendedReward.SignupBonusValue,
+	}
+}
+
+// finish converts the scanned columns into their Go representation.
+func (row *sqliteTransactionRow) finish() {
+	row.transaction.TransactionAt = time.UnixMilli(row.transactionAt)
+	if row.recommended.CardKey != "" {
+		recommended := row.recommended
+		row.transaction.RecommendedCard = &recommended
+	}
+}
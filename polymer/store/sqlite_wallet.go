@@ -0,0 +1,266 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SQLiteWalletRepository is a WalletRepository backed by a SQLite database.
+// A wallet's cards are kept in the wallet_card table, in order.
+type SQLiteWalletRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteWalletRepository returns a SQLiteWalletRepository using the given
+// database.
+func NewSQLiteWalletRepository(db *sql.DB) *SQLiteWalletRepository {
+	return &SQLiteWalletRepository{db: db}
+}
+
+// InsertWallet inserts a new Wallet document into the wallet tables.
+func (repo *SQLiteWalletRepository) InsertWallet(ctx context.Context,
+	baseWallet *BaseWallet) (*Wallet, error) {
+
+	wallet := CreateWallet(baseWallet)
+	wallet.SetCreatedAt()
+
+	valuationTable, err := json.Marshal(wallet.ValuationTable)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO wallet (id, created_at, name, valuation, "+
+			"valuation_table, prioritize_signup_bonus) "+
+			"VALUES (?, ?, ?, ?, ?, ?)",
+		wallet.ID.Hex(), int64(wallet.CreatedAt), wallet.Name,
+		wallet.Valuation, string(valuationTable),
+		wallet.PrioritizeSignupBonus)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	if err := insertSQLiteWalletCards(ctx, tx, &wallet); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	return &wallet, nil
+}
+
+// GetWallet retrieves a Wallet document by its hex encoded ID.
+func (repo *SQLiteWalletRepository) GetWallet(ctx context.Context,
+	id string) (*Wallet, error) {
+
+	var createdAt int64
+	var valuationTable string
+	var baseWallet BaseWallet
+	err := repo.db.QueryRowContext(ctx,
+		"SELECT created_at, name, valuation, valuation_table, "+
+			"prioritize_signup_bonus FROM wallet WHERE id = ?", id,
+	).Scan(&createdAt, &baseWallet.Name, &baseWallet.Valuation,
+		&valuationTable, &baseWallet.PrioritizeSignupBonus)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: no wallet found with id: %s",
+				ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("%w: failed to find wallet: %w", ErrQuery, err)
+	}
+
+	if err := json.Unmarshal([]byte(valuationTable),
+		&baseWallet.ValuationTable); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode valuation table: %w",
+			ErrQuery, err)
+	}
+
+	rows, err := repo.db.QueryContext(ctx,
+		"SELECT card_key, opened_on FROM wallet_card WHERE wallet_id = ? "+
+			"ORDER BY position", id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to find wallet cards: %w",
+			ErrQuery, err)
+	}
+	defer rows.Close()
+
+	baseWallet.Cards = []WalletCard{}
+	for rows.Next() {
+		var card WalletCard
+		var openedOn sql.NullInt64
+		if err := rows.Scan(&card.CardKey, &openedOn); err != nil {
+			return nil, fmt.Errorf("%w: failed to decode wallet card: %w",
+				ErrQuery, err)
+		}
+		if openedOn.Valid {
+			card.OpenedOn = time.UnixMilli(openedOn.Int64).UTC()
+		}
+		baseWallet.Cards = append(baseWallet.Cards, card)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to read wallet cards: %w",
+			ErrQuery, err)
+	}
+
+	document, err := sqliteDocument(id, createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Wallet{BaseDocument: document, BaseWallet: &baseWallet}, nil
+}
+
+// UpdateWallet replaces the stored Wallet document with the same ID as wallet.
+func (repo *SQLiteWalletRepository) UpdateWallet(ctx context.Context,
+	wallet *Wallet) error {
+
+	valuationTable, err := json.Marshal(wallet.ValuationTable)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE wallet SET name = ?, valuation = ?, valuation_table = ?, "+
+			"prioritize_signup_bonus = ? WHERE id = ?",
+		wallet.Name, wallet.Valuation, string(valuationTable),
+		wallet.PrioritizeSignupBonus, wallet.ID.Hex())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInsert, err)
+	} else if updated == 0 {
+		return fmt.Errorf("%w: no wallet found with id: %s", ErrNotFound,
+			wallet.ID.Hex())
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM wallet_card WHERE wallet_id = ?", wallet.ID.Hex())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	if err := insertSQLiteWalletCards(ctx, tx, wallet); err != nil {
+		return fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	return nil
+}
+
+// AddWalletCard appends card to the cards of the stored wallet with the
+// given id, in a transaction so that concurrent changes to its cards do not
+// interleave, and returns the updated wallet. It fails with ErrDuplicate if
+// the wallet already holds the card.
+func (repo *SQLiteWalletRepository) AddWalletCard(ctx context.Context,
+	id string, card WalletCard) (*Wallet, error) {
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	defer tx.Rollback()
+
+	var held bool
+	var position int
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM wallet_card WHERE wallet_id = ? AND "+
+			"card_key = ?), (SELECT COALESCE(MAX(position) + 1, 0) "+
+			"FROM wallet_card WHERE wallet_id = ?) FROM wallet WHERE id = ?",
+		id, card.CardKey, id, id).Scan(&held, &position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: no wallet found with id: %s", ErrNotFound,
+			id)
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: failed to find wallet: %w", ErrQuery, err)
+	}
+	if held {
+		return nil, fmt.Errorf("%w: wallet already holds card: %s",
+			ErrDuplicate, card.CardKey)
+	}
+
+	var openedOn sql.NullInt64
+	if !card.OpenedOn.IsZero() {
+		openedOn = sql.NullInt64{Int64: card.OpenedOn.UnixMilli(), Valid: true}
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO wallet_card (wallet_id, position, card_key, opened_on) "+
+			"VALUES (?, ?, ?, ?)", id, position, card.CardKey, openedOn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+
+	return repo.GetWallet(ctx, id)
+}
+
+// RemoveWalletCard deletes the card with cardKey from the cards of the
+// stored wallet with the given id, and returns the updated wallet. It fails
+// with ErrNotFound if the wallet does not hold the card.
+func (repo *SQLiteWalletRepository) RemoveWalletCard(ctx context.Context,
+	id, cardKey string) (*Wallet, error) {
+
+	result, err := repo.db.ExecContext(ctx,
+		"DELETE FROM wallet_card WHERE wallet_id = ? AND card_key = ?",
+		id, cardKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	}
+	wallet, err := repo.GetWallet(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if removed, err := result.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInsert, err)
+	} else if removed == 0 {
+		return nil, fmt.Errorf("%w: wallet does not hold card: %s",
+			ErrNotFound, cardKey)
+	}
+
+	return wallet, nil
+}
+
+// insertSQLiteWalletCards inserts the cards of wallet into the wallet_card
+// table using tx.
+func insertSQLiteWalletCards(ctx context.Context, tx *sql.Tx,
+	wallet *Wallet) error {
+
+	for position, card := range wallet.Cards {
+		var openedOn sql.NullInt64
+		if !card.OpenedOn.IsZero() {
+			openedOn = sql.NullInt64{Int64: card.OpenedOn.UnixMilli(),
+				Valid: true}
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO wallet_card (wallet_id, position, card_key, "+
+				"opened_on) VALUES (?, ?, ?, ?)",
+			wallet.ID.Hex(), position, card.CardKey, openedOn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
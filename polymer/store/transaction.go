@@ -1,38 +1,96 @@
 package store
 
 import (
+	"context"
+	"fmt"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const TransactionCollection = "transaction"
 
 type BaseTransaction struct {
-	TransactionAt   time.Time       `bson:"transaction_at"`
-	SpendAmount     float64         `bson:"spend_amount"`
-	MerchantDetails MerchantDetails `bson:"merchant_details"`
-	CardDetails     CardDetails     `bson:"card_details"`
+	WalletID        string          `bson:"wallet_id,omitempty" json:"walletID,omitempty"` // Wallet the card belongs to, if any
+	TransactionAt   time.Time       `bson:"transaction_at" json:"transactionAt"`
+	SpendAmount     float64         `bson:"spend_amount" json:"spendAmount"`
+	MerchantDetails MerchantDetails `bson:"merchant_details" json:"merchant"`
+	CardDetails     CardDetails     `bson:"card_details" json:"card"`
+
+	// Card the wallet would have recommended, if it differs from the card
+	// used or was evaluated after the fact, as for imported statements
+	RecommendedCard *CardDetails `bson:"recommended_card,omitempty" json:"recommendedCard,omitempty"`
 }
 
 type MerchantDetails struct {
-	DomainName   string `bson:"name"`
-	CategoryID   int    `bson:"category_id"`
-	CategoryName string `bson:"category_name"`
+	DomainName   string `bson:"name" json:"domainName"`
+	CategoryID   int    `bson:"category_id" json:"categoryID"`
+	CategoryName string `bson:"category_name" json:"categoryName"`
+	MCC          string `bson:"mcc,omitempty" json:"mcc,omitempty"`               // ISO 18245 merchant category code, if known
+	Country      string `bson:"country,omitempty" json:"country,omitempty"`       // ISO 3166-1 alpha-2, empty if domestic
+	Currency     string `bson:"currency,omitempty" json:"currency,omitempty"`     // ISO 4217, empty if USD
+	Descriptor   string `bson:"descriptor,omitempty" json:"descriptor,omitempty"` // Statement descriptor, if known
+
+	// How the category was decided, empty if unknown, as for transactions
+	// recorded before it was
+	CategorySource string `bson:"category_source,omitempty" json:"categorySource,omitempty"`
 }
 
 type CardDetails struct {
-	CardKey       string        `bson:"card_key"`
-	CardName      string        `bson:"card_name"`
-	RewardDetails RewardDetails `bson:"reward_details"`
+	CardKey       string        `bson:"card_key" json:"cardKey"`
+	CardName      string        `bson:"card_name" json:"cardName"`
+	RewardDetails RewardDetails `bson:"reward_details" json:"reward"`
 }
 
 type RewardDetails struct {
-	Amount          float64 `bson:"amount"`
-	Currency        string  `bson:"currency"`
-	CashConvertible bool    `bson:"cash_convertible"`
-	CashConvValue   float64 `bson:"cash_conv_value"`
-	Value           float64 `bson:"value"`
+	Amount          float64 `bson:"amount" json:"amount"`
+	Currency        string  `bson:"currency" json:"currency"`
+	CashConvertible bool    `bson:"cash_convertible" json:"cashConvertible"`
+	CashConvValue   float64 `bson:"cash_conv_value" json:"cashConvValue"`
+	Value           float64 `bson:"value" json:"value"`                   // Net value per dollar, after fees
+	FxFee           float64 `bson:"fx_fee" json:"fxFee"`                  // Foreign transaction fee per dollar
+	Valuation       string  `bson:"valuation" json:"valuation"`           // Name of the point valuation used
+	CentsPerPoint   float64 `bson:"cents_per_point" json:"centsPerPoint"` // Point value in cents under Valuation
+
+	// Amortized sign-up bonus value per dollar included in Value
+	SignupBonusValue float64 `bson:"signup_bonus_value,omitempty" json:"signupBonusValue,omitempty"`
+}
+
+// TransactionFilter selects stored transactions. Zero valued fields match
+// every transaction.
+type TransactionFilter struct {
+	WalletID string    // Wallet the card belongs to
+	CardKeys []string  // Card used, any of
+	From     time.Time // Earliest TransactionAt, inclusive
+	To       time.Time // Latest TransactionAt, exclusive
+}
+
+// Matches reports whether transaction is selected by the filter.
+func (filter *TransactionFilter) Matches(transaction *BaseTransaction) bool {
+	if filter.WalletID != "" && transaction.WalletID != filter.WalletID {
+		return false
+	}
+	if len(filter.CardKeys) > 0 {
+		found := false
+		for _, cardKey := range filter.CardKeys {
+			if transaction.CardDetails.CardKey == cardKey {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !filter.From.IsZero() && transaction.TransactionAt.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !transaction.TransactionAt.Before(filter.To) {
+		return false
+	}
+	return true
 }
 
 // Transaction represents the structure of a transaction document in MongoDB.
@@ -52,12 +110,81 @@ func CreateTransaction(baseTransation *BaseTransaction) Transaction {
 	return transaction
 }
 
+// MongoTransactionRepository is a TransactionRepository backed by a MongoDB
+// collection.
+type MongoTransactionRepository struct {
+	store MongoStore
+}
+
+// NewMongoTransactionRepository returns a MongoTransactionRepository using the
+// transaction collection named in options.
+func NewMongoTransactionRepository(client *mongo.Client,
+	options MongoOptions) *MongoTransactionRepository {
+
+	store := GetStore(client, options, options.TransactionCollection)
+	return &MongoTransactionRepository{store: store}
+}
+
 // InsertTransaction inserts a new Transaction document into the MongoDB
 // collection.
-func InsertTransaction(client *mongo.Client, baseTransaction *BaseTransaction) (
-	*mongo.InsertOneResult, error) {
+func (repo *MongoTransactionRepository) InsertTransaction(ctx context.Context,
+	baseTransaction *BaseTransaction) (*Transaction, error) {
+
+	transaction := CreateTransaction(baseTransaction)
+	if _, err := repo.store.InsertDocument(ctx, transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// ListTransactions retrieves the Transaction documents selected by filter,
+// oldest first.
+func (repo *MongoTransactionRepository) ListTransactions(ctx context.Context,
+	filter *TransactionFilter) ([]*Transaction, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
+	defer cancel()
+
+	query := bson.M{}
+	if filter.WalletID != "" {
+		query["wallet_id"] = filter.WalletID
+	}
+	if len(filter.CardKeys) > 0 {
+		query["card_details.card_key"] = bson.M{"$in": filter.CardKeys}
+	}
+	transactionAt := bson.M{}
+	if !filter.From.IsZero() {
+		transactionAt["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		transactionAt["$lt"] = filter.To
+	}
+	if len(transactionAt) > 0 {
+		query["transaction_at"] = transactionAt
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "transaction_at",
+		Value: 1}})
+	cursor, err := repo.store.Collection.Find(ctx, query, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to retrieve transactions: %w",
+			ErrQuery, err)
+	}
+	defer cursor.Close(ctx)
+
+	var transactions []*Transaction
+	for cursor.Next(ctx) {
+		var transaction Transaction
+		if err := cursor.Decode(&transaction); err != nil {
+			return nil, fmt.Errorf("%w: failed to decode transaction: %w",
+				ErrQuery, err)
+		}
+		transactions = append(transactions, &transaction)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("%w: cursor error: %w", ErrQuery, err)
+	}
 
-	domain := CreateTransaction(baseTransaction)
-	store := GetStore(client, TransactionCollection)
-	return store.InsertDocument(domain)
+	return transactions, nil
 }
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const WalletCollection = "wallet"
+
+// WalletCard is a card held in a wallet.
+type WalletCard struct {
+	CardKey  string    `bson:"card_key" json:"cardKey"`
+	OpenedOn time.Time `bson:"opened_on,omitempty" json:"openedOn,omitempty"`
+}
+
+type BaseWallet struct {
+	Name  string       `bson:"name" json:"name"`
+	Cards []WalletCard `bson:"cards" json:"cards"`
+
+	// Valuation names the point valuation, with ValuationTable holding the
+	// cents per point by program for the table valuation.
+	Valuation      string             `bson:"valuation" json:"valuation"`
+	ValuationTable map[string]float64 `bson:"valuation_table,omitempty" json:"valuationTable,omitempty"`
+
+	PrioritizeSignupBonus bool `bson:"prioritize_signup_bonus" json:"prioritizeSignupBonus"`
+}
+
+// Wallet represents the structure of a wallet document in MongoDB.
+type Wallet struct {
+	*BaseDocument `bson:",inline"`
+	*BaseWallet   `bson:",inline"`
+}
+
+// CreateWallet creates a Wallet document from the given baseWallet.
+func CreateWallet(baseWallet *BaseWallet) Wallet {
+	wallet := Wallet{
+		BaseDocument: &BaseDocument{},
+		BaseWallet:   baseWallet,
+	}
+	wallet.SetID()
+	return wallet
+}
+
+// CardIndex returns the position of cardKey in the wallet's cards, or -1 if
+// the wallet does not hold it.
+func (wallet *BaseWallet) CardIndex(cardKey string) int {
+	for i, card := range wallet.Cards {
+		if card.CardKey == cardKey {
+			return i
+		}
+	}
+	return -1
+}
+
+// CardKeys returns the keys of the wallet's cards, in order.
+func (wallet *BaseWallet) CardKeys() []string {
+	cardKeys := make([]string, len(wallet.Cards))
+	for i, card := range wallet.Cards {
+		cardKeys[i] = card.CardKey
+	}
+	return cardKeys
+}
+
+// MongoWalletRepository is a WalletRepository backed by a MongoDB collection.
+type MongoWalletRepository struct {
+	store MongoStore
+}
+
+// NewMongoWalletRepository returns a MongoWalletRepository using the wallet
+// collection named in options.
+func NewMongoWalletRepository(client *mongo.Client,
+	options MongoOptions) *MongoWalletRepository {
+
+	store := GetStore(client, options, options.WalletCollection)
+	return &MongoWalletRepository{store: store}
+}
+
+// InsertWallet inserts a new Wallet document into the MongoDB collection.
+func (repo *MongoWalletRepository) InsertWallet(ctx context.Context,
+	baseWallet *BaseWallet) (*Wallet, error) {
+
+	wallet := CreateWallet(baseWallet)
+	if _, err := repo.store.InsertDocument(ctx, wallet); err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// GetWallet retrieves a Wallet document by its hex encoded ID.
+func (repo *MongoWalletRepository) GetWallet(ctx context.Context,
+	id string) (*Wallet, error) {
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: no wallet found with id: %s", ErrNotFound,
+			id)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
+	defer cancel()
+
+	var wallet Wallet
+	err = repo.store.Collection.FindOne(ctx, bson.M{"_id": objectID}).
+		Decode(&wallet)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: no wallet found with id: %s",
+				ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("%w: failed to find wallet: %w", ErrQuery, err)
+	}
+
+	return &wallet, nil
+}
+
+// UpdateWallet replaces the stored Wallet document with the same ID as wallet.
+func (repo *MongoWalletRepository) UpdateWallet(ctx context.Context,
+	wallet *Wallet) error {
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
+	defer cancel()
+
+	result, err := repo.store.Collection.ReplaceOne(ctx,
+		bson.M{"_id": wallet.ID}, wallet)
+	if err != nil {
+		return fmt.Errorf("%w: failed to update wallet: %w", ErrInsert, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: no wallet found with id: %s", ErrNotFound,
+			wallet.ID.Hex())
+	}
+
+	return nil
+}
+
+// AddWalletCard appends card to the cards of the stored wallet with the hex
+// encoded id, in a single update that only matches a wallet not holding the
+// card yet, and returns the updated wallet. It fails with ErrDuplicate if the
+// wallet already holds the card.
+func (repo *MongoWalletRepository) AddWalletCard(ctx context.Context,
+	id string, card WalletCard) (*Wallet, error) {
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: no wallet found with id: %s", ErrNotFound,
+			id)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
+	defer cancel()
+
+	// A pipeline update, as $push fails on wallets stored without cards. The
+	// card is a $literal, as a pipeline reads strings starting with "$" as
+	// field paths and the card key comes from the caller.
+	update := bson.A{bson.M{"$set": bson.M{"cards": bson.M{
+		"$concatArrays": bson.A{
+			bson.M{"$ifNull": bson.A{"$cards", bson.A{}}},
+			bson.M{"$literal": bson.A{card}},
+		},
+	}}}}
+	var wallet Wallet
+	err = repo.store.Collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID, "cards.card_key": bson.M{"$ne": card.CardKey}},
+		update, options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&wallet)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if _, err := repo.GetWallet(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: wallet already holds card: %s",
+			ErrDuplicate, card.CardKey)
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: failed to add wallet card: %w", ErrInsert,
+			err)
+	}
+
+	return &wallet, nil
+}
+
+// RemoveWalletCard pulls the card with cardKey from the cards of the stored
+// wallet with the hex encoded id, and returns the updated wallet. It fails
+// with ErrNotFound if the wallet does not hold the card.
+func (repo *MongoWalletRepository) RemoveWalletCard(ctx context.Context,
+	id, cardKey string) (*Wallet, error) {
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: no wallet found with id: %s", ErrNotFound,
+			id)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, repo.store.Timeout)
+	defer cancel()
+
+	var wallet Wallet
+	err = repo.store.Collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID, "cards.card_key": cardKey},
+		bson.M{"$pull": bson.M{"cards": bson.M{"card_key": cardKey}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&wallet)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if _, err := repo.GetWallet(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: wallet does not hold card: %s",
+			ErrNotFound, cardKey)
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: failed to remove wallet card: %w",
+			ErrInsert, err)
+	}
+
+	return &wallet, nil
+}